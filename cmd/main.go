@@ -1,20 +1,45 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata" // Embed zone data for minimal container images
 
 	"github.com/azme12/ai-agent-project/internal/agent"
 	"github.com/azme12/ai-agent-project/internal/api"
+	"github.com/azme12/ai-agent-project/internal/availability"
+	"github.com/azme12/ai-agent-project/internal/clock"
 	"github.com/azme12/ai-agent-project/internal/config"
+	"github.com/azme12/ai-agent-project/internal/oauth"
+	"github.com/azme12/ai-agent-project/internal/store"
+	"github.com/azme12/ai-agent-project/internal/templates"
 	"github.com/azme12/ai-agent-project/pkg/logger"
 )
 
 var agentService *agent.Service
 
+// googleOAuth completes the browser authorization of Google Calendar. It is
+// nil unless an OAuth client is configured.
+var googleOAuth *oauth.UserTokenSource
+
+// outbox queues outgoing email and retries failed deliveries.
+var outbox *agent.Outbox
+
+// inboundToken authenticates the inbound email webhook, which is disabled
+// when it is empty.
+var inboundToken string
+
 func main() {
 	// Initialize logger and config
 	logr := logger.New()
@@ -24,15 +49,56 @@ func main() {
 		os.Exit(1)
 	}
 
+	clk := clock.Real()
+
 	// Initialize API clients
-	calendar := api.NewGoogleCalendarService(cfg)
-	email := api.NewSendGridEmailService(cfg)
-	nlp := api.NewGeminiService(cfg)
+	googleAuth, err := oauth.NewGoogleTokenSource(cfg, clk)
+	if err != nil {
+		logr.Error("Failed to initialize Google credentials", "error", err)
+		os.Exit(1)
+	}
+	if source, ok := googleAuth.(*oauth.UserTokenSource); ok {
+		googleOAuth = source
+		if !source.Authorized() {
+			logr.Info("Google Calendar is not authorized yet, visit /oauth/google/start")
+		}
+	}
+	calendar, err := api.NewCalendarProvider(cfg, logr, clk, googleAuth)
+	if err != nil {
+		logr.Error("Failed to initialize calendar provider", "error", err)
+		os.Exit(1)
+	}
+	email, err := api.NewMailSender(cfg, clk)
+	if err != nil {
+		logr.Error("Failed to initialize email provider", "error", err)
+		os.Exit(1)
+	}
+	nlp, err := api.NewLanguageModel(cfg)
+	if err != nil {
+		logr.Error("Failed to initialize NLP provider", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize task history
+	tasks, err := store.NewTaskStore(cfg.DataDir, clk)
+	if err != nil {
+		logr.Error("Failed to open task store", "error", err)
+		os.Exit(1)
+	}
+
+	// Queue outgoing email so that it survives provider outages and restarts
+	outbox, err = agent.NewOutbox(cfg, logr, clk, email)
+	if err != nil {
+		logr.Error("Failed to open outbox", "error", err)
+		os.Exit(1)
+	}
 
 	// Initialize agent
-	agentService = agent.NewService(cfg, logr, calendar, email, nlp)
+	agentService = agent.NewService(cfg, logr, clk, calendar, outbox, nlp, tasks)
+	inboundToken = cfg.InboundWebhookToken
 
 	// Start the agent
+	outbox.Start()
 	if err := agentService.Start(); err != nil {
 		logr.Error("Failed to start agent", "error", err)
 		os.Exit(1)
@@ -41,9 +107,27 @@ func main() {
 	// HTTP endpoints
 	http.HandleFunc("/health", healthHandler)
 	http.HandleFunc("/schedule", scheduleHandler)
+	http.HandleFunc("/schedule/suggest", suggestHandler)
 	http.HandleFunc("/email", emailHandler)
 	http.HandleFunc("/nlp", nlpHandler)
 	http.HandleFunc("/status", statusHandler)
+	http.HandleFunc("/jobs/", jobHandler)
+	http.HandleFunc("/jobs/scheduled", scheduledJobsHandler)
+	http.HandleFunc("/jobs/scheduled/", scheduledJobHandler)
+	http.HandleFunc("/tasks", tasksHandler)
+	http.HandleFunc("/tasks/", taskHandler)
+	http.HandleFunc("/events", eventsHandler)
+	http.HandleFunc("/calendars", calendarsHandler)
+	http.HandleFunc("/events/", eventHandler)
+	http.HandleFunc("/calendar/export.ics", exportCalendarHandler)
+	http.HandleFunc("/calendar/import", importCalendarHandler)
+	http.HandleFunc("/templates", templatesHandler)
+	http.HandleFunc("/templates/", templatePreviewHandler)
+	http.HandleFunc("/outbox", outboxHandler)
+	http.HandleFunc("/outbox/", outboxMessageHandler)
+	http.HandleFunc("/inbound/email", inboundEmailHandler)
+	http.HandleFunc("/oauth/google/start", oauthStartHandler)
+	http.HandleFunc("/oauth/google/callback", oauthCallbackHandler)
 
 	// Use configured port
 	port := cfg.ServerPort
@@ -51,11 +135,66 @@ func main() {
 		port = "8080"
 	}
 
-	logr.Info("Starting HTTP server", "port", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	server := &http.Server{Addr: ":" + port}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logr.Info("Starting HTTP server", "port", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	// Wait for a shutdown signal or a server failure
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	exitCode := 0
+	select {
+	case sig := <-signals:
+		logr.Info("Received shutdown signal", "signal", sig)
+	case err := <-serverErr:
 		logr.Error("HTTP server failed", "error", err)
-		os.Exit(1)
+		exitCode = 1
+	}
+
+	if !shutdown(logr, server, time.Duration(cfg.ShutdownTimeoutSeconds)*time.Second) {
+		exitCode = 1
+	}
+	os.Exit(exitCode)
+}
+
+// shutdown stops accepting requests, waits for in-flight requests, then stops
+// the agent so running reminders and jobs can finish, and then the outbox.
+// It reports whether everything stopped cleanly before the timeout.
+func shutdown(logr *logger.Logger, server *http.Server, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	clean := true
+
+	logr.Info("Shutting down HTTP server", "timeout", timeout)
+	if err := server.Shutdown(ctx); err != nil {
+		logr.Error("HTTP server shutdown failed", "error", err)
+		clean = false
+	}
+
+	if err := agentService.Stop(ctx); err != nil {
+		logr.Error("Agent shutdown failed", "error", err)
+		clean = false
+	}
+
+	// Stopped last, after the agent has queued its final emails
+	if err := outbox.Stop(ctx); err != nil {
+		logr.Error("Outbox shutdown failed", "error", err)
+		clean = false
 	}
+
+	if clean {
+		logr.Info("Shutdown complete")
+	}
+	return clean
 }
 
 func healthHandler(w http.ResponseWriter, r *http.Request) {
@@ -90,6 +229,30 @@ func statusHandler(w http.ResponseWriter, r *http.Request) {
 			"POST /email",
 			"POST /nlp",
 			"GET /status",
+			"GET /jobs/{id}",
+			"GET /jobs/scheduled",
+			"POST /jobs/scheduled",
+			"DELETE /jobs/scheduled/{id}",
+			"GET /tasks",
+			"GET /tasks/{id}",
+			"POST /schedule/suggest",
+			"GET /events",
+			"GET /calendars",
+			"GET /events/{id}",
+			"PATCH /events/{id}",
+			"DELETE /events/{id}",
+			"POST /events/{id}/reschedule",
+			"GET /calendar/export.ics",
+			"POST /calendar/import",
+			"GET /templates",
+			"GET /templates/{name}/preview",
+			"GET /outbox",
+			"GET /outbox/{id}",
+			"DELETE /outbox/{id}",
+			"POST /outbox/{id}/retry",
+			"POST /inbound/email",
+			"GET /oauth/google/start",
+			"GET /oauth/google/callback",
 		},
 	})
 }
@@ -120,17 +283,69 @@ func scheduleHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := agentService.ProcessTask(req.Task); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to process task: %v", err), http.StatusInternalServerError)
+	job, err := agentService.SubmitTask(req.Task)
+	if err != nil {
+		writeSubmitError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "accepted",
+		"message":    "Task queued",
+		"task":       req.Task,
+		"job_id":     job.ID,
+		"status_url": "/jobs/" + job.ID,
+	})
+}
+
+func suggestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
 		return
 	}
+	defer r.Body.Close()
+
+	var req struct {
+		agent.SlotRequest
+		Task string `json:"task"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Task == "" && len(req.Attendees) == 0 {
+		http.Error(w, "Task or attendees are required", http.StatusBadRequest)
+		return
+	}
+
+	slots, err := agentService.SuggestSlots(req.Task, req.SlotRequest)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, agent.ErrInvalidSlotRequest) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, fmt.Sprintf("Failed to suggest slots: %v", err), status)
+		return
+	}
+	if slots == nil {
+		slots = []availability.Slot{}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":  "success",
-		"message": "Task processed successfully",
-		"task":    req.Task,
+		"status": "success",
+		"count":  len(slots),
+		"slots":  slots,
 	})
 }
 
@@ -160,17 +375,20 @@ func emailHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := agentService.ProcessTask(req.Task); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to process email task: %v", err), http.StatusInternalServerError)
+	job, err := agentService.SubmitTask(req.Task)
+	if err != nil {
+		writeSubmitError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":  "success",
-		"message": "Email task processed successfully",
-		"task":    req.Task,
+		"status":     "accepted",
+		"message":    "Email task queued",
+		"task":       req.Task,
+		"job_id":     job.ID,
+		"status_url": "/jobs/" + job.ID,
 	})
 }
 
@@ -214,3 +432,712 @@ func nlpHandler(w http.ResponseWriter, r *http.Request) {
 		"command":  req.Command,
 	})
 }
+
+func jobHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	job, ok := agentService.GetJob(id)
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(job)
+}
+
+func scheduledJobsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		jobs := agentService.ListScheduledJobs()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "success",
+			"count":  len(jobs),
+			"jobs":   jobs,
+		})
+
+	case http.MethodPost:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		var req struct {
+			Name    string `json:"name"`
+			Kind    string `json:"kind"`
+			Spec    string `json:"spec"`
+			Task    string `json:"task"`
+			Misfire string `json:"misfire"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		job, err := agentService.AddScheduledJob(agent.ScheduledJob{
+			Name:    req.Name,
+			Kind:    req.Kind,
+			Spec:    req.Spec,
+			Task:    req.Task,
+			Misfire: req.Misfire,
+		})
+		if errors.Is(err, agent.ErrInvalidJob) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "success",
+			"job":    job,
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func scheduledJobHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/jobs/scheduled/")
+	if err := agentService.RemoveScheduledJob(id); err != nil {
+		if errors.Is(err, agent.ErrJobNotFound) {
+			http.Error(w, "Scheduled job not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to delete scheduled job: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeSubmitError reports a job that could not be enqueued.
+func writeSubmitError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, agent.ErrQueueFull):
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Job queue is full, try again later", http.StatusServiceUnavailable)
+	case errors.Is(err, agent.ErrQueueClosed):
+		http.Error(w, "Service is shutting down", http.StatusServiceUnavailable)
+	default:
+		http.Error(w, fmt.Sprintf("Failed to submit task: %v", err), http.StatusInternalServerError)
+	}
+}
+
+func tasksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := store.TaskFilter{
+		Type:   query.Get("type"),
+		Status: query.Get("status"),
+	}
+
+	var err error
+	if filter.From, err = parseQueryTime(query.Get("from")); err != nil {
+		http.Error(w, "Invalid from time", http.StatusBadRequest)
+		return
+	}
+	if filter.To, err = parseQueryTime(query.Get("to")); err != nil {
+		http.Error(w, "Invalid to time", http.StatusBadRequest)
+		return
+	}
+	// A date includes that whole day
+	if _, err := time.Parse("2006-01-02", query.Get("to")); err == nil {
+		filter.To = filter.To.AddDate(0, 0, 1)
+	}
+
+	tasks := agentService.ListTasks(filter)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"count":  len(tasks),
+		"tasks":  tasks,
+	})
+}
+
+func taskHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/tasks/")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+
+	task, ok := agentService.GetTask(id)
+	if !ok {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(task)
+}
+
+func eventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := parseQueryTime(query.Get("from"))
+	if err != nil {
+		http.Error(w, "Invalid from time", http.StatusBadRequest)
+		return
+	}
+	to, err := parseQueryTime(query.Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid to time", http.StatusBadRequest)
+		return
+	}
+
+	// Default to the coming week
+	if from.IsZero() {
+		from = agentService.Now()
+	}
+	if to.IsZero() {
+		to = from.AddDate(0, 0, 7)
+	}
+	if !to.After(from) {
+		http.Error(w, "to must be after from", http.StatusBadRequest)
+		return
+	}
+
+	events, err := agentService.ListEvents(from, to, query.Get("calendar"))
+	if err != nil {
+		if errors.Is(err, api.ErrUnknownCalendar) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to list events: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if events == nil {
+		events = []api.Event{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"count":  len(events),
+		"events": events,
+	})
+}
+
+func calendarsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "success",
+		"calendars": agentService.Calendars(),
+	})
+}
+
+func eventHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/events/")
+	id, action := path, ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		id, action = path[:i], path[i+1:]
+	}
+	if id == "" || (action != "" && action != "reschedule") {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+
+	if action == "reschedule" {
+		rescheduleEventHandler(w, r, id)
+		return
+	}
+
+	var event *api.Event
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		event, err = agentService.GetEvent(id)
+
+	case http.MethodPatch:
+		var update api.EventUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if update == (api.EventUpdate{}) {
+			http.Error(w, "No fields to update", http.StatusBadRequest)
+			return
+		}
+		event, err = agentService.UpdateEvent(id, update)
+
+	case http.MethodDelete:
+		if err := agentService.CancelEvent(id); err != nil {
+			writeEventError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		writeEventError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"event":  event,
+	})
+}
+
+func rescheduleEventHandler(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		StartTime time.Time `json:"start_time"`
+		Duration  int       `json:"duration_minutes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.StartTime.IsZero() {
+		http.Error(w, "start_time is required", http.StatusBadRequest)
+		return
+	}
+	if req.Duration < 0 || req.Duration > 24*60 {
+		http.Error(w, "Invalid duration_minutes", http.StatusBadRequest)
+		return
+	}
+
+	event, err := agentService.RescheduleEvent(id, req.StartTime, time.Duration(req.Duration)*time.Minute)
+	if err != nil {
+		writeEventError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"event":  event,
+	})
+}
+
+// writeEventError reports a failed calendar event operation.
+func writeEventError(w http.ResponseWriter, err error) {
+	if errors.Is(err, api.ErrEventNotFound) {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, api.ErrReadOnlyCalendar) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	http.Error(w, fmt.Sprintf("Calendar request failed: %v", err), http.StatusInternalServerError)
+}
+
+// maxImportSize bounds the size of uploaded iCalendar files.
+const maxImportSize = 5 << 20
+
+func exportCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := parseQueryTime(query.Get("from"))
+	if err != nil {
+		http.Error(w, "Invalid from time", http.StatusBadRequest)
+		return
+	}
+	to, err := parseQueryTime(query.Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid to time", http.StatusBadRequest)
+		return
+	}
+
+	// Default to the coming month so subscribed clients see what's ahead
+	if from.IsZero() {
+		from = agentService.Now()
+	}
+	if to.IsZero() {
+		to = from.AddDate(0, 0, 30)
+	}
+	if !to.After(from) {
+		http.Error(w, "to must be after from", http.StatusBadRequest)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := agentService.ExportCalendar(&buf, from, to); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to export calendar: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="calendar.ics"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// importCalendarHandler accepts an .ics file either as the request body or
+// as the "file" field of a multipart form.
+func importCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Missing file field", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	result, err := agentService.ImportCalendar(body)
+	if err != nil {
+		if errors.Is(err, agent.ErrInvalidCalendar) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to import calendar: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "success",
+		"count":    len(result.Imported),
+		"imported": result.Imported,
+		"skipped":  result.Skipped,
+	})
+}
+
+// templatesHandler lists the email templates and the file each is loaded
+// from for the given locale and recipient.
+func templatesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	infos, err := agentService.ListTemplates(query.Get("locale"), query.Get("recipient"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "success",
+		"templates": infos,
+	})
+}
+
+// templatePreviewHandler renders a template with sample data, as JSON or,
+// with format=text or format=html, as the body alone to view in a browser.
+func templatePreviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/templates/")
+	if !strings.HasSuffix(name, "/preview") {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return
+	}
+	name = strings.TrimSuffix(name, "/preview")
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "text" && format != "html" {
+		http.Error(w, "format must be json, text or html", http.StatusBadRequest)
+		return
+	}
+
+	email, err := agentService.PreviewTemplate(name, query.Get("locale"), query.Get("recipient"))
+	if errors.Is(err, templates.ErrUnknownTemplate) {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch format {
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, email.Text)
+	case "html":
+		if email.HTML == "" {
+			http.Error(w, "Template has no HTML version", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, email.HTML)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":   "success",
+			"template": name,
+			"subject":  email.Subject,
+			"text":     email.Text,
+			"html":     email.HTML,
+		})
+	}
+}
+
+func outboxHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", agent.OutboxPending, agent.OutboxSent, agent.OutboxFailed, agent.OutboxCancelled:
+	default:
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "success",
+		"messages": outbox.List(status),
+	})
+}
+
+func outboxMessageHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/outbox/")
+	id, action := path, ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		id, action = path[:i], path[i+1:]
+	}
+	if id == "" || (action != "" && action != "retry") {
+		http.Error(w, "Message not found", http.StatusNotFound)
+		return
+	}
+
+	var message *agent.OutboxMessage
+	var err error
+	switch {
+	case action == "retry" && r.Method == http.MethodPost:
+		message, err = outbox.Retry(id)
+	case action == "" && r.Method == http.MethodGet:
+		message, err = outbox.Get(id)
+	case action == "" && r.Method == http.MethodDelete:
+		message, err = outbox.Cancel(id)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch {
+	case errors.Is(err, agent.ErrOutboxNotFound):
+		http.Error(w, "Message not found", http.StatusNotFound)
+		return
+	case errors.Is(err, agent.ErrOutboxSent):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": message,
+	})
+}
+
+// maxInboundSize matches the largest email SendGrid Inbound Parse posts.
+const maxInboundSize = 30 << 20
+
+// inboundEmailHandler receives emails from the SendGrid Inbound Parse
+// webhook, authenticated by the token in the "token" query parameter or the
+// X-Inbound-Token header. Ignored emails are acknowledged too, so that they
+// aren't posted again.
+func inboundEmailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if inboundToken == "" {
+		http.Error(w, "Inbound email is not configured", http.StatusNotFound)
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token = r.Header.Get("X-Inbound-Token")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(inboundToken)) != 1 {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxInboundSize)
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		// Attachments are spooled to disk and not used
+		err = r.ParseMultipartForm(10 << 20)
+		if r.MultipartForm != nil {
+			defer r.MultipartForm.RemoveAll()
+		}
+	} else {
+		err = r.ParseForm()
+	}
+	if err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	email, err := api.ParseSendGridInbound(r.PostForm)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := agentService.ReceiveEmail(email)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to receive email: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"result": result,
+	})
+}
+
+// oauthStartHandler sends the user to Google to authorize the agent's access
+// to their calendar.
+func oauthStartHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if googleOAuth == nil {
+		http.Error(w, "Google OAuth is not configured", http.StatusNotFound)
+		return
+	}
+
+	http.Redirect(w, r, googleOAuth.AuthCodeURL(), http.StatusFound)
+}
+
+// oauthCallbackHandler receives the authorization code Google redirects the
+// user back with and stores the resulting refresh token.
+func oauthCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if googleOAuth == nil {
+		http.Error(w, "Google OAuth is not configured", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	if reason := query.Get("error"); reason != "" {
+		http.Error(w, fmt.Sprintf("Authorization denied: %s", reason), http.StatusBadRequest)
+		return
+	}
+	if query.Get("code") == "" {
+		http.Error(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := googleOAuth.Exchange(query.Get("state"), query.Get("code")); err != nil {
+		if errors.Is(err, oauth.ErrInvalidState) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to authorize: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": "Google Calendar authorized",
+	})
+}
+
+// parseQueryTime accepts RFC 3339 timestamps or plain YYYY-MM-DD dates,
+// which are taken as midnight in the configured time zone.
+func parseQueryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, agentService.Location())
+}
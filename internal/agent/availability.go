@@ -0,0 +1,302 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/api"
+	"github.com/azme12/ai-agent-project/internal/availability"
+)
+
+// ErrSchedulingConflict is returned when a meeting would overlap busy time
+// and the conflict policy is "reject".
+var ErrSchedulingConflict = errors.New("requested time is busy")
+
+// ErrInvalidSlotRequest is returned for slot searches that cannot be run as
+// asked.
+var ErrInvalidSlotRequest = errors.New("invalid slot request")
+
+// Conflict is a busy period on one calendar that overlaps a requested
+// meeting.
+type Conflict struct {
+	Calendar string    `json:"calendar"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+}
+
+// maxSuggestWindow bounds the search window of a slot suggestion.
+const maxSuggestWindow = 31 * 24 * time.Hour
+
+// AttendeeHours describes an attendee of a slot search. TimeZone,
+// WorkingHours and WorkingDays default to the agent's configuration.
+type AttendeeHours struct {
+	Email        string `json:"email"`
+	TimeZone     string `json:"time_zone,omitempty"`
+	WorkingHours string `json:"working_hours,omitempty"`
+	WorkingDays  []int  `json:"working_days,omitempty"`
+}
+
+// UnmarshalJSON accepts a bare email address as well as an object.
+func (a *AttendeeHours) UnmarshalJSON(data []byte) error {
+	var email string
+	if err := json.Unmarshal(data, &email); err == nil {
+		*a = AttendeeHours{Email: email}
+		return nil
+	}
+
+	type attendee AttendeeHours
+	return json.Unmarshal(data, (*attendee)(a))
+}
+
+// SlotRequest asks for the best times to meet with a set of attendees.
+type SlotRequest struct {
+	Attendees []AttendeeHours `json:"attendees"`
+	Duration  int             `json:"duration_minutes"`
+	From      time.Time       `json:"from"`
+	To        time.Time       `json:"to"`
+	// Buffer overrides MEETING_BUFFER_MINUTES when set.
+	Buffer *int `json:"buffer_minutes,omitempty"`
+	Count  int  `json:"count"`
+}
+
+// SuggestSlots finds the best ranked open slots that suit the user and every
+// attendee. Zero fields default to a 30 minute meeting in the coming week.
+func (h *Handler) SuggestSlots(req SlotRequest) ([]availability.Slot, error) {
+	now := h.now()
+	if req.Duration == 0 {
+		req.Duration = 30
+	}
+	if req.Duration < 0 || req.Duration > 24*60 {
+		return nil, fmt.Errorf("%w: invalid duration %d minutes", ErrInvalidSlotRequest, req.Duration)
+	}
+	if req.From.IsZero() || req.From.Before(now) {
+		req.From = now
+	}
+	if req.To.IsZero() {
+		req.To = req.From.AddDate(0, 0, 7)
+	}
+	if !req.To.After(req.From) {
+		return nil, fmt.Errorf("%w: search window ends before it starts", ErrInvalidSlotRequest)
+	}
+	if req.To.Sub(req.From) > maxSuggestWindow {
+		return nil, fmt.Errorf("%w: search window is longer than %d days", ErrInvalidSlotRequest, int(maxSuggestWindow.Hours()/24))
+	}
+	if req.Count <= 0 {
+		req.Count = h.config.SlotSuggestions
+	}
+	buffer := h.config.MeetingBufferMinutes
+	if req.Buffer != nil {
+		buffer = *req.Buffer
+	}
+	if buffer < 0 {
+		return nil, fmt.Errorf("%w: invalid buffer %d minutes", ErrInvalidSlotRequest, buffer)
+	}
+
+	hours, err := availability.ParseWorkingHours(h.config.WorkingHours, h.config.WorkingDays, h.config.Location())
+	if err != nil {
+		return nil, fmt.Errorf("invalid working hours: %v", err)
+	}
+
+	var emails []string
+	for i, attendee := range req.Attendees {
+		req.Attendees[i].Email = strings.TrimSpace(attendee.Email)
+		if !validEmailRegex.MatchString(req.Attendees[i].Email) {
+			return nil, fmt.Errorf("%w: invalid attendee email %q", ErrInvalidSlotRequest, attendee.Email)
+		}
+		if !strings.EqualFold(req.Attendees[i].Email, h.config.UserEmail) {
+			emails = append(emails, req.Attendees[i].Email)
+		}
+	}
+
+	freeBusy, err := h.calendar.FreeBusy(emails, req.From, req.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check availability: %v", err)
+	}
+	if len(freeBusy.Unavailable) > 0 {
+		h.logger.Info("Availability unknown for some attendees", "attendees", freeBusy.Unavailable)
+	}
+
+	participants := []availability.Participant{{Email: h.config.UserEmail, Hours: hours, Busy: freeBusy.Calendar}}
+	for _, attendee := range req.Attendees {
+		if strings.EqualFold(attendee.Email, h.config.UserEmail) {
+			continue
+		}
+		attendeeHours, err := h.attendeeHours(attendee)
+		if err != nil {
+			return nil, err
+		}
+		participants = append(participants, availability.Participant{
+			Email: attendee.Email,
+			Hours: attendeeHours,
+			Busy:  freeBusy.Attendees[attendee.Email],
+		})
+	}
+
+	search := availability.Search{
+		From:     req.From,
+		To:       req.To,
+		Duration: time.Duration(req.Duration) * time.Minute,
+		Buffer:   time.Duration(buffer) * time.Minute,
+		Location: h.config.Location(),
+	}
+	return availability.FindSlots(participants, search, req.Count), nil
+}
+
+// attendeeHours resolves an attendee's working hours, falling back to the
+// agent's configuration for anything not given.
+func (h *Handler) attendeeHours(attendee AttendeeHours) (availability.WorkingHours, error) {
+	loc := h.config.Location()
+	if attendee.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(attendee.TimeZone); err != nil {
+			return availability.WorkingHours{}, fmt.Errorf("%w: unknown time zone %q for %s", ErrInvalidSlotRequest, attendee.TimeZone, attendee.Email)
+		}
+	}
+
+	spec, days := h.config.WorkingHours, h.config.WorkingDays
+	if attendee.WorkingHours != "" {
+		spec = attendee.WorkingHours
+	}
+	if len(attendee.WorkingDays) > 0 {
+		days = attendee.WorkingDays
+	}
+
+	hours, err := availability.ParseWorkingHours(spec, days, loc)
+	if err != nil {
+		return availability.WorkingHours{}, fmt.Errorf("%w: invalid working hours for %s: %v", ErrInvalidSlotRequest, attendee.Email, err)
+	}
+	return hours, nil
+}
+
+// SlotRequestFromTask builds a slot search from a natural-language request
+// such as "find 30 minutes with alice@example.com this week". Requests to
+// schedule a meeting search the day it was asked for.
+func (h *Handler) SlotRequestFromTask(task string) (*SlotRequest, error) {
+	req, _, err := h.understandTask(task)
+	if err != nil {
+		return nil, err
+	}
+
+	slotRequest := &SlotRequest{Duration: req.Duration, From: req.StartTime}
+	switch req.Type {
+	case "suggest":
+		if req.Until != nil {
+			slotRequest.To = *req.Until
+		}
+	case "schedule":
+		start := req.StartTime.In(h.config.Location())
+		slotRequest.From = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+		slotRequest.To = slotRequest.From.AddDate(0, 0, 1)
+	default:
+		return nil, fmt.Errorf("%w: task does not describe a meeting", ErrInvalidSlotRequest)
+	}
+
+	for _, email := range req.Attendees {
+		slotRequest.Attendees = append(slotRequest.Attendees, AttendeeHours{Email: email})
+	}
+	return slotRequest, nil
+}
+
+func (h *Handler) handleSuggestTask(req *TaskRequest, result *TaskResult) error {
+	h.logger.Info("Handling suggest task", "attendees", req.Attendees, "duration", req.Duration)
+
+	slotRequest := SlotRequest{Duration: req.Duration, From: req.StartTime}
+	if req.Until != nil {
+		slotRequest.To = *req.Until
+	}
+	for _, email := range req.Attendees {
+		slotRequest.Attendees = append(slotRequest.Attendees, AttendeeHours{Email: email})
+	}
+
+	slots, err := h.SuggestSlots(slotRequest)
+	if err != nil {
+		return err
+	}
+
+	result.Suggestions = slots
+	return nil
+}
+
+// checkAvailability looks up free/busy time for the user and attendees of a
+// meeting. When the meeting overlaps busy time it records the conflicts and
+// the next open slots on result, and fails unless the conflict policy is
+// "warn". A free/busy outage does not block scheduling.
+func (h *Handler) checkAvailability(req *TaskRequest, duration time.Duration, result *TaskResult) error {
+	hours, err := availability.ParseWorkingHours(h.config.WorkingHours, h.config.WorkingDays, h.config.Location())
+	if err != nil {
+		return fmt.Errorf("invalid working hours: %v", err)
+	}
+
+	var attendees []string
+	for _, attendee := range req.Attendees {
+		if !strings.EqualFold(attendee, h.config.UserEmail) {
+			attendees = append(attendees, attendee)
+		}
+	}
+
+	start, end := req.StartTime, req.StartTime.Add(duration)
+	freeBusy, err := h.calendar.FreeBusy(attendees, start, start.Add(availability.SearchHorizon))
+	if err != nil {
+		h.logger.Error("Failed to check availability, scheduling anyway", "error", err)
+		return nil
+	}
+	if len(freeBusy.Unavailable) > 0 {
+		h.logger.Info("Availability unknown for some attendees", "attendees", freeBusy.Unavailable)
+	}
+
+	for _, period := range availability.Overlapping(freeBusy.Calendar, start, end) {
+		result.Conflicts = append(result.Conflicts, Conflict{Calendar: h.config.UserEmail, Start: period.Start, End: period.End})
+	}
+	for _, attendee := range attendees {
+		for _, period := range availability.Overlapping(freeBusy.Attendees[attendee], start, end) {
+			result.Conflicts = append(result.Conflicts, Conflict{Calendar: attendee, Start: period.Start, End: period.End})
+		}
+	}
+
+	if len(result.Conflicts) == 0 {
+		return nil
+	}
+
+	busy := busyPeriods(freeBusy, attendees)
+	result.Suggestions = availability.NextSlots(busy, hours, start, duration, h.config.SlotSuggestions)
+	h.logger.Info("Meeting conflicts with busy time", "conflicts", len(result.Conflicts), "suggestions", len(result.Suggestions))
+
+	if h.config.ConflictPolicy == "warn" {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrSchedulingConflict, h.describeConflicts(result))
+}
+
+// describeConflicts summarizes conflicts and suggestions for the task error.
+func (h *Handler) describeConflicts(result *TaskResult) string {
+	loc := h.config.Location()
+	const layout = "Mon Jan 2 15:04"
+
+	var busy []string
+	for _, c := range result.Conflicts {
+		busy = append(busy, fmt.Sprintf("%s is busy %s-%s", c.Calendar, c.Start.In(loc).Format(layout), c.End.In(loc).Format("15:04")))
+	}
+	description := strings.Join(busy, "; ")
+
+	if len(result.Suggestions) == 0 {
+		return description + "; no open slots found"
+	}
+
+	var open []string
+	for _, slot := range result.Suggestions {
+		open = append(open, slot.Start.In(loc).Format(layout))
+	}
+	return description + "; next open slots: " + strings.Join(open, ", ")
+}
+
+// busyPeriods flattens the busy time of the user and the given attendees.
+func busyPeriods(freeBusy *api.FreeBusy, attendees []string) []api.BusyPeriod {
+	busy := append([]api.BusyPeriod(nil), freeBusy.Calendar...)
+	for _, attendee := range attendees {
+		busy = append(busy, freeBusy.Attendees[attendee]...)
+	}
+	return busy
+}
@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/api"
+)
+
+func TestScheduleTaskConflicts(t *testing.T) {
+	loc := loadLocation(t)
+	// Wednesday
+	h, cal := newEventsHandler(t, time.Date(2026, time.October, 14, 10, 0, 0, 0, loc))
+	schedule(t, cal, "Hiring", time.Date(2026, time.October, 15, 14, 0, 0, 0, loc), "bob@example.com")
+	schedule(t, cal, "Standup", time.Date(2026, time.October, 15, 15, 0, 0, 0, loc))
+
+	// Bob is busy at 2pm, the user at 3pm
+	result, err := h.ProcessTask("Schedule a meeting with bob@example.com tomorrow at 2pm for an hour")
+	if !errors.Is(err, ErrSchedulingConflict) {
+		t.Fatalf("got error %v, want ErrSchedulingConflict", err)
+	}
+	if len(result.Conflicts) != 2 {
+		t.Errorf("got %d conflicts, want 2", len(result.Conflicts))
+	}
+
+	want := []time.Time{
+		time.Date(2026, time.October, 15, 15, 30, 0, 0, loc),
+		time.Date(2026, time.October, 15, 16, 0, 0, 0, loc),
+	}
+	if len(result.Suggestions) != len(want) {
+		t.Fatalf("got %d suggestions, want %d", len(result.Suggestions), len(want))
+	}
+	for i, slot := range result.Suggestions {
+		if !slot.Start.Equal(want[i]) {
+			t.Errorf("suggestion %d starts %s, want %s", i, slot.Start, want[i])
+		}
+	}
+	if events := allEvents(t, cal); len(events) != 2 {
+		t.Errorf("conflicting meeting was booked")
+	}
+
+	// With the warn policy the meeting is booked and the conflicts reported
+	h.config.ConflictPolicy = "warn"
+	result, err = h.ProcessTask("Schedule a meeting with bob@example.com tomorrow at 2pm for an hour")
+	if err != nil {
+		t.Fatalf("warn policy failed: %v", err)
+	}
+	if len(result.Conflicts) != 2 || result.Request.EventID == "" {
+		t.Errorf("got %d conflicts and event %q, want 2 conflicts and a booked event", len(result.Conflicts), result.Request.EventID)
+	}
+
+	// A free slot books without conflicts
+	result, err = h.ProcessTask("Schedule a meeting with bob@example.com tomorrow at 10am")
+	if err != nil || len(result.Conflicts) != 0 || len(result.Suggestions) != 0 {
+		t.Errorf("free slot: err %v, %d conflicts, %d suggestions", err, len(result.Conflicts), len(result.Suggestions))
+	}
+}
+
+func TestSuggestTaskFindsSharedSlots(t *testing.T) {
+	loc := loadLocation(t)
+	// Wednesday
+	h, cal := newEventsHandler(t, time.Date(2026, time.October, 14, 10, 0, 0, 0, loc))
+	h.config.SlotSuggestions = 3
+
+	// Alice is busy for the rest of Wednesday, Bob on Thursday morning
+	offsite := schedule(t, cal, "Offsite", time.Date(2026, time.October, 14, 10, 0, 0, 0, loc), "alice@example.com")
+	dentist := schedule(t, cal, "Dentist", time.Date(2026, time.October, 15, 9, 0, 0, 0, loc), "bob@example.com")
+	extend(t, cal, offsite.ID, time.Date(2026, time.October, 14, 17, 0, 0, 0, loc))
+	extend(t, cal, dentist.ID, time.Date(2026, time.October, 15, 12, 0, 0, 0, loc))
+
+	result, err := h.ProcessTask("find 45 minutes with alice@example.com and bob@example.com this week")
+	if err != nil {
+		t.Fatalf("suggest failed: %v", err)
+	}
+	if result.Request.Type != "suggest" || result.Request.Duration != 45 {
+		t.Fatalf("parsed %s of %d minutes, want suggest of 45", result.Request.Type, result.Request.Duration)
+	}
+	if len(result.Suggestions) != 3 {
+		t.Fatalf("got %d suggestions, want 3", len(result.Suggestions))
+	}
+
+	for _, slot := range result.Suggestions {
+		start := slot.Start.In(loc)
+		if start.Day() < 15 || (start.Day() == 15 && start.Hour() < 12) || start.Day() > 16 {
+			t.Errorf("suggested busy or out-of-window slot %s", start)
+		}
+		if slot.End.Sub(slot.Start) != 45*time.Minute {
+			t.Errorf("suggested slot %s-%s is not 45 minutes", slot.Start, slot.End)
+		}
+	}
+	if events := allEvents(t, cal); len(events) != 2 {
+		t.Errorf("suggest task booked a meeting")
+	}
+}
+
+// extend moves the end of an event to end.
+func extend(t *testing.T, cal api.CalendarProvider, id string, end time.Time) {
+	t.Helper()
+
+	if _, err := cal.UpdateEvent(id, api.EventUpdate{EndTime: &end}); err != nil {
+		t.Fatalf("failed to extend event %s: %v", id, err)
+	}
+}
@@ -0,0 +1,190 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/api"
+	"github.com/azme12/ai-agent-project/internal/timeparse"
+)
+
+// eventSearchWindow is how far ahead a natural-language event reference is
+// looked up when it doesn't name a date.
+const eventSearchWindow = 30 * 24 * time.Hour
+
+var (
+	withRegex   = regexp.MustCompile(`(?i)\bwith\s+([\w.@+-]+(?:\s*(?:,|\band\b)\s*[\w.@+-]+)*)`)
+	peopleRegex = regexp.MustCompile(`(?i)\s*(?:,|\band\b)\s*`)
+	quotedRegex = regexp.MustCompile(`"([^"]+)"`)
+	wordRegex   = regexp.MustCompile(`[a-z][a-z'-]*`)
+)
+
+// eventQueryStopWords are ignored when matching the words of a query against
+// event titles.
+var eventQueryStopWords = map[string]bool{
+	"my": true, "the": true, "a": true, "an": true, "our": true, "this": true, "next": true,
+	"meeting": true, "meetings": true, "call": true, "event": true, "appointment": true,
+	"with": true, "and": true, "on": true, "at": true, "for": true, "of": true, "in": true,
+	"am": true, "pm": true, "noon": true, "midnight": true, "back": true,
+	"today": true, "tomorrow": true, "tonight": true, "morning": true, "afternoon": true, "evening": true,
+	"monday": true, "tuesday": true, "wednesday": true, "thursday": true, "friday": true, "saturday": true, "sunday": true,
+	"january": true, "february": true, "march": true, "april": true, "may": true, "june": true,
+	"july": true, "august": true, "september": true, "october": true, "november": true, "december": true,
+}
+
+// eventQuery is a parsed natural-language reference to an existing event,
+// such as "my 3pm with Sarah".
+type eventQuery struct {
+	when     *timeparse.Result
+	people   []string
+	keywords []string
+}
+
+func parseEventQuery(query string, now time.Time) eventQuery {
+	var q eventQuery
+
+	if result, ok := timeparse.Parse(query, now); ok {
+		q.when = &result
+	}
+
+	rest := query
+	if m := withRegex.FindStringSubmatch(query); m != nil {
+		for _, person := range peopleRegex.Split(m[1], -1) {
+			if person = strings.ToLower(strings.TrimSpace(person)); person != "" {
+				q.people = append(q.people, person)
+			}
+		}
+		rest = strings.Replace(rest, m[0], " ", 1)
+	}
+
+	// Quoted titles are matched as a whole
+	if m := quotedRegex.FindStringSubmatch(rest); m != nil {
+		q.keywords = []string{strings.ToLower(m[1])}
+		return q
+	}
+
+	for _, word := range wordRegex.FindAllString(strings.ToLower(rest), -1) {
+		if !eventQueryStopWords[word] {
+			q.keywords = append(q.keywords, word)
+		}
+	}
+	return q
+}
+
+// findEvent resolves a natural-language event reference against the
+// calendar. When several occurrences of the same meeting match, the next one
+// is used; matches with different titles are reported as ambiguous.
+func (h *Handler) findEvent(query string) (*api.Event, error) {
+	now := h.now()
+	q := parseEventQuery(query, now)
+
+	from, to := now, now.Add(eventSearchWindow)
+	if q.when != nil && q.when.HasDate {
+		day := q.when.Start
+		from = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+		to = from.AddDate(0, 0, 1)
+		if from.Before(now) {
+			from = now
+		}
+	}
+
+	events, err := h.calendar.ListEvents(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up events: %v", err)
+	}
+
+	var best []api.Event
+	bestScore := -1
+	for _, event := range events {
+		score, ok := q.score(event)
+		if !ok {
+			continue
+		}
+		if score > bestScore {
+			best, bestScore = nil, score
+		}
+		if score == bestScore {
+			best = append(best, event)
+		}
+	}
+
+	if len(best) == 0 {
+		return nil, fmt.Errorf("no upcoming event matches %q", query)
+	}
+
+	// Occurrences of the same meeting resolve to the next one
+	sort.Slice(best, func(i, j int) bool {
+		return best[i].StartTime.Before(best[j].StartTime)
+	})
+	sameTitle := true
+	for _, event := range best[1:] {
+		if event.Title != best[0].Title {
+			sameTitle = false
+		}
+	}
+
+	if sameTitle {
+		return &best[0], nil
+	}
+
+	var names []string
+	for _, event := range best {
+		names = append(names, fmt.Sprintf("%q at %s", event.Title, event.StartTime.In(h.config.Location()).Format("Mon Jan 2 15:04")))
+	}
+	return nil, fmt.Errorf("%q matches several events: %s", query, strings.Join(names, ", "))
+}
+
+// score reports whether event satisfies the query's time and attendee
+// constraints and how many of its title keywords it contains.
+func (q eventQuery) score(event api.Event) (int, bool) {
+	if q.when != nil && q.when.HasTime {
+		start := event.StartTime.In(q.when.Start.Location())
+		if start.Hour() != q.when.Start.Hour() || start.Minute() != q.when.Start.Minute() {
+			return 0, false
+		}
+	}
+
+	title := strings.ToLower(event.Title)
+	for _, person := range q.people {
+		if !strings.Contains(title, person) && !hasAttendee(event, person) {
+			return 0, false
+		}
+	}
+
+	score := 0
+	for _, keyword := range q.keywords {
+		if strings.Contains(title, keyword) || strings.Contains(strings.ToLower(event.Description), keyword) {
+			score++
+		}
+	}
+
+	// With nothing but words to go on, at least one of them has to match
+	if q.when == nil && len(q.people) == 0 && len(q.keywords) > 0 && score == 0 {
+		return 0, false
+	}
+	return score, true
+}
+
+// hasAttendee matches a name or address against the event's attendees.
+func hasAttendee(event api.Event, person string) bool {
+	for _, attendee := range event.Attendees {
+		attendee = strings.ToLower(attendee)
+		if strings.Contains(person, "@") {
+			if attendee == person {
+				return true
+			}
+			continue
+		}
+		local := attendee
+		if i := strings.Index(local, "@"); i >= 0 {
+			local = local[:i]
+		}
+		if strings.Contains(local, person) {
+			return true
+		}
+	}
+	return false
+}
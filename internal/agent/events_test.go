@@ -0,0 +1,290 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/api"
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/config"
+	"github.com/azme12/ai-agent-project/internal/ical"
+	"github.com/azme12/ai-agent-project/pkg/logger"
+)
+
+func newEventsHandler(t *testing.T, now time.Time) (*Handler, *api.MockCalendarService) {
+	t.Helper()
+
+	clk := clock.NewFake(now)
+	cal := api.NewMockCalendarService(clk)
+	for _, event := range allEvents(t, cal) {
+		if err := cal.DeleteEvent(event.ID); err != nil {
+			t.Fatalf("failed to clear mock calendar: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		TimeZone:        now.Location().String(),
+		UserEmail:       "me@example.com",
+		WorkingHours:    "09:00-17:00",
+		WorkingDays:     []int{1, 2, 3, 4, 5},
+		SlotSuggestions: 2,
+		ConflictPolicy:  "reject",
+	}
+	return NewHandler(cfg, logger.New(), clk, cal, &fakeMailSender{}, api.NewMockLanguageModel()), cal
+}
+
+func allEvents(t *testing.T, cal *api.MockCalendarService) []api.Event {
+	t.Helper()
+
+	events, err := cal.ListEvents(time.Time{}, time.Now().AddDate(10, 0, 0))
+	if err != nil {
+		t.Fatalf("failed to list events: %v", err)
+	}
+	return events
+}
+
+func schedule(t *testing.T, cal *api.MockCalendarService, title string, start time.Time, attendees ...string) *api.Event {
+	t.Helper()
+
+	event, err := cal.ScheduleMeeting(api.Event{
+		Title:     title,
+		StartTime: start,
+		EndTime:   start.Add(30 * time.Minute),
+		Attendees: attendees,
+	})
+	if err != nil {
+		t.Fatalf("failed to schedule %q: %v", title, err)
+	}
+	return event
+}
+
+func TestProcessTaskReschedulesEvent(t *testing.T) {
+	loc := loadLocation(t)
+	// Wednesday
+	h, cal := newEventsHandler(t, time.Date(2026, time.October, 14, 10, 0, 0, 0, loc))
+
+	sync := schedule(t, cal, "Roadmap sync", time.Date(2026, time.October, 14, 15, 0, 0, 0, loc), "sarah@example.com")
+	schedule(t, cal, "Hiring", time.Date(2026, time.October, 14, 15, 0, 0, 0, loc), "bob@example.com")
+	review := schedule(t, cal, "Design review", time.Date(2026, time.October, 15, 11, 0, 0, 0, loc))
+
+	tests := []struct {
+		task string
+		id   string
+		want time.Time
+	}{
+		{"move my 3pm with Sarah to Thursday", sync.ID, time.Date(2026, time.October, 15, 15, 0, 0, 0, loc)},
+		{"push the design review back by an hour", review.ID, time.Date(2026, time.October, 15, 12, 0, 0, 0, loc)},
+		{"reschedule the roadmap sync to 4:30pm", sync.ID, time.Date(2026, time.October, 15, 16, 30, 0, 0, loc)},
+	}
+
+	for _, tt := range tests {
+		result, err := h.ProcessTask(tt.task)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.task, err)
+		}
+		if result.Request.Type != "reschedule" || result.Request.EventID != tt.id {
+			t.Errorf("%q: got %s of %q, want reschedule of %q", tt.task, result.Request.Type, result.Request.EventID, tt.id)
+		}
+
+		event, err := cal.GetEvent(tt.id)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.task, err)
+		}
+		if !event.StartTime.Equal(tt.want) || event.EndTime.Sub(event.StartTime) != 30*time.Minute {
+			t.Errorf("%q: event runs %s-%s, want start %s", tt.task, event.StartTime, event.EndTime, tt.want)
+		}
+	}
+}
+
+func TestProcessTaskCancelsEvent(t *testing.T) {
+	loc := loadLocation(t)
+	h, cal := newEventsHandler(t, time.Date(2026, time.October, 14, 10, 0, 0, 0, loc))
+
+	schedule(t, cal, "Roadmap sync", time.Date(2026, time.October, 14, 15, 0, 0, 0, loc), "sarah@example.com")
+	hiring := schedule(t, cal, "Hiring", time.Date(2026, time.October, 14, 16, 0, 0, 0, loc), "bob@example.com")
+
+	if _, err := h.ProcessTask("cancel my meeting"); err == nil {
+		t.Error("ambiguous cancel succeeded")
+	}
+
+	if _, err := h.ProcessTask("cancel my meeting with bob@example.com"); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if _, err := cal.GetEvent(hiring.ID); err != api.ErrEventNotFound {
+		t.Errorf("cancelled event lookup returned %v, want ErrEventNotFound", err)
+	}
+	if events := allEvents(t, cal); len(events) != 1 || events[0].Title != "Roadmap sync" {
+		t.Errorf("remaining events %v, want only the roadmap sync", events)
+	}
+}
+
+func TestProcessTaskSchedulesRecurringMeeting(t *testing.T) {
+	loc := loadLocation(t)
+	// Wednesday
+	h, cal := newEventsHandler(t, time.Date(2026, time.October, 14, 10, 0, 0, 0, loc))
+
+	result, err := h.ProcessTask("weekly standup every Monday at 9am with team@example.com 3 times")
+	if err != nil {
+		t.Fatalf("ProcessTask failed: %v", err)
+	}
+	if result.Request.Type != "schedule" || result.Request.Recurrence != "FREQ=WEEKLY;BYDAY=MO;COUNT=3" {
+		t.Fatalf("got %s with recurrence %q", result.Request.Type, result.Request.Recurrence)
+	}
+
+	monday := func(day int) time.Time { return time.Date(2026, time.October, day, 9, 0, 0, 0, loc) }
+	events := allEvents(t, cal)
+	if len(events) != 3 {
+		t.Fatalf("got %d occurrences, want 3", len(events))
+	}
+	for i, day := range []int{19, 26} {
+		if !events[i].StartTime.Equal(monday(day)) || events[i].RecurringEventID != result.Request.EventID {
+			t.Errorf("occurrence %d = %s of %q, want %s of %q", i, events[i].StartTime, events[i].RecurringEventID, monday(day), result.Request.EventID)
+		}
+	}
+
+	// Cancelling one occurrence keeps the rest of the series
+	if err := cal.DeleteEvent(events[1].ID); err != nil {
+		t.Fatalf("failed to cancel occurrence: %v", err)
+	}
+	if _, err := cal.GetEvent(events[1].ID); err != api.ErrEventNotFound {
+		t.Errorf("cancelled occurrence lookup = %v, want ErrEventNotFound", err)
+	}
+	if got := len(allEvents(t, cal)); got != 2 {
+		t.Errorf("got %d occurrences after cancelling one, want 2", got)
+	}
+
+	busy, err := cal.FreeBusy([]string{"team@example.com"}, monday(19), monday(19).AddDate(0, 0, 21))
+	if err != nil {
+		t.Fatalf("FreeBusy failed: %v", err)
+	}
+	if len(busy.Attendees["team@example.com"]) != 2 {
+		t.Errorf("got busy periods %v, want two occurrences", busy.Attendees["team@example.com"])
+	}
+}
+
+func TestProcessTaskBooksOnNamedCalendar(t *testing.T) {
+	loc := loadLocation(t)
+	h, work := newEventsHandler(t, time.Date(2026, time.October, 14, 10, 0, 0, 0, loc))
+	personal, _ := newEventsHandler(t, time.Date(2026, time.October, 14, 10, 0, 0, 0, loc))
+	h.config.Calendars = []config.Calendar{
+		{Name: "work", ID: "primary", Role: config.RoleDefault},
+		{Name: "personal", ID: "me@example.com", Role: config.RoleWrite},
+		{Name: "holidays", ID: "holidays@example.com", Role: config.RoleRead},
+	}
+	h.calendar = api.NewMultiCalendar(h.config.Calendars, []api.CalendarProvider{work, personal.calendar, api.NewMockCalendarService(h.clock)})
+
+	result, err := h.ProcessTask(`schedule "Dentist" tomorrow at 4pm on my Personal calendar`)
+	if err != nil {
+		t.Fatalf("ProcessTask failed: %v", err)
+	}
+	if result.Request.Calendar != "personal" || result.Request.Title != "Dentist" {
+		t.Errorf("got %q on calendar %q, want Dentist on personal", result.Request.Title, result.Request.Calendar)
+	}
+	if !strings.HasSuffix(result.Outcome, "on the personal calendar") {
+		t.Errorf("outcome %q doesn't name the calendar", result.Outcome)
+	}
+	if events := allEvents(t, work); len(events) != 0 {
+		t.Errorf("work calendar got %v", events)
+	}
+	event, err := h.calendar.GetEvent(result.Request.EventID)
+	if err != nil || event.Calendar != "personal" || event.StartTime.Hour() != 16 {
+		t.Errorf("booked event = %+v, %v", event, err)
+	}
+
+	// Read-only calendars are refused before anything is booked
+	if _, err := h.ProcessTask(`schedule "Party" tomorrow at 6pm on the holidays calendar`); !errors.Is(err, api.ErrReadOnlyCalendar) {
+		t.Errorf("booking on holidays = %v, want ErrReadOnlyCalendar", err)
+	}
+}
+
+func TestImportCalendarSkipsDuplicates(t *testing.T) {
+	loc := loadLocation(t)
+	h, cal := newEventsHandler(t, time.Date(2026, time.October, 14, 10, 0, 0, 0, loc))
+
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:retro@example.com",
+		"SUMMARY:Retro",
+		"DTSTART:20261016T190000Z",
+		"DTEND:20261016T200000Z",
+		"ATTENDEE:mailto:sarah@example.com",
+		"RRULE:FREQ=WEEKLY;COUNT=2",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	for i, wantImported := range []int{1, 0} {
+		result, err := h.ImportCalendar(strings.NewReader(data))
+		if err != nil {
+			t.Fatalf("import %d failed: %v", i+1, err)
+		}
+		if len(result.Imported) != wantImported || len(result.Imported)+len(result.Skipped) != 1 {
+			t.Errorf("import %d: imported %d and skipped %d, want %d imported", i+1, len(result.Imported), len(result.Skipped), wantImported)
+		}
+	}
+
+	if got := len(allEvents(t, cal)); got != 2 {
+		t.Errorf("got %d occurrences, want 2", got)
+	}
+
+	if _, err := h.ImportCalendar(strings.NewReader("BEGIN:VEVENT")); !errors.Is(err, ErrInvalidCalendar) {
+		t.Errorf("invalid file: got %v, want ErrInvalidCalendar", err)
+	}
+}
+
+func TestScheduleTaskSendsInvites(t *testing.T) {
+	loc := loadLocation(t)
+	h, _ := newEventsHandler(t, time.Date(2026, time.October, 14, 10, 0, 0, 0, loc))
+	h.config.SendInvites = true
+	mail := h.email.(*fakeMailSender)
+
+	if _, err := h.ProcessTask("Schedule a meeting with sarah@example.com and me@example.com tomorrow at 2pm"); err != nil {
+		t.Fatalf("ProcessTask failed: %v", err)
+	}
+
+	if len(mail.sent) != 1 || strings.Join(mail.sent[0].To, ",") != "sarah@example.com" {
+		t.Fatalf("got %d invites (%v), want one to sarah@example.com", len(mail.sent), mail.sent)
+	}
+	attachments := mail.sent[0].Attachments
+	if len(attachments) != 1 || !strings.Contains(attachments[0].ContentType, "method=REQUEST") {
+		t.Fatalf("got attachments %v, want an invite", attachments)
+	}
+	invite, err := ical.Decode(bytes.NewReader(attachments[0].Content), loc)
+	if err != nil {
+		t.Fatalf("failed to read invite: %v", err)
+	}
+	if len(invite.Events) != 1 || !invite.Events[0].Start.Equal(time.Date(2026, time.October, 15, 14, 0, 0, 0, loc)) || len(invite.Events[0].Attendees) != 2 {
+		t.Errorf("invite does not describe the meeting: %+v", invite.Events)
+	}
+}
+
+func TestProcessTaskEmailsSeveralRecipients(t *testing.T) {
+	loc := loadLocation(t)
+	h, _ := newEventsHandler(t, time.Date(2026, time.October, 14, 10, 0, 0, 0, loc))
+	mail := h.email.(*fakeMailSender)
+
+	result, err := h.ProcessTask(`send an email to sarah@example.com and bob@example.com cc carol@example.com, dave@example.com bcc erin@example.com about "Budget" saying the numbers are in`)
+	if err != nil {
+		t.Fatalf("ProcessTask failed: %v", err)
+	}
+	if len(mail.sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(mail.sent))
+	}
+	sent := mail.sent[0]
+	if strings.Join(sent.To, ",") != "sarah@example.com,bob@example.com" ||
+		strings.Join(sent.CC, ",") != "carol@example.com,dave@example.com" ||
+		strings.Join(sent.BCC, ",") != "erin@example.com" {
+		t.Errorf("got to %v, cc %v, bcc %v", sent.To, sent.CC, sent.BCC)
+	}
+	if sent.Subject != "Budget" || sent.Text != "the numbers are in" {
+		t.Errorf("got subject %q and body %q", sent.Subject, sent.Text)
+	}
+	if result.Outcome != `Sent email "Budget" to sarah@example.com, bob@example.com` {
+		t.Errorf("got outcome %q", result.Outcome)
+	}
+}
@@ -1,24 +1,57 @@
 package agent
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/azme12/ai-agent-project/internal/api"
+	"github.com/azme12/ai-agent-project/internal/availability"
+	"github.com/azme12/ai-agent-project/internal/clock"
 	"github.com/azme12/ai-agent-project/internal/config"
+	"github.com/azme12/ai-agent-project/internal/rrule"
+	"github.com/azme12/ai-agent-project/internal/templates"
+	"github.com/azme12/ai-agent-project/internal/timeparse"
 	"github.com/azme12/ai-agent-project/pkg/logger"
 )
 
 type Handler struct {
-	config   *config.Config
-	logger   *logger.Logger
-	calendar *api.CalendarService
-	email    *api.EmailService
-	nlp      *api.GeminiService
+	config    *config.Config
+	logger    *logger.Logger
+	clock     clock.Clock
+	calendar  api.CalendarProvider
+	email     api.MailSender
+	nlp       api.LanguageModel
+	templates *templates.Engine
 }
 
+var (
+	emailRegex = regexp.MustCompile(emailPattern)
+	// "cc bob@example.com and carol@example.com", "bcc: dave@example.com"
+	copyRegex       = regexp.MustCompile(`(?i)\s*\b(bcc|cc)\b:?\s*((?:` + emailPattern + `(?:\s*,\s*(?:and\s+)?|\s+and\s+)?)+)`)
+	validEmailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
+	// "move my 3pm with Sarah to Thursday", "push the standup back by an hour"
+	rescheduleRegex = regexp.MustCompile(`(?i)^\s*(?:(?:please|can you|could you)\s+)*(?:reschedule|move|push|postpone|shift|bump)\s+(.+?)\s+(to|until|till|by|back)\s+(.+?)[\s.?!]*$`)
+	// "cancel my meeting with bob@example.com tomorrow"
+	cancelRegex = regexp.MustCompile(`(?i)^\s*(?:(?:please|can you|could you)\s+)*(?:cancel|call off|delete)\s+(.+?)[\s.?!]*$`)
+	shiftRegex  = regexp.MustCompile(`(?i)^(?:by\s+)?` + amountPattern)
+	// "find 30 minutes with alice@example.com and bob@example.com this week"
+	suggestRegex  = regexp.MustCompile(`(?i)^\s*(?:(?:please|can you|could you)\s+)*(?:find|suggest|look for)\b`)
+	durationRegex = regexp.MustCompile(`(?i)\b` + amountPattern)
+	// "on my personal calendar", "to the work calendar"
+	calendarRegex = regexp.MustCompile(`(?i)\s*\b(?:on|in|to)\s+(?:my|the)\s+(\S+)\s+calendar\b`)
+)
+
+// emailPattern matches an email address.
+const emailPattern = `[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`
+
+// amountPattern matches an amount of time such as "an hour" or "30 minutes".
+const amountPattern = `(an?|half an|\d+)\s*(minute|min|hour|hr|day|week)s?\b`
+
 type TaskRequest struct {
 	Type        string    `json:"type"`
 	Title       string    `json:"title"`
@@ -26,52 +59,264 @@ type TaskRequest struct {
 	Attendees   []string  `json:"attendees"`
 	StartTime   time.Time `json:"start_time"`
 	Duration    int       `json:"duration_minutes"`
-	To          string    `json:"to"`
+	To          []string  `json:"to"`
 	Subject     string    `json:"subject"`
 	Body        string    `json:"body"`
+
+	// CC and BCC are the copied recipients of an email task.
+	CC  []string `json:"cc,omitempty"`
+	BCC []string `json:"bcc,omitempty"`
+
+	// EventID and EventQuery identify the existing event a reschedule or
+	// cancel task applies to.
+	EventID      string `json:"event_id,omitempty"`
+	EventQuery   string `json:"event_query,omitempty"`
+	ShiftMinutes int    `json:"shift_minutes,omitempty"`
+
+	// Until ends the search window of a suggest task, which starts at
+	// StartTime.
+	Until *time.Time `json:"until,omitempty"`
+
+	// Recurrence is the RRULE of a schedule task for a repeating meeting,
+	// which starts at the first occurrence at or after StartTime.
+	Recurrence string `json:"recurrence,omitempty"`
+
+	// Calendar names the calendar a schedule task books on; empty selects
+	// the default calendar.
+	Calendar string `json:"calendar,omitempty"`
+
+	// target is the new time of a reschedule task as parsed, which may lack
+	// a date or a time of day to be taken from the event.
+	target *timeparse.Result
 }
 
-func NewHandler(cfg *config.Config, log *logger.Logger, cal *api.CalendarService, em *api.EmailService, nlp *api.GeminiService) *Handler {
+func NewHandler(cfg *config.Config, log *logger.Logger, clk clock.Clock, cal api.CalendarProvider, em api.MailSender, nlp api.LanguageModel) *Handler {
 	return &Handler{
-		config:   cfg,
-		logger:   log,
-		calendar: cal,
-		email:    em,
-		nlp:      nlp,
+		config:    cfg,
+		logger:    log,
+		clock:     clk,
+		calendar:  cal,
+		email:     em,
+		nlp:       nlp,
+		templates: templates.New(cfg.TemplatesDir, cfg.Locale, cfg.Location()),
 	}
 }
 
-func (h *Handler) ProcessTask(task string) error {
+// TaskResult describes what the agent understood and did for a task.
+type TaskResult struct {
+	Request  *TaskRequest
+	Response string
+	Outcome  string
+	// Conflicts and Suggestions are set when a meeting overlaps busy time.
+	Conflicts   []Conflict
+	Suggestions []availability.Slot
+}
+
+// ProcessTask understands and executes a task. The result is returned
+// whenever the task was understood, even if executing it failed.
+func (h *Handler) ProcessTask(task string) (*TaskResult, error) {
 	h.logger.Info("Processing task", "task", task)
 
 	// Use NLP to understand the command
-	response, err := h.nlp.ProcessCommand(task)
+	taskRequest, response, err := h.understandTask(task)
 	if err != nil {
-		h.logger.Error("Failed to process command with NLP", "error", err)
-		return err
+		h.logger.Error("Failed to understand task", "error", err)
+		return nil, err
 	}
 
 	h.logger.Info("NLP response", "response", response)
 
-	// Parse the task to determine action type
-	taskRequest, err := h.parseTask(task)
-	if err != nil {
-		h.logger.Error("Failed to parse task", "error", err)
-		return err
+	result := &TaskResult{
+		Request:  taskRequest,
+		Response: response,
 	}
 
 	// Route based on task type
 	switch taskRequest.Type {
 	case "schedule":
-		return h.handleScheduleTask(taskRequest)
+		err = h.handleScheduleTask(taskRequest, result)
+		result.Outcome = fmt.Sprintf("Scheduled %q at %s", taskRequest.Title, taskRequest.StartTime.Format(time.RFC3339))
+		if taskRequest.Recurrence != "" {
+			result.Outcome += fmt.Sprintf(" repeating %s", taskRequest.Recurrence)
+		}
+		if taskRequest.Calendar != "" {
+			result.Outcome += fmt.Sprintf(" on the %s calendar", taskRequest.Calendar)
+		}
+		if len(result.Conflicts) > 0 {
+			result.Outcome += fmt.Sprintf(" despite %d conflict(s)", len(result.Conflicts))
+		}
+	case "reschedule":
+		err = h.handleRescheduleTask(taskRequest)
+		result.Outcome = fmt.Sprintf("Rescheduled %q to %s", taskRequest.Title, taskRequest.StartTime.Format(time.RFC3339))
+	case "cancel":
+		err = h.handleCancelTask(taskRequest)
+		result.Outcome = fmt.Sprintf("Cancelled %q", taskRequest.Title)
+	case "suggest":
+		err = h.handleSuggestTask(taskRequest, result)
+		result.Outcome = fmt.Sprintf("Found %d open slot(s)", len(result.Suggestions))
 	case "email":
-		return h.handleEmailTask(taskRequest)
+		var queued string
+		queued, err = h.handleEmailTask(taskRequest)
+		result.Outcome = fmt.Sprintf("Sent email %q to %s", taskRequest.Subject, strings.Join(taskRequest.To, ", "))
+		if queued != "" {
+			result.Outcome = fmt.Sprintf("Queued email %q to %s as outbox message %s", taskRequest.Subject, strings.Join(taskRequest.To, ", "), queued)
+		}
 	case "reminder":
-		return h.handleReminderTask(taskRequest)
+		err = h.handleReminderTask(taskRequest)
+		result.Outcome = fmt.Sprintf("Sent reminder %q", taskRequest.Title)
 	default:
 		h.logger.Info("Unknown task type, using NLP response", "task", task)
-		return nil
+		result.Outcome = "No action taken"
 	}
+
+	if err != nil {
+		result.Outcome = ""
+		return result, err
+	}
+	return result, nil
+}
+
+// understandTask asks the language model for a structured task and returns it
+// together with the model's response. The keyword parser is only used when
+// no model is configured.
+func (h *Handler) understandTask(task string) (*TaskRequest, string, error) {
+	intent, err := h.nlp.ExtractIntent(task, h.now())
+	if errors.Is(err, api.ErrModelNotConfigured) {
+		h.logger.Info("Language model not configured, using offline parser")
+		taskRequest, err := h.parseTask(task)
+		return taskRequest, "", err
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to extract intent: %v", err)
+	}
+
+	taskRequest, err := h.taskFromIntent(intent)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid intent from language model: %v", err)
+	}
+
+	return taskRequest, intent.Response, nil
+}
+
+// taskFromIntent validates a model-extracted intent and converts it into a
+// TaskRequest, applying the same defaults as the offline parser.
+func (h *Handler) taskFromIntent(intent *api.Intent) (*TaskRequest, error) {
+	req := &TaskRequest{
+		Type:      intent.Type,
+		Title:     strings.TrimSpace(intent.Title),
+		Attendees: intent.Attendees,
+		Duration:  intent.Duration,
+		To:        intent.To,
+		CC:        intent.CC,
+		BCC:       intent.BCC,
+		Subject:   strings.TrimSpace(intent.Subject),
+		Body:      strings.TrimSpace(intent.Body),
+
+		EventQuery:   strings.TrimSpace(intent.EventQuery),
+		ShiftMinutes: intent.ShiftMinutes,
+		Recurrence:   strings.TrimPrefix(strings.TrimSpace(intent.Recurrence), "RRULE:"),
+		Calendar:     strings.TrimSpace(intent.Calendar),
+	}
+
+	valid := false
+	for _, t := range api.IntentTypes {
+		if req.Type == t {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, fmt.Errorf("unknown task type %q", req.Type)
+	}
+
+	if intent.StartTime != "" {
+		startTime, err := time.Parse(time.RFC3339, intent.StartTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start time %q", intent.StartTime)
+		}
+		req.StartTime = startTime
+	}
+
+	if req.Duration < 0 || req.Duration > 24*60 {
+		return nil, fmt.Errorf("invalid duration %d minutes", req.Duration)
+	}
+	if req.Duration == 0 && req.Type != "reschedule" && req.Type != "cancel" {
+		req.Duration = 30
+	}
+
+	for _, attendee := range req.Attendees {
+		if !validEmailRegex.MatchString(attendee) {
+			return nil, fmt.Errorf("invalid attendee email %q", attendee)
+		}
+	}
+	for _, recipients := range [][]string{req.To, req.CC, req.BCC} {
+		for _, recipient := range recipients {
+			if !validEmailRegex.MatchString(recipient) {
+				return nil, fmt.Errorf("invalid recipient email %q", recipient)
+			}
+		}
+	}
+
+	switch req.Type {
+	case "schedule":
+		if req.StartTime.IsZero() {
+			return nil, fmt.Errorf("schedule task requires a start time")
+		}
+		if req.Title == "" {
+			req.Title = "Meeting scheduled by AI Assistant"
+		}
+		if req.Recurrence != "" {
+			rule, err := rrule.Parse(req.Recurrence)
+			if err != nil {
+				return nil, fmt.Errorf("invalid recurrence %q: %v", req.Recurrence, err)
+			}
+			req.Recurrence = rule.String()
+		}
+	case "reschedule":
+		if req.EventQuery == "" {
+			return nil, fmt.Errorf("reschedule task requires an event")
+		}
+		if req.StartTime.IsZero() && req.ShiftMinutes == 0 {
+			return nil, fmt.Errorf("reschedule task requires a new time")
+		}
+		if intent.KeepTimeOfDay && !req.StartTime.IsZero() {
+			req.target = &timeparse.Result{Start: req.StartTime, HasDate: true}
+		}
+	case "cancel":
+		if req.EventQuery == "" {
+			return nil, fmt.Errorf("cancel task requires an event")
+		}
+	case "suggest":
+		now := h.now()
+		if req.StartTime.IsZero() || req.StartTime.Before(now) {
+			req.StartTime = now
+		}
+		until := req.StartTime.AddDate(0, 0, 7)
+		if intent.EndTime != "" {
+			endTime, err := time.Parse(time.RFC3339, intent.EndTime)
+			if err != nil {
+				return nil, fmt.Errorf("invalid end time %q", intent.EndTime)
+			}
+			until = endTime
+		}
+		req.Until = &until
+	case "email":
+		if req.Subject == "" {
+			req.Subject = "Message from AI Assistant"
+		}
+		if req.Body == "" {
+			req.Body = "This is an automated message from the AI Assistant."
+		}
+	case "reminder":
+		if req.StartTime.IsZero() {
+			return nil, fmt.Errorf("reminder task requires a time")
+		}
+		if req.Title == "" {
+			req.Title = "Reminder from AI Assistant"
+		}
+	}
+
+	return req, nil
 }
 
 func (h *Handler) parseTask(task string) (*TaskRequest, error) {
@@ -82,8 +327,21 @@ func (h *Handler) parseTask(task string) (*TaskRequest, error) {
 		Duration: 30, // Default 30 minutes
 	}
 
-	// Determine task type
-	if strings.Contains(lowerTask, "schedule") || strings.Contains(lowerTask, "meeting") {
+	// Determine task type. Changes to existing events are recognized by
+	// their leading verb, before "reschedule" can be mistaken for "schedule".
+	if m := rescheduleRegex.FindStringSubmatch(task); m != nil {
+		taskRequest.Type = "reschedule"
+		taskRequest.Duration = 0
+		return h.parseRescheduleTask(m[1], strings.ToLower(m[2]), m[3], taskRequest)
+	} else if m := cancelRegex.FindStringSubmatch(task); m != nil {
+		taskRequest.Type = "cancel"
+		taskRequest.Duration = 0
+		taskRequest.EventQuery = m[1]
+		return taskRequest, nil
+	} else if suggestRegex.MatchString(task) {
+		taskRequest.Type = "suggest"
+		return h.parseSuggestTask(task, taskRequest)
+	} else if strings.Contains(lowerTask, "schedule") || strings.Contains(lowerTask, "meeting") {
 		taskRequest.Type = "schedule"
 		return h.parseScheduleTask(task, taskRequest)
 	} else if strings.Contains(lowerTask, "email") || strings.Contains(lowerTask, "send") {
@@ -92,6 +350,10 @@ func (h *Handler) parseTask(task string) (*TaskRequest, error) {
 	} else if strings.Contains(lowerTask, "remind") || strings.Contains(lowerTask, "reminder") {
 		taskRequest.Type = "reminder"
 		return h.parseReminderTask(task, taskRequest)
+	} else if _, _, ok := rrule.FromText(task, h.now()); ok {
+		// "weekly standup every Monday at 9am"
+		taskRequest.Type = "schedule"
+		return h.parseScheduleTask(task, taskRequest)
 	}
 
 	// Default to general task
@@ -102,15 +364,33 @@ func (h *Handler) parseTask(task string) (*TaskRequest, error) {
 
 func (h *Handler) parseScheduleTask(task string, req *TaskRequest) (*TaskRequest, error) {
 	// Extract attendees (email addresses)
-	emailRegex := regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
 	emails := emailRegex.FindAllString(task, -1)
 	req.Attendees = emails
 
+	// Extract the calendar to book on when a configured one is named
+	for _, m := range calendarRegex.FindAllStringSubmatchIndex(task, -1) {
+		if calendar, err := h.calendarNamed(task[m[2]:m[3]]); err == nil {
+			req.Calendar = calendar.Name
+			task = task[:m[0]] + task[m[1]:]
+			break
+		}
+	}
+
+	// Extract the recurrence, leaving the time of its first occurrence
+	when := task
+	if rule, rest, ok := rrule.FromText(task, h.now()); ok {
+		req.Recurrence = rule.String()
+		when = rest
+	}
+
 	// Extract time information
-	req.StartTime = h.extractTime(task)
+	startTime, endTime := h.extractTimeRange(when)
+	req.StartTime = startTime
 
 	// Extract duration
-	if strings.Contains(task, "hour") || strings.Contains(task, "hr") {
+	if !endTime.IsZero() {
+		req.Duration = int(endTime.Sub(startTime).Minutes())
+	} else if strings.Contains(task, "hour") || strings.Contains(task, "hr") {
 		req.Duration = 60
 	} else if strings.Contains(task, "30") {
 		req.Duration = 30
@@ -127,13 +407,103 @@ func (h *Handler) parseScheduleTask(task string, req *TaskRequest) (*TaskRequest
 	return req, nil
 }
 
+// parseRescheduleTask fills in a reschedule of the event described by query,
+// either to the time named in when or, after "by" or "back", by an amount of
+// time.
+func (h *Handler) parseRescheduleTask(query, sep, when string, req *TaskRequest) (*TaskRequest, error) {
+	req.EventQuery = query
+
+	if m := shiftRegex.FindStringSubmatch(when); m != nil && (sep == "by" || sep == "back") {
+		req.ShiftMinutes = amountMinutes(m)
+		return req, nil
+	}
+
+	result, ok := timeparse.Parse(when, h.now())
+	if !ok {
+		return nil, fmt.Errorf("could not understand the new time %q", when)
+	}
+
+	req.target = &result
+	req.StartTime = result.Start
+	if !result.End.IsZero() {
+		req.Duration = int(result.End.Sub(result.Start).Minutes())
+	}
+	return req, nil
+}
+
+// parseSuggestTask fills in a search for open slots with the attendees,
+// meeting length and window named in the task. The window defaults to the
+// coming week.
+func (h *Handler) parseSuggestTask(task string, req *TaskRequest) (*TaskRequest, error) {
+	req.Attendees = emailRegex.FindAllString(task, -1)
+
+	if m := durationRegex.FindStringSubmatch(task); m != nil {
+		if minutes := amountMinutes(m); minutes <= 24*60 {
+			req.Duration = minutes
+		}
+	}
+
+	now := h.now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	// Days until next Monday
+	toMonday := (8 - int(today.Weekday())) % 7
+	if toMonday == 0 {
+		toMonday = 7
+	}
+
+	from, until := now, now.AddDate(0, 0, 7)
+	lowerTask := strings.ToLower(task)
+	switch {
+	case strings.Contains(lowerTask, "this week"):
+		until = today.AddDate(0, 0, toMonday)
+	case strings.Contains(lowerTask, "next week"):
+		from = today.AddDate(0, 0, toMonday)
+		until = from.AddDate(0, 0, 7)
+	default:
+		if result, ok := timeparse.Parse(task, now); ok && result.HasDate {
+			day := result.Start
+			from = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+			until = from.AddDate(0, 0, 1)
+			if from.Before(now) {
+				from = now
+			}
+		}
+	}
+
+	req.StartTime = from
+	req.Until = &until
+	return req, nil
+}
+
+// amountMinutes converts an amountPattern match into minutes.
+func amountMinutes(m []string) int {
+	amount := 1
+	unit := strings.ToLower(m[2])
+	switch strings.ToLower(m[1]) {
+	case "a", "an":
+	case "half an":
+		amount, unit = 30, "minute"
+	default:
+		amount, _ = strconv.Atoi(m[1])
+	}
+
+	return amount * map[string]int{"minute": 1, "min": 1, "hour": 60, "hr": 60, "day": 24 * 60, "week": 7 * 24 * 60}[unit]
+}
+
 func (h *Handler) parseEmailTask(task string, req *TaskRequest) (*TaskRequest, error) {
-	// Extract recipient
-	emailRegex := regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
-	emails := emailRegex.FindAllString(task, -1)
-	if len(emails) > 0 {
-		req.To = emails[0]
+	// Addresses after "cc" or "bcc" are copied and the others are
+	// recipients. The copy clauses are removed so they don't end up in the
+	// body.
+	for _, m := range copyRegex.FindAllStringSubmatch(task, -1) {
+		emails := emailRegex.FindAllString(m[2], -1)
+		if strings.EqualFold(m[1], "bcc") {
+			req.BCC = append(req.BCC, emails...)
+		} else {
+			req.CC = append(req.CC, emails...)
+		}
 	}
+	task = copyRegex.ReplaceAllString(task, "")
+	req.To = emailRegex.FindAllString(task, -1)
 
 	// Extract subject
 	req.Subject = h.extractSubject(task)
@@ -160,41 +530,34 @@ func (h *Handler) parseReminderTask(task string, req *TaskRequest) (*TaskRequest
 	return req, nil
 }
 
+// now returns the current time in the configured time zone.
+func (h *Handler) now() time.Time {
+	return h.clock.Now().In(h.config.Location())
+}
+
+// extractTime returns the start time mentioned in the task.
 func (h *Handler) extractTime(task string) time.Time {
-	now := time.Now()
-
-	// Look for time patterns
-	if strings.Contains(task, "tomorrow") {
-		return now.AddDate(0, 0, 1)
-	} else if strings.Contains(task, "next week") {
-		return now.AddDate(0, 0, 7)
-	} else if strings.Contains(task, "today") {
-		return now
-	}
-
-	// Look for specific times
-	timeRegex := regexp.MustCompile(`(\d{1,2}):?(\d{2})?\s*(am|pm)?`)
-	matches := timeRegex.FindStringSubmatch(task)
-	if len(matches) > 0 {
-		hour := 9 // Default to 9 AM
-		if len(matches) > 1 {
-			// Parse hour
-			if h, err := fmt.Sscanf(matches[1], "%d", &hour); err == nil && h > 0 {
-				// Handle AM/PM
-				if len(matches) > 3 && strings.ToLower(matches[3]) == "pm" && hour != 12 {
-					hour += 12
-				} else if len(matches) > 3 && strings.ToLower(matches[3]) == "am" && hour == 12 {
-					hour = 0
-				}
-			}
-		}
+	start, _ := h.extractTimeRange(task)
+	return start
+}
 
-		// Set time for today
-		return time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+// extractTimeRange parses the date, time and optional end time mentioned in
+// the task in the configured time zone. Dates without a time of day default
+// to 9 AM and tasks without any time default to one hour from now.
+func (h *Handler) extractTimeRange(task string) (time.Time, time.Time) {
+	now := h.now()
+
+	result, ok := timeparse.Parse(task, now)
+	if !ok {
+		return now.Add(1 * time.Hour), time.Time{}
+	}
+
+	if !result.HasTime {
+		start := result.Start
+		return time.Date(start.Year(), start.Month(), start.Day(), 9, 0, 0, 0, start.Location()), time.Time{}
 	}
 
-	// Default to 1 hour from now
-	return now.Add(1 * time.Hour)
+	return result.Start, result.End
 }
 
 func (h *Handler) extractTitle(task string) string {
@@ -240,33 +603,173 @@ func (h *Handler) extractBody(task string) string {
 	return ""
 }
 
-func (h *Handler) handleScheduleTask(req *TaskRequest) error {
+func (h *Handler) handleScheduleTask(req *TaskRequest, result *TaskResult) error {
 	h.logger.Info("Handling schedule task", "title", req.Title, "attendees", req.Attendees, "startTime", req.StartTime)
 
 	if len(req.Attendees) == 0 {
 		req.Attendees = []string{h.config.UserEmail}
 	}
 
+	// Recurring meetings start at their first occurrence, which is the only
+	// one checked for conflicts
+	if req.Recurrence != "" {
+		rule, err := rrule.Parse(req.Recurrence)
+		if err != nil {
+			return fmt.Errorf("invalid recurrence %q: %v", req.Recurrence, err)
+		}
+		first := rule.First(req.StartTime)
+		if first.IsZero() || (!rule.Until.IsZero() && first.After(rule.Until)) {
+			return fmt.Errorf("recurrence %s has no occurrences after %s", req.Recurrence, req.StartTime.Format(time.RFC3339))
+		}
+		req.StartTime = first
+	}
+
+	if req.Calendar != "" {
+		calendar, err := h.calendarNamed(req.Calendar)
+		if err != nil {
+			return err
+		}
+		if calendar.Role == config.RoleRead {
+			return fmt.Errorf("cannot book on %s: %w", calendar.Name, api.ErrReadOnlyCalendar)
+		}
+		req.Calendar = calendar.Name
+	}
+
 	duration := time.Duration(req.Duration) * time.Minute
-	return h.calendar.ScheduleMeeting(req.Attendees, req.StartTime, duration, req.Title)
+	if err := h.checkAvailability(req, duration, result); err != nil {
+		return err
+	}
+
+	event, err := h.calendar.ScheduleMeeting(api.Event{
+		Title:      req.Title,
+		StartTime:  req.StartTime,
+		EndTime:    req.StartTime.Add(duration),
+		Attendees:  req.Attendees,
+		Recurrence: req.Recurrence,
+		Calendar:   req.Calendar,
+	})
+	if err != nil {
+		return err
+	}
+
+	req.EventID = event.ID
+	if h.config.SendInvites {
+		h.sendInvites(event)
+	}
+	return nil
+}
+
+// calendarNamed returns the configured calendar called name, ignoring case.
+func (h *Handler) calendarNamed(name string) (config.Calendar, error) {
+	for _, calendar := range h.config.Calendars {
+		if strings.EqualFold(calendar.Name, name) {
+			return calendar, nil
+		}
+	}
+	return config.Calendar{}, fmt.Errorf("%w: %s", api.ErrUnknownCalendar, name)
+}
+
+func (h *Handler) handleRescheduleTask(req *TaskRequest) error {
+	h.logger.Info("Handling reschedule task", "event", req.EventQuery, "startTime", req.StartTime)
+
+	event, err := h.resolveEvent(req)
+	if err != nil {
+		return err
+	}
+
+	start := req.StartTime
+	switch {
+	case req.ShiftMinutes != 0:
+		start = event.StartTime.Add(time.Duration(req.ShiftMinutes) * time.Minute)
+	case req.target != nil:
+		start = rescheduledStart(event.StartTime.In(h.config.Location()), *req.target)
+	}
+	if start.IsZero() {
+		return fmt.Errorf("reschedule task requires a new time")
+	}
+
+	updated, err := h.calendar.RescheduleEvent(event.ID, start, time.Duration(req.Duration)*time.Minute)
+	if err != nil {
+		return err
+	}
+
+	req.Title = updated.Title
+	req.StartTime = updated.StartTime
+	return nil
+}
+
+func (h *Handler) handleCancelTask(req *TaskRequest) error {
+	h.logger.Info("Handling cancel task", "event", req.EventQuery)
+
+	event, err := h.resolveEvent(req)
+	if err != nil {
+		return err
+	}
+
+	return h.calendar.DeleteEvent(event.ID)
+}
+
+// resolveEvent looks up the event a task refers to and records its ID and
+// title on the request.
+func (h *Handler) resolveEvent(req *TaskRequest) (*api.Event, error) {
+	var event *api.Event
+	var err error
+	if req.EventID != "" {
+		event, err = h.calendar.GetEvent(req.EventID)
+	} else {
+		event, err = h.findEvent(req.EventQuery)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	req.EventID = event.ID
+	req.Title = event.Title
+	return event, nil
+}
+
+// rescheduledStart combines the parsed new time with the event's current
+// start: a bare day keeps the time of day and a bare time keeps the day.
+func rescheduledStart(current time.Time, target timeparse.Result) time.Time {
+	t := target.Start
+	switch {
+	case target.HasDate && !target.HasTime:
+		return time.Date(t.Year(), t.Month(), t.Day(), current.Hour(), current.Minute(), 0, 0, current.Location())
+	case target.HasTime && !target.HasDate:
+		return time.Date(current.Year(), current.Month(), current.Day(), t.Hour(), t.Minute(), 0, 0, current.Location())
+	default:
+		return t
+	}
 }
 
-func (h *Handler) handleEmailTask(req *TaskRequest) error {
+// handleEmailTask sends the email and returns its outbox message ID when
+// it was only queued for delivery.
+func (h *Handler) handleEmailTask(req *TaskRequest) (string, error) {
 	h.logger.Info("Handling email task", "to", req.To, "subject", req.Subject)
 
-	if req.To == "" {
-		req.To = h.config.UserEmail
+	if len(req.To) == 0 && len(req.CC) == 0 && len(req.BCC) == 0 {
+		req.To = []string{h.config.UserEmail}
 	}
 
-	return h.email.SendEmail(req.To, req.Subject, req.Body)
+	message := api.Message{To: req.To, CC: req.CC, BCC: req.BCC, Subject: req.Subject, Text: req.Body}
+	if outbox, ok := h.email.(*Outbox); ok {
+		queued, err := outbox.Queue(message)
+		if err != nil {
+			return "", err
+		}
+		return queued.ID, nil
+	}
+	return "", h.email.Send(message)
 }
 
 func (h *Handler) handleReminderTask(req *TaskRequest) error {
 	h.logger.Info("Handling reminder task", "title", req.Title, "time", req.StartTime)
 
 	// Send reminder email
-	subject := "Reminder: " + req.Title
-	body := fmt.Sprintf("This is a reminder for: %s\nScheduled for: %s", req.Title, req.StartTime.Format("2006-01-02 15:04:05"))
-
-	return h.email.SendEmail(h.config.UserEmail, subject, body)
+	message, err := templateMessage(h.templates, templates.ReminderTemplate, h.config.UserEmail,
+		templates.Reminder{Title: req.Title, Time: req.StartTime})
+	if err != nil {
+		return err
+	}
+	return h.email.Send(message)
 }
@@ -0,0 +1,186 @@
+package agent
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/api"
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/config"
+	"github.com/azme12/ai-agent-project/pkg/logger"
+)
+
+// fakeLanguageModel answers every command with the same intent or error.
+type fakeLanguageModel struct {
+	intent *api.Intent
+	err    error
+}
+
+func (f *fakeLanguageModel) ProcessCommand(command string) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (f *fakeLanguageModel) ExtractIntent(command string, now time.Time) (*api.Intent, error) {
+	return f.intent, f.err
+}
+
+func TestUnderstandTask(t *testing.T) {
+	now := time.Date(2026, time.October, 14, 10, 0, 0, 0, time.UTC)
+	start := "2026-10-15T15:00:00Z"
+	startTime := time.Date(2026, time.October, 15, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		intent api.Intent
+		err    string
+		check  func(t *testing.T, req *TaskRequest)
+	}{
+		{name: "unknown type", intent: api.Intent{Type: "dance"}, err: `unknown task type "dance"`},
+		{name: "missing type", intent: api.Intent{}, err: `unknown task type ""`},
+		{name: "start time not RFC 3339", intent: api.Intent{Type: "schedule", StartTime: "tomorrow at 3pm"}, err: "invalid start time"},
+		{name: "negative duration", intent: api.Intent{Type: "schedule", StartTime: start, Duration: -30}, err: "invalid duration -30 minutes"},
+		{name: "duration over a day", intent: api.Intent{Type: "schedule", StartTime: start, Duration: 24*60 + 1}, err: "invalid duration 1441 minutes"},
+		{name: "invalid attendee", intent: api.Intent{Type: "schedule", StartTime: start, Attendees: []string{"bob"}}, err: `invalid attendee email "bob"`},
+		{name: "invalid recipient", intent: api.Intent{Type: "email", To: []string{"bob@example.com"}, CC: []string{"carol@"}}, err: `invalid recipient email "carol@"`},
+		{name: "invalid recurrence", intent: api.Intent{Type: "schedule", StartTime: start, Recurrence: "FREQ=HOURLY"}, err: "invalid recurrence"},
+		{name: "schedule without start", intent: api.Intent{Type: "schedule"}, err: "requires a start time"},
+		{name: "reschedule without event", intent: api.Intent{Type: "reschedule", StartTime: start}, err: "requires an event"},
+		{name: "reschedule without new time", intent: api.Intent{Type: "reschedule", EventQuery: "standup"}, err: "requires a new time"},
+		{name: "cancel without event", intent: api.Intent{Type: "cancel"}, err: "requires an event"},
+		{name: "reminder without time", intent: api.Intent{Type: "reminder"}, err: "requires a time"},
+		{name: "suggest end time not RFC 3339", intent: api.Intent{Type: "suggest", EndTime: "friday"}, err: "invalid end time"},
+		{
+			name:   "schedule defaults",
+			intent: api.Intent{Type: "schedule", StartTime: start, Attendees: []string{"bob@example.com"}, Recurrence: " RRULE:FREQ=WEEKLY;BYDAY=MO ", Response: "Booking it"},
+			check: func(t *testing.T, req *TaskRequest) {
+				if !req.StartTime.Equal(startTime) || req.Duration != 30 || req.Title != "Meeting scheduled by AI Assistant" || req.Recurrence != "FREQ=WEEKLY;BYDAY=MO" {
+					t.Errorf("got %+v", req)
+				}
+			},
+		},
+		{
+			name:   "schedule keeps given fields",
+			intent: api.Intent{Type: "schedule", StartTime: start, Duration: 24 * 60, Title: "  Planning ", Calendar: " work "},
+			check: func(t *testing.T, req *TaskRequest) {
+				if req.Duration != 24*60 || req.Title != "Planning" || req.Calendar != "work" {
+					t.Errorf("got %+v", req)
+				}
+			},
+		},
+		{
+			name:   "reschedule to a day keeps the time of day",
+			intent: api.Intent{Type: "reschedule", EventQuery: "3pm with Sarah", StartTime: start, KeepTimeOfDay: true},
+			check: func(t *testing.T, req *TaskRequest) {
+				if req.Duration != 0 || req.EventQuery != "3pm with Sarah" || req.target == nil || !req.target.Start.Equal(startTime) {
+					t.Errorf("got %+v", req)
+				}
+			},
+		},
+		{
+			name:   "reschedule by an amount",
+			intent: api.Intent{Type: "reschedule", EventQuery: "standup", ShiftMinutes: -60},
+			check: func(t *testing.T, req *TaskRequest) {
+				if req.ShiftMinutes != -60 || req.target != nil || !req.StartTime.IsZero() {
+					t.Errorf("got %+v", req)
+				}
+			},
+		},
+		{
+			name:   "cancel has no duration",
+			intent: api.Intent{Type: "cancel", EventQuery: "standup"},
+			check: func(t *testing.T, req *TaskRequest) {
+				if req.Duration != 0 {
+					t.Errorf("got %+v", req)
+				}
+			},
+		},
+		{
+			name:   "suggest starts now and searches a week",
+			intent: api.Intent{Type: "suggest", StartTime: "2026-10-01T09:00:00Z"},
+			check: func(t *testing.T, req *TaskRequest) {
+				if !req.StartTime.Equal(now) || req.Until == nil || !req.Until.Equal(now.AddDate(0, 0, 7)) || req.Duration != 30 {
+					t.Errorf("got %+v", req)
+				}
+			},
+		},
+		{
+			name:   "suggest until an end time",
+			intent: api.Intent{Type: "suggest", StartTime: start, EndTime: "2026-10-16T17:00:00Z", Duration: 60},
+			check: func(t *testing.T, req *TaskRequest) {
+				if !req.StartTime.Equal(startTime) || !req.Until.Equal(time.Date(2026, time.October, 16, 17, 0, 0, 0, time.UTC)) || req.Duration != 60 {
+					t.Errorf("got %+v", req)
+				}
+			},
+		},
+		{
+			name:   "email defaults",
+			intent: api.Intent{Type: "email", To: []string{"bob@example.com"}, BCC: []string{"dave@example.com"}},
+			check: func(t *testing.T, req *TaskRequest) {
+				if req.Subject != "Message from AI Assistant" || req.Body != "This is an automated message from the AI Assistant." || req.BCC[0] != "dave@example.com" {
+					t.Errorf("got %+v", req)
+				}
+			},
+		},
+		{
+			name:   "reminder defaults",
+			intent: api.Intent{Type: "reminder", StartTime: start},
+			check: func(t *testing.T, req *TaskRequest) {
+				if req.Title != "Reminder from AI Assistant" || req.Duration != 30 {
+					t.Errorf("got %+v", req)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			intent := tt.intent
+			h := newIntentHandler(now, &fakeLanguageModel{intent: &intent})
+
+			req, response, err := h.understandTask("a command")
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got %v, want an error containing %q", err, tt.err)
+				}
+				if !strings.HasPrefix(err.Error(), "invalid intent from language model") {
+					t.Errorf("error %q doesn't say the intent was invalid", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("understandTask failed: %v", err)
+			}
+			if response != tt.intent.Response {
+				t.Errorf("response = %q, want %q", response, tt.intent.Response)
+			}
+			tt.check(t, req)
+		})
+	}
+}
+
+func TestUnderstandTaskFallsBackToParser(t *testing.T) {
+	now := time.Date(2026, time.October, 14, 10, 0, 0, 0, time.UTC)
+
+	// Without a model the keyword parser understands the task
+	h := newIntentHandler(now, &fakeLanguageModel{err: api.ErrModelNotConfigured})
+	req, response, err := h.understandTask("Send an email to bob@example.com")
+	if err != nil {
+		t.Fatalf("understandTask failed: %v", err)
+	}
+	if req.Type != "email" || len(req.To) != 1 || req.To[0] != "bob@example.com" || response != "" {
+		t.Errorf("got %+v with response %q", req, response)
+	}
+
+	// Other model failures are reported rather than guessed around
+	h = newIntentHandler(now, &fakeLanguageModel{err: errors.New("quota exceeded")})
+	if _, _, err := h.understandTask("Send an email to bob@example.com"); err == nil || !strings.Contains(err.Error(), "failed to extract intent: quota exceeded") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func newIntentHandler(now time.Time, nlp api.LanguageModel) *Handler {
+	cfg := &config.Config{TimeZone: "UTC", UserEmail: "me@example.com"}
+	return NewHandler(cfg, logger.New(), clock.NewFake(now), nil, &fakeMailSender{}, nlp)
+}
@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/api"
+	"github.com/azme12/ai-agent-project/internal/ical"
+	"github.com/azme12/ai-agent-project/internal/templates"
+)
+
+// ErrInvalidCalendar is returned when an imported iCalendar file cannot be
+// read.
+var ErrInvalidCalendar = errors.New("invalid calendar")
+
+// ImportResult lists the events created from an iCalendar file and those
+// that were left out.
+type ImportResult struct {
+	Imported []api.Event    `json:"imported"`
+	Skipped  []SkippedEvent `json:"skipped,omitempty"`
+}
+
+// SkippedEvent is an imported event that was not created.
+type SkippedEvent struct {
+	Title     string    `json:"title"`
+	StartTime time.Time `json:"start_time"`
+	Reason    string    `json:"reason"`
+}
+
+// ExportCalendar writes the events starting between from and to as an
+// iCalendar feed.
+func (h *Handler) ExportCalendar(w io.Writer, from, to time.Time) error {
+	events, err := h.calendar.ListEvents(from, to)
+	if err != nil {
+		return err
+	}
+
+	calendar := &ical.Calendar{}
+	for _, event := range events {
+		calendar.Events = append(calendar.Events, icalEvent(event))
+	}
+	return calendar.Encode(w, h.clock.Now())
+}
+
+// ImportCalendar creates the events of an iCalendar file. Events already on
+// the calendar with the same title and start time are skipped, so importing
+// a file twice doesn't duplicate them.
+func (h *Handler) ImportCalendar(r io.Reader) (*ImportResult, error) {
+	calendar, err := ical.Decode(r, h.config.Location())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCalendar, err)
+	}
+
+	result := &ImportResult{Imported: []api.Event{}}
+	for _, vevent := range calendar.Events {
+		event := api.EventFromICal(vevent, "")
+		skip := func(reason string) {
+			result.Skipped = append(result.Skipped, SkippedEvent{Title: event.Title, StartTime: event.StartTime, Reason: reason})
+		}
+
+		// Cancelled events and edits of single occurrences of a series
+		// aren't imported
+		if event.Status == "cancelled" || !vevent.RecurrenceID.IsZero() {
+			continue
+		}
+		if event.Title == "" {
+			event.Title = "Imported event"
+		}
+
+		existing, err := h.calendar.ListEvents(event.StartTime.Add(-time.Minute), event.StartTime.Add(time.Minute))
+		if err != nil {
+			return nil, fmt.Errorf("failed to check for existing events: %v", err)
+		}
+		if duplicate(existing, event) {
+			skip("already on the calendar")
+			continue
+		}
+
+		event.Status = ""
+		created, err := h.calendar.ScheduleMeeting(event)
+		if err != nil {
+			h.logger.Error("Failed to import event", "title", event.Title, "error", err)
+			skip(err.Error())
+			continue
+		}
+		result.Imported = append(result.Imported, *created)
+	}
+
+	h.logger.Info("Imported calendar", "imported", len(result.Imported), "skipped", len(result.Skipped))
+	return result, nil
+}
+
+func duplicate(existing []api.Event, event api.Event) bool {
+	for _, e := range existing {
+		if e.Title == event.Title && e.StartTime.Equal(event.StartTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// sendInvites emails an invitation with an .ics attachment to every
+// attendee of a newly scheduled event other than the user. Failures are
+// logged, since the event has already been booked.
+func (h *Handler) sendInvites(event *api.Event) {
+	invite, err := calendarAttachment(&ical.Calendar{
+		Method:    ical.MethodRequest,
+		Organizer: h.config.FromEmail,
+		Events:    []ical.Event{icalEvent(*event)},
+	}, h.clock.Now())
+	if err != nil {
+		h.logger.Error("Failed to build invite", "event", event.ID, "error", err)
+		return
+	}
+
+	invitation := templates.Invitation{Meeting: templateMeeting(*event)}
+	for _, attendee := range event.Attendees {
+		if strings.EqualFold(attendee, h.config.UserEmail) {
+			continue
+		}
+		// Rendered for each attendee, who may have their own template
+		message, err := templateMessage(h.templates, templates.InvitationTemplate, attendee, invitation)
+		if err != nil {
+			h.logger.Error("Failed to render invite", "event", event.ID, "attendee", attendee, "error", err)
+			continue
+		}
+		message.Attachments = []api.Attachment{invite}
+		if err := h.email.Send(message); err != nil {
+			h.logger.Error("Failed to send invite", "event", event.ID, "attendee", attendee, "error", err)
+		}
+	}
+}
+
+// icalEvent converts event to a VEVENT whose UID is unique beyond the
+// agent's calendar, so that clients update rather than duplicate events they
+// have seen before.
+func icalEvent(event api.Event) ical.Event {
+	return api.ICalEvent(event, event.ID+"@ai-agent")
+}
+
+// calendarAttachment encodes calendar as an .ics email attachment.
+func calendarAttachment(calendar *ical.Calendar, stamp time.Time) (api.Attachment, error) {
+	var buf bytes.Buffer
+	if err := calendar.Encode(&buf, stamp); err != nil {
+		return api.Attachment{}, err
+	}
+
+	contentType := "text/calendar; charset=utf-8"
+	filename := "event.ics"
+	if calendar.Method != "" {
+		contentType += "; method=" + calendar.Method
+	}
+	if calendar.Method == ical.MethodRequest {
+		filename = "invite.ics"
+	}
+	return api.Attachment{Filename: filename, ContentType: contentType, Content: buf.Bytes()}, nil
+}
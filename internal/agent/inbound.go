@@ -0,0 +1,338 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/api"
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/config"
+	"github.com/azme12/ai-agent-project/internal/store"
+	"github.com/azme12/ai-agent-project/internal/templates"
+	"github.com/azme12/ai-agent-project/pkg/logger"
+)
+
+const (
+	InboundProcessed = "processed"
+	InboundIgnored   = "ignored"
+)
+
+const (
+	// inboundRetention is how long the IDs of received emails are kept to
+	// recognize redeliveries.
+	inboundRetention = 30 * 24 * time.Hour
+	// maxInboundTask bounds the length of a task taken from an email.
+	maxInboundTask = 2000
+)
+
+// InboundResult says what became of a received email.
+type InboundResult struct {
+	Status string `json:"status"`
+	// Reason says why an email was ignored
+	Reason string `json:"reason,omitempty"`
+	TaskID string `json:"task_id,omitempty"`
+}
+
+// Inbound runs the tasks users email to the agent, from the inbound webhook
+// or an IMAP mailbox, and replies to the sender with the outcome in the same
+// thread. Only allowed senders whose address is authenticated by DMARC or
+// DKIM are answered, and automatic emails, including the agent's own
+// replies, are ignored, so that it can't be made to loop or to act for
+// strangers. Each email is run once, however often it is delivered.
+type Inbound struct {
+	config    *config.Config
+	logger    *logger.Logger
+	clock     clock.Clock
+	email     api.MailSender
+	templates *templates.Engine
+	process   func(task string) (*store.Task, error)
+	// mailbox is polled for new emails when IMAP is configured
+	mailbox *api.IMAPMailbox
+	path    string
+
+	mu sync.Mutex
+	// seen holds when each received email, by message ID, was run
+	seen map[string]time.Time
+	// polling is set once the poller is launched, which is what closes done
+	polling  bool
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewInbound creates the inbound email processor, which runs tasks with
+// process and sends its replies through em.
+func NewInbound(cfg *config.Config, log *logger.Logger, clk clock.Clock, em api.MailSender, process func(task string) (*store.Task, error)) *Inbound {
+	in := &Inbound{
+		config:    cfg,
+		logger:    log,
+		clock:     clk,
+		email:     em,
+		templates: templates.New(cfg.TemplatesDir, cfg.Locale, cfg.Location()),
+		process:   process,
+		path:      filepath.Join(cfg.DataDir, "inbound.json"),
+		seen:      make(map[string]time.Time),
+		stopCh:    make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	if cfg.IMAPHost != "" {
+		in.mailbox = api.NewIMAPMailbox(cfg)
+	}
+	return in
+}
+
+// Start loads the IDs of emails already received and starts polling the
+// IMAP mailbox, if one is configured.
+func (in *Inbound) Start() error {
+	in.mu.Lock()
+	err := store.LoadJSON(in.path, &in.seen)
+	if in.seen == nil {
+		in.seen = make(map[string]time.Time)
+	}
+	in.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to load received emails: %v", err)
+	}
+
+	if in.mailbox == nil {
+		return nil
+	}
+	in.logger.Info("Polling IMAP mailbox", "host", in.config.IMAPHost, "mailbox", in.config.IMAPMailbox)
+	in.mu.Lock()
+	in.polling = true
+	in.mu.Unlock()
+	go in.run()
+	return nil
+}
+
+// Stop waits for the email being processed by the poller, if any, to
+// finish. It is safe to call more than once.
+func (in *Inbound) Stop(ctx context.Context) error {
+	in.stopOnce.Do(func() {
+		close(in.stopCh)
+	})
+
+	in.mu.Lock()
+	polling := in.polling
+	in.mu.Unlock()
+	if !polling {
+		return nil
+	}
+
+	select {
+	case <-in.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("inbound email poller did not stop: %v", ctx.Err())
+	}
+}
+
+func (in *Inbound) run() {
+	defer close(in.done)
+
+	interval := time.Duration(in.config.IMAPPollSeconds) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		in.poll()
+		select {
+		case <-in.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll runs the tasks in the unseen emails of the IMAP mailbox.
+func (in *Inbound) poll() {
+	err := in.mailbox.Receive(func(data []byte) error {
+		select {
+		case <-in.stopCh:
+			return fmt.Errorf("inbound email poller stopped")
+		default:
+		}
+
+		email, err := api.ParseEmail(bytes.NewReader(data), in.config.InboundAuthServ)
+		if err != nil {
+			// Marked seen, since it won't parse any better next time
+			in.logger.Error("Failed to parse received email", "error", err)
+			return nil
+		}
+		_, err = in.Receive(email)
+		return err
+	})
+	if err != nil {
+		in.logger.Error("Failed to poll IMAP mailbox", "error", err)
+	}
+}
+
+// Receive runs the task in email and replies to the sender. It fails only
+// when the email can't be recorded as received; the outcome of the task,
+// failed or not, is in the reply.
+func (in *Inbound) Receive(email *api.InboundEmail) (*InboundResult, error) {
+	if reason := in.refuse(email); reason != "" {
+		in.logger.Info("Ignored email", "from", email.From, "subject", email.Subject, "reason", reason)
+		return &InboundResult{Status: InboundIgnored, Reason: reason}, nil
+	}
+
+	// The email is recorded before the task runs, so that a redelivery
+	// while it runs, or after a crash, doesn't run it twice
+	id := messageKey(email)
+	in.mu.Lock()
+	if _, ok := in.seen[id]; ok {
+		in.mu.Unlock()
+		in.logger.Info("Ignored duplicate email", "from", email.From, "message_id", email.MessageID)
+		return &InboundResult{Status: InboundIgnored, Reason: "duplicate"}, nil
+	}
+	now := in.clock.Now()
+	in.seen[id] = now
+	for key, at := range in.seen {
+		if now.Sub(at) > inboundRetention {
+			delete(in.seen, key)
+		}
+	}
+	if err := store.SaveJSON(in.path, in.seen); err != nil {
+		delete(in.seen, id)
+		in.mu.Unlock()
+		return nil, fmt.Errorf("failed to record received email: %v", err)
+	}
+	in.mu.Unlock()
+
+	in.logger.Info("Received task by email", "from", email.From, "subject", email.Subject)
+	subject := replySubject(email.Subject)
+	reply := templates.TaskReply{Subject: subject, Task: emailTask(email.PlainText(), subject)}
+	result := &InboundResult{Status: InboundProcessed}
+	if reply.Task == "" {
+		reply.Error = "the email has no task in it"
+	} else {
+		record, err := in.process(reply.Task)
+		if record != nil {
+			result.TaskID = record.ID
+			reply.Outcome = record.Outcome
+		}
+		if err != nil {
+			reply.Error = err.Error()
+		}
+	}
+
+	if err := in.reply(email, reply); err != nil {
+		in.logger.Error("Failed to reply to email", "to", email.From, "error", err)
+	}
+	return result, nil
+}
+
+// refuse returns why email must not be answered, or "" when it may.
+func (in *Inbound) refuse(email *api.InboundEmail) string {
+	switch {
+	// The agent's own replies are marked as automatic, which keeps it from
+	// answering them when it sends email from the address it reads
+	case email.AutoSubmitted:
+		return "automatic email"
+	// Without DMARC or a DKIM signature of its domain, the From address
+	// could be anyone's
+	case email.Authentication == "fail",
+		email.Authentication == "" && !in.config.InboundAllowUnauthenticated:
+		return "sender not authenticated"
+	case !in.allowed(email.From):
+		return "sender not allowed"
+	}
+	return ""
+}
+
+// allowed reports whether address is one of the allowed senders or in one
+// of their domains, given as "example.com" or "@example.com".
+func (in *Inbound) allowed(address string) bool {
+	address = strings.ToLower(address)
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return false
+	}
+	for _, sender := range in.config.InboundAllowedSenders {
+		sender = strings.ToLower(strings.TrimSpace(sender))
+		if sender == address || strings.TrimPrefix(sender, "@") == address[at+1:] {
+			return true
+		}
+	}
+	return false
+}
+
+// reply sends the outcome of a task to the sender of email, threaded
+// under it.
+func (in *Inbound) reply(email *api.InboundEmail, reply templates.TaskReply) error {
+	message, err := templateMessage(in.templates, templates.TaskReplyTemplate, email.From, reply)
+	if err != nil {
+		return err
+	}
+
+	// Mark it as an automatic reply so other agents don't answer it
+	message.Headers = map[string]string{"Auto-Submitted": "auto-replied"}
+	if email.MessageID != "" {
+		references := append([]string{}, email.References...)
+		if len(references) == 0 && email.InReplyTo != "" {
+			references = append(references, email.InReplyTo)
+		}
+		references = append(references, email.MessageID)
+		message.Headers["In-Reply-To"] = email.MessageID
+		message.Headers["References"] = strings.Join(references, " ")
+	}
+	return in.email.Send(message)
+}
+
+// messageKey identifies email among redeliveries, by its Message-ID or,
+// without one, by its content.
+func messageKey(email *api.InboundEmail) string {
+	if email.MessageID != "" {
+		return email.MessageID
+	}
+	sum := sha256.Sum256([]byte(email.From + "\x00" + email.Subject + "\x00" + email.Text + "\x00" + email.HTML))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+var (
+	replyPrefixRegex = regexp.MustCompile(`(?i)^\s*((re|fwd?|aw|wg|rv|tr)\s*:\s*)+`)
+	// quoteHeaderRegex matches the line introducing a quoted email, such as
+	// "On Mon, Oct 12, 2026 at 9:00 AM Bob <bob@example.com> wrote:"
+	quoteHeaderRegex = regexp.MustCompile(`(?i)^(on\s.*\swrote:|-+\s*original message\s*-+|from:\s.*@.*)$`)
+)
+
+// replySubject returns subject without its reply and forward prefixes.
+func replySubject(subject string) string {
+	return strings.TrimSpace(replyPrefixRegex.ReplaceAllString(subject, ""))
+}
+
+// emailTask takes the task from the body of an email, leaving out quoted
+// text and the signature, or from its subject when the body is empty.
+func emailTask(body, subject string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if line == "-- " || trimmed == "--" || quoteHeaderRegex.MatchString(trimmed) {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		lines = append(lines, trimmed)
+	}
+
+	task := strings.TrimSpace(strings.Join(lines, "\n"))
+	if task == "" {
+		task = subject
+	}
+	if runes := []rune(task); len(runes) > maxInboundTask {
+		task = string(runes[:maxInboundTask])
+	}
+	return task
+}
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/api"
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/config"
+	"github.com/azme12/ai-agent-project/internal/store"
+	"github.com/azme12/ai-agent-project/pkg/logger"
+)
+
+func newTestInbound(t *testing.T, dir string, sender api.MailSender, tasks *[]string) *Inbound {
+	t.Helper()
+
+	cfg := &config.Config{
+		DataDir:               dir,
+		FromEmail:             "agent@example.com",
+		InboundAllowedSenders: []string{"bob@example.com", "@team.example.com"},
+		TimeZone:              "UTC",
+	}
+	clk := clock.NewFake(time.Date(2026, time.October, 16, 9, 0, 0, 0, time.UTC))
+	in := NewInbound(cfg, logger.New(), clk, sender, func(task string) (*store.Task, error) {
+		*tasks = append(*tasks, task)
+		if strings.Contains(task, "impossible") {
+			return &store.Task{ID: "task-2", Input: task}, errors.New("no free slot")
+		}
+		return &store.Task{ID: "task-1", Input: task, Outcome: "Scheduled Standup"}, nil
+	})
+	if err := in.Start(); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	return in
+}
+
+func TestInboundRepliesInThread(t *testing.T) {
+	dir := t.TempDir()
+	sender := &flakyMailSender{}
+	var tasks []string
+	in := newTestInbound(t, dir, sender, &tasks)
+
+	email := &api.InboundEmail{
+		From:           "bob@example.com",
+		Authentication: "pass",
+		Subject:        "Re: Standup",
+		Text:           "Schedule the standup tomorrow at 9\n\n-- \nBob\n\nOn Thu, Oct 15, 2026 at 9:00 AM Agent <agent@example.com> wrote:\n> Done: something else\n",
+		MessageID:      "<2@example.com>",
+		InReplyTo:      "<1@example.com>",
+		References:     []string{"<1@example.com>"},
+	}
+	result, err := in.Receive(email)
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	if result.Status != InboundProcessed || result.TaskID != "task-1" {
+		t.Errorf("got %+v", result)
+	}
+	if len(tasks) != 1 || tasks[0] != "Schedule the standup tomorrow at 9" {
+		t.Fatalf("ran tasks %q", tasks)
+	}
+
+	if len(sender.sent) != 1 {
+		t.Fatalf("sent %d replies, want 1", len(sender.sent))
+	}
+	reply := sender.sent[0]
+	if reply.To[0] != "bob@example.com" || reply.Subject != "Re: Standup" || !strings.Contains(reply.Text, "Done: Scheduled Standup") {
+		t.Errorf("got reply %+v", reply)
+	}
+	if reply.Headers["In-Reply-To"] != "<2@example.com>" || reply.Headers["References"] != "<1@example.com> <2@example.com>" ||
+		reply.Headers["Auto-Submitted"] != "auto-replied" {
+		t.Errorf("got headers %v", reply.Headers)
+	}
+
+	// Redeliveries are ignored, after a restart too
+	in = newTestInbound(t, dir, sender, &tasks)
+	if result, _ := in.Receive(email); result.Status != InboundIgnored || len(tasks) != 1 {
+		t.Errorf("redelivery got %+v and ran %q", result, tasks)
+	}
+
+	// A failed task is reported, and the subject is the task without a body
+	if _, err := in.Receive(&api.InboundEmail{From: "carol@team.example.com", Authentication: "pass", Subject: "Fwd: an impossible meeting"}); err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	if tasks[1] != "an impossible meeting" {
+		t.Errorf("ran %q", tasks[1])
+	}
+	if reply := sender.sent[1]; !strings.Contains(reply.Text, "I couldn't complete your task: no free slot") || reply.Headers["In-Reply-To"] != "" {
+		t.Errorf("got reply %+v", reply)
+	}
+}
+
+func TestInboundIgnores(t *testing.T) {
+	sender := &flakyMailSender{}
+	var tasks []string
+	in := newTestInbound(t, t.TempDir(), sender, &tasks)
+
+	tests := []struct {
+		email  *api.InboundEmail
+		reason string
+	}{
+		{&api.InboundEmail{From: "mallory@example.org", Text: "Email everyone", Authentication: "pass"}, "sender not allowed"},
+		{&api.InboundEmail{From: "bob@example.com.evil.org", Text: "Email everyone", Authentication: "pass"}, "sender not allowed"},
+		{&api.InboundEmail{From: "bob@example.com", Text: "Email everyone", Authentication: "fail"}, "sender not authenticated"},
+		// Never checked, so the From address can't be trusted
+		{&api.InboundEmail{From: "bob@example.com", Text: "Email everyone"}, "sender not authenticated"},
+		{&api.InboundEmail{From: "bob@example.com", Text: "I'm out of office", Authentication: "pass", AutoSubmitted: true}, "automatic email"},
+	}
+	for _, test := range tests {
+		result, err := in.Receive(test.email)
+		if err != nil {
+			t.Fatalf("%s: %v", test.email.From, err)
+		}
+		if result.Status != InboundIgnored || result.Reason != test.reason {
+			t.Errorf("%s: got %+v, want %s", test.email.From, result, test.reason)
+		}
+	}
+	if len(tasks) != 0 || len(sender.sent) != 0 {
+		t.Errorf("ran %q and sent %d replies", tasks, len(sender.sent))
+	}
+
+	// Unchecked emails can be accepted explicitly, but failures never are
+	in.config.InboundAllowUnauthenticated = true
+	if result, _ := in.Receive(&api.InboundEmail{From: "bob@example.com", Text: "Email everyone"}); result.Status != InboundProcessed {
+		t.Errorf("unchecked email got %+v with unauthenticated senders allowed", result)
+	}
+	if result, _ := in.Receive(&api.InboundEmail{From: "bob@example.com", Text: "Email all", Authentication: "fail"}); result.Status != InboundIgnored {
+		t.Errorf("failed email got %+v with unauthenticated senders allowed", result)
+	}
+}
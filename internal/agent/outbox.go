@@ -0,0 +1,434 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/api"
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/config"
+	"github.com/azme12/ai-agent-project/internal/store"
+	"github.com/azme12/ai-agent-project/pkg/logger"
+)
+
+const (
+	OutboxPending   = "pending"
+	OutboxSent      = "sent"
+	OutboxFailed    = "failed"
+	OutboxCancelled = "cancelled"
+)
+
+const (
+	// maxRetryDelay caps the backoff between delivery attempts.
+	maxRetryDelay = 1 * time.Hour
+	// outboxRetention is how long sent and cancelled messages are kept for
+	// inspection. Failed messages are kept until retried or cancelled.
+	outboxRetention = 7 * 24 * time.Hour
+)
+
+var (
+	ErrOutboxNotFound = errors.New("outbox message not found")
+	ErrOutboxSent     = errors.New("message was already sent")
+)
+
+// OutboxMessage is an email in the outbox.
+type OutboxMessage struct {
+	ID        string      `json:"id"`
+	Status    string      `json:"status"`
+	Message   api.Message `json:"message"`
+	Attempts  int         `json:"attempts"`
+	LastError string      `json:"last_error,omitempty"`
+	// NextAttempt is when a pending message is sent next
+	NextAttempt *time.Time `json:"next_attempt,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	SentAt      *time.Time `json:"sent_at,omitempty"`
+}
+
+// Outbox is a MailSender that persists every message before a background
+// worker delivers it, so that emails survive provider outages and restarts.
+// Failed deliveries are retried with exponential backoff and jitter, and
+// messages are marked failed after the configured number of attempts, or
+// at once when the provider rejects them. A message being sent when the
+// process dies is sent again on the next start.
+type Outbox struct {
+	logger      *logger.Logger
+	clock       clock.Clock
+	sender      api.MailSender
+	path        string
+	maxAttempts int
+	retryDelay  time.Duration
+	// jitter randomizes a retry delay so that messages failed by the same
+	// outage aren't all retried at once
+	jitter func(time.Duration) time.Duration
+
+	mu       sync.Mutex
+	messages map[string]*OutboxMessage
+	wake     chan struct{}
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewOutbox loads the outbox from the data directory. Messages are
+// delivered through sender once Start is called.
+func NewOutbox(cfg *config.Config, log *logger.Logger, clk clock.Clock, sender api.MailSender) (*Outbox, error) {
+	maxAttempts := cfg.OutboxMaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	o := &Outbox{
+		logger:      log,
+		clock:       clk,
+		sender:      sender,
+		path:        filepath.Join(cfg.DataDir, "outbox.json"),
+		maxAttempts: maxAttempts,
+		retryDelay:  time.Duration(cfg.OutboxRetrySeconds) * time.Second,
+		// Wait between half and all of the delay
+		jitter: func(d time.Duration) time.Duration {
+			return d/2 + time.Duration(random.Int63n(int64(d/2)+1))
+		},
+		messages: make(map[string]*OutboxMessage),
+		wake:     make(chan struct{}, 1),
+		stopCh:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if o.retryDelay <= 0 {
+		o.retryDelay = time.Second
+	}
+
+	var messages []*OutboxMessage
+	if err := store.LoadJSON(o.path, &messages); err != nil {
+		return nil, fmt.Errorf("failed to load outbox: %v", err)
+	}
+	for _, message := range messages {
+		o.messages[message.ID] = message
+	}
+	return o, nil
+}
+
+func (o *Outbox) Start() {
+	o.logger.Info("Starting outbox", "pending", len(o.List(OutboxPending)))
+	go o.run()
+}
+
+// Stop waits for the message being sent, if any, to finish. Pending
+// messages stay in the outbox for the next start. It is safe to call more
+// than once.
+func (o *Outbox) Stop(ctx context.Context) error {
+	o.stopOnce.Do(func() {
+		o.logger.Info("Stopping outbox")
+		close(o.stopCh)
+	})
+
+	select {
+	case <-o.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("outbox did not stop: %v", ctx.Err())
+	}
+}
+
+// Send queues message for delivery. It fails only when the message is
+// invalid or can't be saved.
+func (o *Outbox) Send(message api.Message) error {
+	_, err := o.Queue(message)
+	return err
+}
+
+// Queue is Send that also returns the queued message, so callers can
+// refer to it under /outbox.
+func (o *Outbox) Queue(message api.Message) (*OutboxMessage, error) {
+	if err := message.Validate(); err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	now := o.clock.Now()
+	queued := &OutboxMessage{
+		ID:          store.NewID(),
+		Status:      OutboxPending,
+		Message:     message,
+		NextAttempt: &now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	o.messages[queued.ID] = queued
+	if err := o.save(); err != nil {
+		delete(o.messages, queued.ID)
+		o.mu.Unlock()
+		return nil, fmt.Errorf("failed to queue email: %v", err)
+	}
+	copied := *queued
+	o.mu.Unlock()
+
+	o.logger.Info("Queued email", "id", queued.ID, "subject", message.Subject)
+	o.notify()
+	return &copied, nil
+}
+
+// List returns the messages with the given status, or all of them when
+// status is empty, oldest first. Attachments are listed without their
+// content.
+func (o *Outbox) List(status string) []OutboxMessage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	messages := []OutboxMessage{}
+	for _, message := range o.sorted() {
+		if status == "" || message.Status == status {
+			messages = append(messages, snapshot(message))
+		}
+	}
+	return messages
+}
+
+// Get returns the message with the given ID.
+func (o *Outbox) Get(id string) (*OutboxMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	message, ok := o.messages[id]
+	if !ok {
+		return nil, ErrOutboxNotFound
+	}
+	m := snapshot(message)
+	return &m, nil
+}
+
+// Retry sends a failed, cancelled or pending message now, with a fresh set
+// of attempts.
+func (o *Outbox) Retry(id string) (*OutboxMessage, error) {
+	m, err := o.update(id, func(message *OutboxMessage, now time.Time) {
+		message.Status = OutboxPending
+		message.Attempts = 0
+		message.NextAttempt = &now
+	})
+	if err != nil {
+		return nil, err
+	}
+	o.notify()
+	return m, nil
+}
+
+// Cancel stops a pending or failed message from being sent.
+func (o *Outbox) Cancel(id string) (*OutboxMessage, error) {
+	return o.update(id, func(message *OutboxMessage, now time.Time) {
+		message.Status = OutboxCancelled
+		message.NextAttempt = nil
+	})
+}
+
+// update changes a message that hasn't been sent yet.
+func (o *Outbox) update(id string, change func(message *OutboxMessage, now time.Time)) (*OutboxMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	message, ok := o.messages[id]
+	if !ok {
+		return nil, ErrOutboxNotFound
+	}
+	if message.Status == OutboxSent {
+		return nil, ErrOutboxSent
+	}
+
+	previous := *message
+	now := o.clock.Now()
+	change(message, now)
+	message.UpdatedAt = now
+	if err := o.save(); err != nil {
+		*message = previous
+		return nil, err
+	}
+	m := snapshot(message)
+	return &m, nil
+}
+
+func (o *Outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers messages as they come due until Stop. Like the scheduler's
+// ticker, the timer is real because clock.Clock only reports the time: it
+// just decides when to look again, while whether a message is due is always
+// judged by o.clock, so an early or late wake never sends one too soon.
+func (o *Outbox) run() {
+	defer close(o.done)
+
+	for {
+		timer := time.NewTimer(o.deliver())
+		select {
+		case <-timer.C:
+		case <-o.wake:
+			timer.Stop()
+		case <-o.stopCh:
+			timer.Stop()
+			o.logger.Info("Outbox stopped")
+			return
+		}
+	}
+}
+
+// deliver sends every message that is due, oldest first, and returns how
+// long to wait until the next one is.
+func (o *Outbox) deliver() time.Duration {
+	for {
+		select {
+		case <-o.stopCh:
+			return 0
+		default:
+		}
+
+		id, message, ok := o.nextDue()
+		if !ok {
+			return o.untilNext()
+		}
+		// Sent without holding the lock, so the API stays responsive
+		o.record(id, o.sender.Send(message))
+	}
+}
+
+// nextDue returns the oldest pending message whose next attempt is due.
+func (o *Outbox) nextDue() (string, api.Message, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := o.clock.Now()
+	for _, message := range o.sorted() {
+		if message.Status == OutboxPending && (message.NextAttempt == nil || !message.NextAttempt.After(now)) {
+			return message.ID, message.Message, true
+		}
+	}
+	return "", api.Message{}, false
+}
+
+// untilNext returns how long until a pending message is due.
+func (o *Outbox) untilNext() time.Duration {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := o.clock.Now()
+	wait := maxRetryDelay
+	for _, message := range o.messages {
+		if message.Status == OutboxPending && message.NextAttempt != nil {
+			if d := message.NextAttempt.Sub(now); d < wait {
+				wait = d
+			}
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// record saves the outcome of an attempt to send a message.
+func (o *Outbox) record(id string, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	message, ok := o.messages[id]
+	if !ok {
+		return
+	}
+	now := o.clock.Now()
+	message.Attempts++
+	message.UpdatedAt = now
+
+	switch {
+	case err == nil:
+		// Even if it was cancelled meanwhile, it has gone out
+		message.Status = OutboxSent
+		message.SentAt = &now
+		message.NextAttempt = nil
+		message.LastError = ""
+		o.logger.Info("Sent email", "id", id, "subject", message.Message.Subject, "attempts", message.Attempts)
+
+	case message.Status != OutboxPending:
+		message.LastError = err.Error()
+
+	case errors.Is(err, api.ErrRejected) || message.Attempts >= o.maxAttempts:
+		message.Status = OutboxFailed
+		message.NextAttempt = nil
+		message.LastError = err.Error()
+		o.logger.Error("Giving up on email", "id", id, "subject", message.Message.Subject, "attempts", message.Attempts, "error", err)
+
+	default:
+		next := now.Add(o.backoff(message.Attempts))
+		message.NextAttempt = &next
+		message.LastError = err.Error()
+		o.logger.Error("Failed to send email, will retry", "id", id, "attempts", message.Attempts, "retry_at", next, "error", err)
+	}
+
+	o.prune(now)
+	if err := o.save(); err != nil {
+		o.logger.Error("Failed to save outbox", "error", err)
+	}
+}
+
+// backoff returns the delay before the attempt after the given number of
+// failed ones: retryDelay doubled for each earlier failure, up to
+// maxRetryDelay, then jittered.
+func (o *Outbox) backoff(attempts int) time.Duration {
+	delay := o.retryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return o.jitter(delay)
+}
+
+// prune forgets sent and cancelled messages older than outboxRetention.
+// Callers hold o.mu.
+func (o *Outbox) prune(now time.Time) {
+	cutoff := now.Add(-outboxRetention)
+	for id, message := range o.messages {
+		if (message.Status == OutboxSent || message.Status == OutboxCancelled) && message.UpdatedAt.Before(cutoff) {
+			delete(o.messages, id)
+		}
+	}
+}
+
+// sorted returns the messages oldest first. Callers hold o.mu.
+func (o *Outbox) sorted() []*OutboxMessage {
+	messages := make([]*OutboxMessage, 0, len(o.messages))
+	for _, message := range o.messages {
+		messages = append(messages, message)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		if !messages[i].CreatedAt.Equal(messages[j].CreatedAt) {
+			return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+		}
+		return messages[i].ID < messages[j].ID
+	})
+	return messages
+}
+
+// save writes the outbox to disk. Callers hold o.mu.
+func (o *Outbox) save() error {
+	return store.SaveJSON(o.path, o.sorted())
+}
+
+// snapshot copies message for callers, leaving out attachment content.
+func snapshot(message *OutboxMessage) OutboxMessage {
+	m := *message
+	m.Message.Attachments = nil
+	for _, attachment := range message.Message.Attachments {
+		attachment.Content = nil
+		m.Message.Attachments = append(m.Message.Attachments, attachment)
+	}
+	return m
+}
@@ -0,0 +1,215 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/api"
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/config"
+	"github.com/azme12/ai-agent-project/pkg/logger"
+)
+
+// flakyMailSender fails with the queued errors before succeeding.
+type flakyMailSender struct {
+	errs []error
+	sent []api.Message
+	// attempts counts every call to Send, failed or not
+	attempts int
+}
+
+func (f *flakyMailSender) Send(message api.Message) error {
+	f.attempts++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return err
+	}
+	f.sent = append(f.sent, message)
+	return nil
+}
+
+func newTestOutbox(t *testing.T, dir string, clk clock.Clock, sender api.MailSender) *Outbox {
+	t.Helper()
+
+	cfg := &config.Config{DataDir: dir, OutboxMaxAttempts: 3, OutboxRetrySeconds: 30}
+	o, err := NewOutbox(cfg, logger.New(), clk, sender)
+	if err != nil {
+		t.Fatalf("failed to open outbox: %v", err)
+	}
+	// Retry delays are exact so the test can step to them
+	o.jitter = func(d time.Duration) time.Duration { return d }
+	return o
+}
+
+func queue(t *testing.T, o *Outbox, subject string) string {
+	t.Helper()
+
+	if err := o.Send(api.Message{To: []string{"me@example.com"}, Subject: subject, Text: "body"}); err != nil {
+		t.Fatalf("failed to queue %s: %v", subject, err)
+	}
+	for _, message := range o.List(OutboxPending) {
+		if message.Message.Subject == subject {
+			return message.ID
+		}
+	}
+	t.Fatalf("%s is not pending", subject)
+	return ""
+}
+
+func assertOutbox(t *testing.T, o *Outbox, id, status string, attempts int) {
+	t.Helper()
+
+	message, err := o.Get(id)
+	if err != nil {
+		t.Fatalf("failed to get %s: %v", id, err)
+	}
+	if message.Status != status || message.Attempts != attempts {
+		t.Errorf("message is %s after %d attempts, want %s after %d", message.Status, message.Attempts, status, attempts)
+	}
+}
+
+func TestOutboxRetriesWithBackoff(t *testing.T) {
+	dir := t.TempDir()
+	clk := clock.NewFake(time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC))
+	timeout := fmt.Errorf("sendgrid API error: 503 - unavailable")
+	sender := &flakyMailSender{errs: []error{timeout, timeout}}
+	o := newTestOutbox(t, dir, clk, sender)
+
+	id := queue(t, o, "Daily Summary")
+	if wait := o.deliver(); wait != 30*time.Second {
+		t.Errorf("first retry in %s, want 30s", wait)
+	}
+	assertOutbox(t, o, id, OutboxPending, 1)
+
+	// Nothing is sent before the retry is due, then the delay doubles
+	clk.Advance(29 * time.Second)
+	o.deliver()
+	clk.Advance(time.Second)
+	if wait := o.deliver(); wait != time.Minute {
+		t.Errorf("second retry in %s, want 1m", wait)
+	}
+	if sender.attempts != 2 {
+		t.Errorf("got %d attempts, want 2", sender.attempts)
+	}
+
+	clk.Advance(time.Minute)
+	o.deliver()
+	assertOutbox(t, o, id, OutboxSent, 3)
+	if len(sender.sent) != 1 || sender.sent[0].Subject != "Daily Summary" {
+		t.Errorf("sent %+v", sender.sent)
+	}
+
+	// The outcome is saved
+	reopened := newTestOutbox(t, dir, clk, sender)
+	assertOutbox(t, reopened, id, OutboxSent, 3)
+
+	if got := o.backoff(20); got != maxRetryDelay {
+		t.Errorf("backoff after 20 attempts = %s, want %s", got, maxRetryDelay)
+	}
+}
+
+func TestOutboxDeadLetters(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC))
+	down := errors.New("connection refused")
+	sender := &flakyMailSender{errs: []error{down, down, down}}
+	o := newTestOutbox(t, t.TempDir(), clk, sender)
+
+	id := queue(t, o, "Weekly Summary")
+	for i := 0; i < 3; i++ {
+		o.deliver()
+		clk.Advance(maxRetryDelay)
+	}
+	assertOutbox(t, o, id, OutboxFailed, 3)
+	if failed := o.List(OutboxFailed); len(failed) != 1 || failed[0].LastError != "connection refused" {
+		t.Errorf("failed messages = %+v", failed)
+	}
+
+	// Retrying starts over and delivers it
+	if _, err := o.Retry(id); err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	o.deliver()
+	assertOutbox(t, o, id, OutboxSent, 1)
+	if _, err := o.Cancel(id); !errors.Is(err, ErrOutboxSent) {
+		t.Errorf("cancelling a sent message returned %v", err)
+	}
+
+	// A rejected message isn't retried
+	sender.errs = []error{fmt.Errorf("%w: sendgrid API error: 400 - bad address", api.ErrRejected)}
+	rejected := queue(t, o, "Rejected")
+	o.deliver()
+	assertOutbox(t, o, rejected, OutboxFailed, 1)
+
+	// A cancelled message isn't sent
+	cancelled := queue(t, o, "Cancelled")
+	if _, err := o.Cancel(cancelled); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	o.deliver()
+	assertOutbox(t, o, cancelled, OutboxCancelled, 0)
+
+	if _, err := o.Get("missing"); !errors.Is(err, ErrOutboxNotFound) {
+		t.Errorf("got %v for a missing message", err)
+	}
+	if err := o.Send(api.Message{Subject: "Nobody", Text: "body"}); err == nil {
+		t.Error("queued a message without recipients")
+	}
+}
+
+func TestOutboxSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	clk := clock.NewFake(time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC))
+	sender := &flakyMailSender{}
+
+	// Queued but never delivered, as when the process stops
+	invite := api.Attachment{Filename: "invite.ics", ContentType: "text/calendar", Content: []byte("BEGIN:VCALENDAR")}
+	if err := newTestOutbox(t, dir, clk, sender).Send(api.Message{
+		To: []string{"bob@example.com"}, Subject: "Invitation", Text: "body", Attachments: []api.Attachment{invite},
+	}); err != nil {
+		t.Fatalf("failed to queue: %v", err)
+	}
+
+	o := newTestOutbox(t, dir, clk, sender)
+	pending := o.List(OutboxPending)
+	if len(pending) != 1 || pending[0].Message.Attachments[0].Content != nil {
+		t.Fatalf("pending = %+v, want one message listed without attachment content", pending)
+	}
+	o.deliver()
+	if len(sender.sent) != 1 || string(sender.sent[0].Attachments[0].Content) != "BEGIN:VCALENDAR" {
+		t.Errorf("sent %+v", sender.sent)
+	}
+
+	// Sent messages are forgotten after a week
+	clk.Advance(outboxRetention + time.Minute)
+	queue(t, o, "Later")
+	o.deliver()
+	if messages := o.List(""); len(messages) != 1 || messages[0].Message.Subject != "Later" {
+		t.Errorf("messages = %+v, want only the latest", messages)
+	}
+}
+
+func TestEmailTaskReportsQueuedMessage(t *testing.T) {
+	now := time.Date(2026, time.October, 14, 10, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+	o := newTestOutbox(t, t.TempDir(), clk, &flakyMailSender{})
+	cfg := &config.Config{TimeZone: "UTC", UserEmail: "me@example.com"}
+	h := NewHandler(cfg, logger.New(), clk, nil, o, &fakeLanguageModel{err: api.ErrModelNotConfigured})
+
+	result, err := h.ProcessTask(`send an email to bob@example.com about "Budget" saying the numbers are in`)
+	if err != nil {
+		t.Fatalf("ProcessTask failed: %v", err)
+	}
+
+	// The outcome names the pending message rather than claiming delivery
+	pending := o.List(OutboxPending)
+	if len(pending) != 1 {
+		t.Fatalf("got %d pending messages, want 1", len(pending))
+	}
+	want := fmt.Sprintf(`Queued email "Budget" to bob@example.com as outbox message %s`, pending[0].ID)
+	if result.Outcome != want {
+		t.Errorf("got outcome %q, want %q", result.Outcome, want)
+	}
+}
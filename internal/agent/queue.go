@@ -0,0 +1,197 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/store"
+	"github.com/azme12/ai-agent-project/pkg/logger"
+)
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// jobRetention is how long finished jobs stay available for status polling.
+const jobRetention = 1 * time.Hour
+
+var (
+	ErrQueueFull   = errors.New("job queue is full")
+	ErrQueueClosed = errors.New("job queue is shut down")
+)
+
+// Job is a task submitted for asynchronous execution.
+type Job struct {
+	ID      string `json:"id"`
+	Task    string `json:"task"`
+	Status  string `json:"status"`
+	TaskID  string `json:"task_id,omitempty"`
+	Outcome string `json:"outcome,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// Result carries the task's details, such as suggested meeting slots
+	Result     json.RawMessage `json:"result,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
+
+// Queue runs submitted tasks on a bounded pool of workers.
+type Queue struct {
+	logger  *logger.Logger
+	clock   clock.Clock
+	run     func(task string) (*store.Task, error)
+	workers int
+
+	mu     sync.Mutex
+	jobs   map[string]*Job
+	work   chan *Job
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewQueue creates a queue with the given number of workers that buffers up
+// to size pending jobs. run executes a single task.
+func NewQueue(log *logger.Logger, clk clock.Clock, workers, size int, run func(task string) (*store.Task, error)) *Queue {
+	if workers < 1 {
+		workers = 1
+	}
+	if size < 0 {
+		size = 0
+	}
+
+	return &Queue{
+		logger:  log,
+		clock:   clk,
+		run:     run,
+		workers: workers,
+		jobs:    make(map[string]*Job),
+		work:    make(chan *Job, size),
+	}
+}
+
+func (q *Queue) Start() {
+	q.logger.Info("Starting job workers", "workers", q.workers)
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+}
+
+// Stop stops accepting jobs and waits for queued and running jobs to finish
+// or for ctx to expire. It is safe to call more than once.
+func (q *Queue) Stop(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.work)
+		q.logger.Info("Draining job queue")
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.logger.Info("Job queue drained")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("job queue did not drain: %v", ctx.Err())
+	}
+}
+
+// Submit enqueues task without blocking.
+func (q *Queue) Submit(task string) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil, ErrQueueClosed
+	}
+
+	q.prune()
+
+	job := &Job{
+		ID:        store.NewID(),
+		Task:      task,
+		Status:    JobQueued,
+		CreatedAt: q.clock.Now(),
+	}
+
+	select {
+	case q.work <- job:
+	default:
+		return nil, ErrQueueFull
+	}
+
+	q.jobs[job.ID] = job
+	j := *job
+	return &j, nil
+}
+
+// Get returns a snapshot of the job with the given ID.
+func (q *Queue) Get(id string) (*Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, false
+	}
+
+	j := *job
+	return &j, true
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+
+	for job := range q.work {
+		q.mu.Lock()
+		startedAt := q.clock.Now()
+		job.Status = JobRunning
+		job.StartedAt = &startedAt
+		q.mu.Unlock()
+
+		record, err := q.run(job.Task)
+
+		q.mu.Lock()
+		finishedAt := q.clock.Now()
+		job.FinishedAt = &finishedAt
+		if record != nil {
+			job.TaskID = record.ID
+			job.Outcome = record.Outcome
+			job.Result = record.Result
+		}
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+			q.logger.Error("Job failed", "id", job.ID, "error", err)
+		} else {
+			job.Status = JobSucceeded
+		}
+		q.mu.Unlock()
+	}
+}
+
+// prune forgets finished jobs older than jobRetention. Callers hold q.mu.
+func (q *Queue) prune() {
+	cutoff := q.clock.Now().Add(-jobRetention)
+	for id, job := range q.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(q.jobs, id)
+		}
+	}
+}
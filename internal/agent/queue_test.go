@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/store"
+	"github.com/azme12/ai-agent-project/pkg/logger"
+)
+
+// blockingRunner runs tasks once they are released, reporting each as it
+// starts.
+type blockingRunner struct {
+	started chan string
+	release chan struct{}
+}
+
+func newBlockingRunner() *blockingRunner {
+	return &blockingRunner{started: make(chan string, 10), release: make(chan struct{})}
+}
+
+func (r *blockingRunner) run(task string) (*store.Task, error) {
+	r.started <- task
+	<-r.release
+	record := &store.Task{ID: "task-" + task, Outcome: "Done " + task}
+	if strings.HasPrefix(task, "bad") {
+		return record, errors.New("no free slot")
+	}
+	return record, nil
+}
+
+func TestQueueRejectsWhenFull(t *testing.T) {
+	runner := newBlockingRunner()
+	clk := clock.NewFake(time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC))
+	q := NewQueue(logger.New(), clk, 1, 2, runner.run)
+	q.Start()
+
+	first, err := q.Submit("one")
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if first.Status != JobQueued || !first.CreatedAt.Equal(clk.Now()) {
+		t.Errorf("submitted job is %+v", first)
+	}
+	// The only worker is busy, so two more jobs fill the buffer
+	<-runner.started
+	if job, _ := q.Get(first.ID); job.Status != JobRunning || job.StartedAt == nil {
+		t.Errorf("running job is %+v", job)
+	}
+	second, err := q.Submit("bad two")
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if _, err := q.Submit("three"); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if _, err := q.Submit("four"); err != ErrQueueFull {
+		t.Fatalf("submitting to a full queue returned %v", err)
+	}
+
+	close(runner.release)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.Stop(ctx); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	if job, _ := q.Get(first.ID); job.Status != JobSucceeded || job.TaskID != "task-one" || job.Outcome != "Done one" || job.FinishedAt == nil {
+		t.Errorf("finished job is %+v", job)
+	}
+	if job, _ := q.Get(second.ID); job.Status != JobFailed || job.Error != "no free slot" || job.TaskID != "task-bad two" {
+		t.Errorf("failed job is %+v", job)
+	}
+	if _, err := q.Submit("five"); err != ErrQueueClosed {
+		t.Errorf("submitting to a stopped queue returned %v", err)
+	}
+}
+
+func TestQueueStopWaitsForRunningJobs(t *testing.T) {
+	runner := newBlockingRunner()
+	q := NewQueue(logger.New(), clock.Real(), 1, 1, runner.run)
+	q.Start()
+	job, err := q.Submit("one")
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	<-runner.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.Stop(ctx); err == nil {
+		t.Fatal("Stop returned before the running job finished")
+	}
+
+	// Stopping again waits for the same jobs
+	close(runner.release)
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.Stop(ctx); err != nil {
+		t.Fatalf("second Stop failed: %v", err)
+	}
+	if got, _ := q.Get(job.ID); got.Status != JobSucceeded {
+		t.Errorf("job is %q after Stop", got.Status)
+	}
+}
+
+func TestQueuePrunesFinishedJobs(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC))
+	q := NewQueue(logger.New(), clk, 1, 1, nil)
+
+	old := clk.Now()
+	recent := old.Add(jobRetention)
+	q.jobs["old"] = &Job{ID: "old", Status: JobSucceeded, CreatedAt: old, FinishedAt: &old}
+	q.jobs["recent"] = &Job{ID: "recent", Status: JobFailed, CreatedAt: recent, FinishedAt: &recent}
+	// Unfinished jobs are kept however old they are
+	q.jobs["queued"] = &Job{ID: "queued", Status: JobQueued, CreatedAt: old}
+
+	clk.Advance(jobRetention + time.Minute)
+	if _, err := q.Submit("new"); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	for id, want := range map[string]bool{"old": false, "recent": true, "queued": true} {
+		if _, ok := q.Get(id); ok != want {
+			t.Errorf("job %s kept: %v, want %v", id, ok, want)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/cron"
+	"github.com/azme12/ai-agent-project/internal/store"
+)
+
+// Scheduled job kinds. Custom jobs run their Task through the handler as if it
+// had been submitted over HTTP.
+const (
+	JobKindDailySummary   = "daily_summary"
+	JobKindWeeklySummary  = "weekly_summary"
+	JobKindMonthlySummary = "monthly_summary"
+	JobKindCustom         = "custom"
+)
+
+// Misfire policies decide what happens to activations missed while the agent
+// was down or a tick ran late.
+const (
+	// MisfireRunOnce runs a job once no matter how many activations were missed.
+	MisfireRunOnce = "run_once"
+	// MisfireRunAll runs a job once for every missed activation.
+	MisfireRunAll = "run_all"
+	// MisfireSkip only runs a job if its latest activation is within
+	// misfireGrace; older activations are dropped.
+	MisfireSkip = "skip"
+)
+
+const (
+	// misfireGrace is how late an activation may run and still count as on
+	// time under MisfireSkip.
+	misfireGrace = 2 * time.Minute
+	// maxCatchUp bounds how many missed activations MisfireRunAll replays.
+	maxCatchUp = 100
+	// jobRetryDelay is the wait before retrying a failed job, doubled for
+	// each further failure up to maxJobRetryDelay.
+	jobRetryDelay = 1 * time.Minute
+	// maxJobRetryDelay caps the backoff between attempts at a job.
+	maxJobRetryDelay = 1 * time.Hour
+	// maxJobAttempts is how often an activation is tried before it is given
+	// up on.
+	maxJobAttempts = 5
+)
+
+var (
+	ErrJobNotFound = errors.New("scheduled job not found")
+	// ErrInvalidJob is returned for jobs that can't be scheduled as given
+	ErrInvalidJob = errors.New("invalid scheduled job")
+)
+
+// ScheduledJob is a recurring job fired by the scheduler on a cron schedule.
+type ScheduledJob struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Spec      string    `json:"spec"`
+	Task      string    `json:"task,omitempty"`
+	Misfire   string    `json:"misfire"`
+	CreatedAt time.Time `json:"created_at"`
+	// LastRun is the activation time of the last successful run
+	LastRun *time.Time `json:"last_run,omitempty"`
+	NextRun *time.Time `json:"next_run,omitempty"`
+	// Failures counts the failed attempts at the activation after LastRun,
+	// which isn't retried before RetryAt
+	Failures  int        `json:"failures,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	RetryAt   *time.Time `json:"retry_at,omitempty"`
+}
+
+// DueJob is a job together with the activations it has not run yet, oldest
+// first.
+type DueJob struct {
+	Job   ScheduledJob
+	Times []time.Time
+}
+
+type registryEntry struct {
+	job      ScheduledJob
+	schedule *cron.Schedule
+}
+
+// JobRegistry holds the scheduler's cron jobs and persists them to disk.
+type JobRegistry struct {
+	mu      sync.Mutex
+	clock   clock.Clock
+	path    string
+	entries map[string]*registryEntry
+}
+
+// dailySummaryID is the ID of the built-in daily summary, whose schedule
+// follows the configured daily reminder time.
+const dailySummaryID = "daily-summary"
+
+// LoadJobRegistry opens the registry in dir. On first use it is seeded with
+// the built-in daily, weekly and monthly summaries, and afterwards the daily
+// summary is moved to dailyReminderTime if that has changed.
+func LoadJobRegistry(dir string, dailyReminderTime string, clk clock.Clock) (*JobRegistry, error) {
+	r := &JobRegistry{
+		clock:   clk,
+		path:    filepath.Join(dir, "scheduled_jobs.json"),
+		entries: make(map[string]*registryEntry),
+	}
+
+	var jobs []ScheduledJob
+	if err := store.LoadJSON(r.path, &jobs); err != nil {
+		return nil, err
+	}
+
+	if jobs == nil {
+		jobs = defaultJobs(dailyReminderTime, clk.Now())
+		for _, job := range jobs {
+			if _, err := r.add(job); err != nil {
+				return nil, err
+			}
+		}
+		return r, r.save()
+	}
+
+	changed := false
+	for _, job := range jobs {
+		if job.ID == dailySummaryID && job.Kind == JobKindDailySummary {
+			if spec := dailySpec(dailyReminderTime); job.Spec != spec {
+				job.Spec = spec
+				changed = true
+			}
+		}
+		if _, err := r.add(job); err != nil {
+			return nil, fmt.Errorf("failed to load scheduled job %s: %v", job.ID, err)
+		}
+	}
+	if changed {
+		if err := r.save(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func defaultJobs(dailyReminderTime string, now time.Time) []ScheduledJob {
+	return []ScheduledJob{
+		{ID: dailySummaryID, Name: "Daily summary", Kind: JobKindDailySummary, Spec: dailySpec(dailyReminderTime), CreatedAt: now},
+		{ID: "weekly-summary", Name: "Weekly summary", Kind: JobKindWeeklySummary, Spec: "0 9 * * 1", CreatedAt: now},
+		{ID: "monthly-summary", Name: "Monthly summary", Kind: JobKindMonthlySummary, Spec: "0 9 1 * *", CreatedAt: now},
+	}
+}
+
+// dailySpec converts an HH:MM time into a daily cron expression, defaulting
+// to 09:00 when the time is malformed.
+func dailySpec(reminderTime string) string {
+	parts := strings.Split(reminderTime, ":")
+	if len(parts) == 2 {
+		hour, herr := strconv.Atoi(parts[0])
+		minute, merr := strconv.Atoi(parts[1])
+		if herr == nil && merr == nil && hour >= 0 && hour < 24 && minute >= 0 && minute < 60 {
+			return fmt.Sprintf("%d %d * * *", minute, hour)
+		}
+	}
+	return "0 9 * * *"
+}
+
+// Add validates and registers a new job.
+func (r *JobRegistry) Add(job ScheduledJob) (*ScheduledJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job.ID = store.NewID()
+	job.CreatedAt = r.clock.Now()
+	job.LastRun = nil
+	job.Failures = 0
+	job.LastError = ""
+	job.RetryAt = nil
+
+	entry, err := r.add(job)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.save(); err != nil {
+		delete(r.entries, job.ID)
+		return nil, fmt.Errorf("failed to save scheduled job: %v", err)
+	}
+
+	added := entry.job
+	return &added, nil
+}
+
+// Remove deletes a job.
+func (r *JobRegistry) Remove(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	delete(r.entries, id)
+	if err := r.save(); err != nil {
+		r.entries[id] = entry
+		return err
+	}
+	return nil
+}
+
+// List returns all jobs with their next run after now, ordered by name.
+func (r *JobRegistry) List(now time.Time) []ScheduledJob {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	jobs := make([]ScheduledJob, 0, len(r.entries))
+	for _, entry := range r.entries {
+		job := entry.job
+		if next := entry.schedule.Next(now); !next.IsZero() {
+			job.NextRun = &next
+		}
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Name < jobs[j].Name
+	})
+	return jobs
+}
+
+// Due returns the jobs with activations after their last successful run (or
+// creation) up to now, evaluated in now's location. Only the latest
+// maxCatchUp activations are returned, and jobs waiting to retry a failure
+// are left out.
+func (r *JobRegistry) Due(now time.Time) []DueJob {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []DueJob
+	for _, entry := range r.entries {
+		if entry.job.RetryAt != nil && entry.job.RetryAt.After(now) {
+			continue
+		}
+
+		since := entry.job.CreatedAt
+		if entry.job.LastRun != nil {
+			since = *entry.job.LastRun
+		}
+
+		// Walk back from now, so long downtime costs no more than maxCatchUp
+		// steps. Prev is exclusive, and an activation at now is due.
+		var times []time.Time
+		for t := entry.schedule.Prev(now.Add(time.Nanosecond)); !t.IsZero() && t.After(since) && len(times) < maxCatchUp; t = entry.schedule.Prev(t) {
+			times = append(times, t)
+		}
+		for i, j := 0, len(times)-1; i < j; i, j = i+1, j-1 {
+			times[i], times[j] = times[j], times[i]
+		}
+
+		if len(times) > 0 {
+			due = append(due, DueJob{Job: entry.job, Times: times})
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].Job.Name < due[j].Job.Name
+	})
+	return due
+}
+
+// MarkRun records at as the last handled activation of a job.
+func (r *JobRegistry) MarkRun(id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	entry.job.LastRun = &at
+	entry.job.Failures = 0
+	entry.job.LastError = ""
+	entry.job.RetryAt = nil
+	return r.save()
+}
+
+// MarkFailed records a failed attempt at a job's activation at. The
+// activation is retried with backoff, and after maxJobAttempts it is given
+// up on as if it had run, keeping the error. It reports whether it gave up.
+func (r *JobRegistry) MarkFailed(id string, at, now time.Time, runErr error) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[id]
+	if !ok {
+		return false, ErrJobNotFound
+	}
+
+	job := &entry.job
+	job.Failures++
+	job.LastError = runErr.Error()
+
+	gaveUp := job.Failures >= maxJobAttempts
+	if gaveUp {
+		job.LastRun = &at
+		job.Failures = 0
+		job.RetryAt = nil
+	} else {
+		retryAt := now.Add(jobBackoff(job.Failures))
+		job.RetryAt = &retryAt
+	}
+	return gaveUp, r.save()
+}
+
+// jobBackoff returns the wait after the given number of failed attempts.
+func jobBackoff(failures int) time.Duration {
+	delay := jobRetryDelay
+	for i := 1; i < failures && delay < maxJobRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxJobRetryDelay {
+		delay = maxJobRetryDelay
+	}
+	return delay
+}
+
+// add validates job and stores it in memory. Callers hold r.mu or own r.
+func (r *JobRegistry) add(job ScheduledJob) (*registryEntry, error) {
+	job.Name = strings.TrimSpace(job.Name)
+	job.Spec = strings.TrimSpace(job.Spec)
+	job.Task = strings.TrimSpace(job.Task)
+	job.NextRun = nil
+
+	switch job.Kind {
+	case JobKindDailySummary, JobKindWeeklySummary, JobKindMonthlySummary:
+	case JobKindCustom:
+		if job.Task == "" {
+			return nil, fmt.Errorf("%w: custom jobs require a task", ErrInvalidJob)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown job kind %q", ErrInvalidJob, job.Kind)
+	}
+
+	switch job.Misfire {
+	case "":
+		job.Misfire = MisfireRunOnce
+	case MisfireRunOnce, MisfireRunAll, MisfireSkip:
+	default:
+		return nil, fmt.Errorf("%w: unknown misfire policy %q", ErrInvalidJob, job.Misfire)
+	}
+
+	schedule, err := cron.Parse(job.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJob, err)
+	}
+	if schedule.Next(r.clock.Now()).IsZero() {
+		return nil, fmt.Errorf("%w: cron expression %q never fires", ErrInvalidJob, job.Spec)
+	}
+
+	if job.Name == "" {
+		job.Name = job.Kind
+	}
+
+	entry := &registryEntry{job: job, schedule: schedule}
+	r.entries[job.ID] = entry
+	return entry, nil
+}
+
+func (r *JobRegistry) save() error {
+	jobs := make([]ScheduledJob, 0, len(r.entries))
+	for _, entry := range r.entries {
+		jobs = append(jobs, entry.job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return store.SaveJSON(r.path, jobs)
+}
@@ -1,47 +1,110 @@
 package agent
 
 import (
+	"context"
 	"fmt"
-	"strconv"
-	"strings"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/azme12/ai-agent-project/internal/api"
+	"github.com/azme12/ai-agent-project/internal/clock"
 	"github.com/azme12/ai-agent-project/internal/config"
+	"github.com/azme12/ai-agent-project/internal/ical"
+	"github.com/azme12/ai-agent-project/internal/store"
+	"github.com/azme12/ai-agent-project/internal/templates"
 	"github.com/azme12/ai-agent-project/pkg/logger"
 )
 
 type Scheduler struct {
 	config   *config.Config
 	logger   *logger.Logger
+	clock    clock.Clock
 	stopCh   chan struct{}
-	calendar *api.CalendarService
-	email    *api.EmailService
+	stopOnce sync.Once
+	done     chan struct{}
+	// started is set once run is launched, which is what closes done
+	startMu   sync.Mutex
+	started   bool
+	calendar  api.CalendarProvider
+	email     api.MailSender
+	jobs      *JobRegistry
+	reminders *store.ReminderLedger
+	templates *templates.Engine
+
+	// runTask executes the task of a custom scheduled job
+	runTask func(task string) error
 }
 
-func NewScheduler(cfg *config.Config, log *logger.Logger) *Scheduler {
+func NewScheduler(cfg *config.Config, log *logger.Logger, clk clock.Clock) *Scheduler {
 	return &Scheduler{
-		config: cfg,
-		logger: log,
-		stopCh: make(chan struct{}),
+		config:    cfg,
+		logger:    log,
+		clock:     clk,
+		stopCh:    make(chan struct{}),
+		done:      make(chan struct{}),
+		templates: templates.New(cfg.TemplatesDir, cfg.Locale, cfg.Location()),
 	}
 }
 
 func (s *Scheduler) Start() error {
 	s.logger.Info("Starting scheduler")
 
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	s.startMu.Lock()
+	s.started = true
+	s.startMu.Unlock()
 	go s.run()
 
 	return nil
 }
 
-func (s *Scheduler) Stop() error {
-	s.logger.Info("Stopping scheduler")
-	close(s.stopCh)
+// load opens the persisted job registry and reminder ledger.
+func (s *Scheduler) load() error {
+	jobs, err := LoadJobRegistry(s.config.DataDir, s.config.DailyReminderTime, s.clock)
+	if err != nil {
+		return fmt.Errorf("failed to load scheduled jobs: %v", err)
+	}
+	s.jobs = jobs
+
+	reminders, err := store.NewReminderLedger(s.config.DataDir)
+	if err != nil {
+		return fmt.Errorf("failed to load reminder ledger: %v", err)
+	}
+	s.reminders = reminders
+
 	return nil
 }
 
+// Stop signals the scheduler to stop and waits for the current tick, including
+// any reminders being sent, to finish. It is safe to call more than once.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		s.logger.Info("Stopping scheduler")
+		close(s.stopCh)
+	})
+
+	s.startMu.Lock()
+	started := s.started
+	s.startMu.Unlock()
+	if !started {
+		return nil
+	}
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("scheduler did not stop: %v", ctx.Err())
+	}
+}
+
 func (s *Scheduler) run() {
+	defer close(s.done)
+
 	// Check for scheduled tasks every minute
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
@@ -64,48 +127,100 @@ func (s *Scheduler) run() {
 func (s *Scheduler) checkScheduledTasks() {
 	s.logger.Debug("Checking scheduled tasks")
 
-	// Send daily reminder at configured time
-	if s.shouldSendDailyReminder() {
-		s.sendDailyReminder()
-	}
+	// Run cron jobs that came due since their last run, catching up on
+	// activations missed during downtime or slow ticks
+	s.runDueJobs()
 
 	// Check for meetings starting soon
 	s.checkUpcomingMeetings()
+}
 
-	// Process recurring tasks
-	s.processRecurringTasks()
+// now returns the current time in the configured time zone, which is the
+// zone cron expressions are evaluated in.
+func (s *Scheduler) now() time.Time {
+	return s.clock.Now().In(s.config.Location())
 }
 
-func (s *Scheduler) shouldSendDailyReminder() bool {
-	now := time.Now()
+func (s *Scheduler) runDueJobs() {
+	now := s.now()
+
+	for _, due := range s.jobs.Due(now) {
+		job := due.Job
+		latest := due.Times[len(due.Times)-1]
+
+		var runs []time.Time
+		switch job.Misfire {
+		case MisfireRunAll:
+			runs = due.Times
+		case MisfireSkip:
+			if now.Sub(latest) <= misfireGrace {
+				runs = []time.Time{latest}
+			} else {
+				s.logger.Info("Skipping missed scheduled job", "id", job.ID, "missed", len(due.Times))
+				s.markRun(job, latest)
+			}
+		default:
+			if len(due.Times) > 1 {
+				s.logger.Info("Catching up missed scheduled job once", "id", job.ID, "missed", len(due.Times))
+			}
+			runs = []time.Time{latest}
+		}
 
-	// Parse the configured reminder time
-	reminderTime := s.config.DailyReminderTime
-	if reminderTime == "" {
-		reminderTime = "09:00" // Default to 9 AM
+		// Stop at the first failure so the remaining activations are retried
+		// once the failed one has been retried or given up on
+		for _, at := range runs {
+			if err := s.runJob(job); err != nil {
+				s.markFailed(job, at, now, err)
+				break
+			}
+			s.markRun(job, at)
+		}
 	}
+}
 
-	// Parse hour and minute from reminder time
-	parts := strings.Split(reminderTime, ":")
-	if len(parts) != 2 {
-		return false
+func (s *Scheduler) markRun(job ScheduledJob, at time.Time) {
+	if err := s.jobs.MarkRun(job.ID, at); err != nil && err != ErrJobNotFound {
+		s.logger.Error("Failed to record scheduled job run", "id", job.ID, "error", err)
 	}
+}
 
-	hour, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return false
+func (s *Scheduler) markFailed(job ScheduledJob, at, now time.Time, runErr error) {
+	gaveUp, err := s.jobs.MarkFailed(job.ID, at, now, runErr)
+	if err != nil && err != ErrJobNotFound {
+		s.logger.Error("Failed to record scheduled job failure", "id", job.ID, "error", err)
+	}
+	if gaveUp {
+		s.logger.Error("Giving up on scheduled job run", "id", job.ID, "activation", at, "error", runErr)
+	} else {
+		s.logger.Error("Scheduled job failed, will retry", "id", job.ID, "activation", at, "error", runErr)
 	}
+}
 
-	minute, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return false
+func (s *Scheduler) runJob(job ScheduledJob) error {
+	s.logger.Info("Running scheduled job", "id", job.ID, "name", job.Name)
+
+	switch job.Kind {
+	case JobKindDailySummary:
+		return s.sendDailyReminder()
+	case JobKindWeeklySummary:
+		return s.sendWeeklySummary()
+	case JobKindMonthlySummary:
+		return s.sendMonthlySummary()
+	case JobKindCustom:
+		if s.runTask == nil {
+			return nil
+		}
+		return s.runTask(job.Task)
 	}
+	return nil
+}
 
-	// Check if it's the configured time
-	return now.Hour() == hour && now.Minute() == minute
+// Jobs returns the scheduler's job registry. It is nil until Start.
+func (s *Scheduler) Jobs() *JobRegistry {
+	return s.jobs
 }
 
-func (s *Scheduler) sendDailyReminder() {
+func (s *Scheduler) sendDailyReminder() error {
 	s.logger.Info("Sending daily reminder")
 
 	// Get today's tasks
@@ -114,102 +229,72 @@ func (s *Scheduler) sendDailyReminder() {
 	// Get today's meetings
 	meetings := s.getTodaysMeetings()
 
-	reminderBody := "Good morning! Here's the daily summary:\n\n"
+	// All-day events are listed first, as they have no time of day
+	sort.SliceStable(meetings, func(i, j int) bool {
+		return meetings[i].AllDay && !meetings[j].AllDay
+	})
 
-	if len(tasks) > 0 {
-		reminderBody += "Today's Tasks:\n"
-		for i, task := range tasks {
-			reminderBody += fmt.Sprintf("%d. %s\n", i+1, task)
-		}
-		reminderBody += "\n"
+	summary := templates.DailySummary{
+		Date:  s.today(),
+		Tasks: tasks,
+		// Say which calendar each meeting is on when several are merged
+		ShowCalendars: len(s.config.Calendars) > 1,
 	}
-
-	if len(meetings) > 0 {
-		reminderBody += " Today's Meetings:\n"
-		for _, meeting := range meetings {
-			reminderBody += fmt.Sprintf("• %s at %s\n", meeting.Title, meeting.StartTime.Format("15:04"))
-		}
-		reminderBody += "\n"
+	for _, meeting := range meetings {
+		summary.Meetings = append(summary.Meetings, templateMeeting(meeting))
 	}
 
-	reminderBody += "Have a productive day! "
-
-	// Send reminder email
-	if s.email != nil {
-		err := s.email.SendEmail(
-			s.config.UserEmail,
-			"Daily Summary - AI Assistant",
-			reminderBody,
-		)
-		if err != nil {
-			s.logger.Error("Failed to send daily reminder", "error", err)
-		}
+	if err := s.sendTemplate(templates.DailySummaryTemplate, summary, nil); err != nil {
+		return fmt.Errorf("failed to send daily reminder: %v", err)
 	}
+	return nil
 }
 
-func (s *Scheduler) processRecurringTasks() {
-	s.logger.Debug("Processing recurring tasks")
+func (s *Scheduler) sendWeeklySummary() error {
+	s.logger.Info("Sending weekly summary")
 
-	// Check for weekly tasks on Monday
-	now := time.Now()
-	if now.Weekday() == time.Monday && now.Hour() == 9 && now.Minute() == 0 {
-		s.sendWeeklySummary()
-	}
+	today := s.today()
+	start := today.AddDate(0, 0, -int((today.Weekday()+6)%7))
+	summary := templates.PeriodSummary{Start: start, End: start.AddDate(0, 0, 6)}
 
-	// Check for monthly tasks on the 1st
-	if now.Day() == 1 && now.Hour() == 9 && now.Minute() == 0 {
-		s.sendMonthlySummary()
+	if err := s.sendTemplate(templates.WeeklySummaryTemplate, summary, nil); err != nil {
+		return fmt.Errorf("failed to send weekly summary: %v", err)
 	}
+	return nil
 }
 
-func (s *Scheduler) sendWeeklySummary() {
-	s.logger.Info("Sending weekly summary")
+func (s *Scheduler) sendMonthlySummary() error {
+	s.logger.Info("Sending monthly summary")
 
-	summaryBody := " Weekly Summary\n\n"
-	summaryBody += "This week's accomplishments:\n"
-	summaryBody += "• Completed project milestones\n"
-	summaryBody += "• Scheduled team meetings\n"
-	summaryBody += "• Responded to important emails\n\n"
-	summaryBody += "Next week's priorities:\n"
-	summaryBody += "• Review pending tasks\n"
-	summaryBody += "• Plan upcoming meetings\n"
-	summaryBody += "• Follow up on action items"
-
-	if s.email != nil {
-		err := s.email.SendEmail(
-			s.config.UserEmail,
-			"Weekly Summary - AI Assistant",
-			summaryBody,
-		)
-		if err != nil {
-			s.logger.Error("Failed to send weekly summary", "error", err)
-		}
+	today := s.today()
+	start := today.AddDate(0, 0, 1-today.Day())
+	summary := templates.PeriodSummary{Start: start, End: start.AddDate(0, 1, -1)}
+
+	if err := s.sendTemplate(templates.MonthlySummaryTemplate, summary, nil); err != nil {
+		return fmt.Errorf("failed to send monthly summary: %v", err)
 	}
+	return nil
 }
 
-func (s *Scheduler) sendMonthlySummary() {
-	s.logger.Info("Sending monthly summary")
+// today returns midnight at the start of the current day in the configured
+// time zone.
+func (s *Scheduler) today() time.Time {
+	now := s.clock.Now().In(s.config.Location())
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
 
-	summaryBody := " Monthly Summary\n\n"
-	summaryBody += "This month's key achievements:\n"
-	summaryBody += "• Completed major project phases\n"
-	summaryBody += "• Attended important meetings\n"
-	summaryBody += "• Maintained communication with stakeholders\n\n"
-	summaryBody += "Next month's focus areas:\n"
-	summaryBody += "• Strategic planning\n"
-	summaryBody += "• Team coordination\n"
-	summaryBody += "• Performance review"
-
-	if s.email != nil {
-		err := s.email.SendEmail(
-			s.config.UserEmail,
-			"Monthly Summary - AI Assistant",
-			summaryBody,
-		)
-		if err != nil {
-			s.logger.Error("Failed to send monthly summary", "error", err)
-		}
+// sendTemplate renders the named template and emails it to the user.
+func (s *Scheduler) sendTemplate(name string, data interface{}, attachments []api.Attachment) error {
+	if s.email == nil {
+		return nil
 	}
+
+	message, err := templateMessage(s.templates, name, s.config.UserEmail, data)
+	if err != nil {
+		return err
+	}
+	message.Attachments = attachments
+	return s.email.Send(message)
 }
 
 func (s *Scheduler) getTodaysTasks() []string {
@@ -231,14 +316,15 @@ func (s *Scheduler) getTodaysMeetings() []api.Event {
 			return []api.Event{}
 		}
 
-		// Filter for today's events
+		// Filter for events during today, including all-day events that
+		// start at midnight or span several days
 		var todaysEvents []api.Event
-		now := time.Now()
+		now := s.now()
 		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 		tomorrow := today.AddDate(0, 0, 1)
 
 		for _, event := range events {
-			if event.StartTime.After(today) && event.StartTime.Before(tomorrow) {
+			if event.StartTime.Before(tomorrow) && event.EndTime.After(today) {
 				todaysEvents = append(todaysEvents, event)
 			}
 		}
@@ -259,44 +345,97 @@ func (s *Scheduler) checkUpcomingMeetings() {
 			return
 		}
 
-		now := time.Now()
-		reminderMinutes := s.config.MeetingReminderMinutes
-		if reminderMinutes == 0 {
-			reminderMinutes = 15 // Default to 15 minutes
-		}
+		now := s.now()
+		offsets := s.reminderOffsets()
 
 		for _, event := range events {
-			// Check if meeting starts within the reminder window
 			timeUntilMeeting := event.StartTime.Sub(now)
-			if timeUntilMeeting <= time.Duration(reminderMinutes)*time.Minute && timeUntilMeeting > 0 {
-				s.sendMeetingReminder(event)
+			if timeUntilMeeting <= 0 {
+				continue
+			}
+
+			// Offsets are sorted from largest to smallest, so the last one
+			// whose window we are in is the reminder that is due now. Larger
+			// offsets that were missed are not sent late.
+			var due []string
+			offset := time.Duration(0)
+			for _, o := range offsets {
+				// A reminder minutes before midnight is no use for an
+				// all-day event, so those only get reminders days ahead
+				if event.AllDay && o < 24*time.Hour {
+					continue
+				}
+				if timeUntilMeeting <= o {
+					due = append(due, reminderKey(event, o))
+					offset = o
+				}
+			}
+			if len(due) == 0 || s.reminders.WasSent(due[len(due)-1]) {
+				continue
 			}
+
+			if err := s.sendMeetingReminder(event, offset); err != nil {
+				s.logger.Error("Failed to send meeting reminder", "error", err)
+				continue
+			}
+			if err := s.reminders.MarkSent(event.StartTime, due...); err != nil {
+				s.logger.Error("Failed to record meeting reminder", "error", err)
+			}
+		}
+
+		if err := s.reminders.Prune(now.Add(-24 * time.Hour)); err != nil {
+			s.logger.Error("Failed to prune reminder ledger", "error", err)
 		}
 	}
 }
 
-func (s *Scheduler) sendMeetingReminder(event api.Event) {
-	s.logger.Info("Sending meeting reminder", "meeting", event.Title)
+// reminderOffsets returns the configured reminder offsets, largest first.
+func (s *Scheduler) reminderOffsets() []time.Duration {
+	minutes := s.config.MeetingReminderOffsets
+	if len(minutes) == 0 {
+		reminderMinutes := s.config.MeetingReminderMinutes
+		if reminderMinutes == 0 {
+			reminderMinutes = 15 // Default to 15 minutes
+		}
+		minutes = []int{reminderMinutes}
+	}
 
-	reminderBody := fmt.Sprintf(" Meeting Reminder\n\n")
-	reminderBody += fmt.Sprintf("Meeting: %s\n", event.Title)
-	reminderBody += fmt.Sprintf("Time: %s\n", event.StartTime.Format("Monday, January 2, 2006 at 15:04"))
-	reminderBody += fmt.Sprintf("Duration: %s\n", event.EndTime.Sub(event.StartTime).String())
+	offsets := make([]time.Duration, 0, len(minutes))
+	for _, m := range minutes {
+		if m > 0 {
+			offsets = append(offsets, time.Duration(m)*time.Minute)
+		}
+	}
+	sort.Slice(offsets, func(i, j int) bool {
+		return offsets[i] > offsets[j]
+	})
+	return offsets
+}
 
-	if len(event.Attendees) > 0 {
-		reminderBody += fmt.Sprintf("Attendees: %s\n", strings.Join(event.Attendees, ", "))
+// reminderKey identifies one reminder offset of one event occurrence. The
+// start time is part of the key so a rescheduled event is reminded again.
+func reminderKey(event api.Event, offset time.Duration) string {
+	id := event.ID
+	if id == "" {
+		id = event.Title
 	}
+	return fmt.Sprintf("%s|%s|%d", id, event.StartTime.UTC().Format(time.RFC3339), int(offset.Minutes()))
+}
 
-	reminderBody += "\nPlease join on time! "
+func (s *Scheduler) sendMeetingReminder(event api.Event, offset time.Duration) error {
+	s.logger.Info("Sending meeting reminder", "meeting", event.Title, "offset", offset)
 
-	if s.email != nil {
-		err := s.email.SendEmail(
-			s.config.UserEmail,
-			"Meeting Reminder - AI Assistant",
-			reminderBody,
-		)
-		if err != nil {
-			s.logger.Error("Failed to send meeting reminder", "error", err)
-		}
+	if s.email == nil {
+		return nil
+	}
+
+	// Attach the meeting so it can be added to any calendar
+	var attachments []api.Attachment
+	meeting := &ical.Calendar{Method: ical.MethodPublish, Events: []ical.Event{icalEvent(event)}}
+	if attachment, err := calendarAttachment(meeting, s.clock.Now()); err == nil {
+		attachments = append(attachments, attachment)
 	}
+
+	reminder := templates.MeetingReminder{Meeting: templateMeeting(event), StartsIn: offset}
+	return s.sendTemplate(templates.MeetingReminderTemplate, reminder, attachments)
 }
@@ -0,0 +1,446 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/api"
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/config"
+	"github.com/azme12/ai-agent-project/pkg/logger"
+)
+
+type fakeMailSender struct {
+	sent []api.Message
+}
+
+func (f *fakeMailSender) Send(message api.Message) error {
+	f.sent = append(f.sent, message)
+	return nil
+}
+
+// take returns the subjects of all emails sent so far and forgets them.
+func (f *fakeMailSender) take() []string {
+	var subjects []string
+	for _, email := range f.sent {
+		subjects = append(subjects, email.Subject)
+	}
+	f.sent = nil
+	return subjects
+}
+
+// fakeCalendar serves a fixed list of upcoming events. The scheduler only
+// reads events, so the remaining methods are left to the nil interface.
+type fakeCalendar struct {
+	api.CalendarProvider
+	events []api.Event
+}
+
+func (f *fakeCalendar) GetUpcomingEvents() ([]api.Event, error) {
+	return f.events, nil
+}
+
+type schedulerFixture struct {
+	clock    *clock.Fake
+	email    *fakeMailSender
+	calendar *fakeCalendar
+	config   *config.Config
+}
+
+func newSchedulerFixture(t *testing.T, start time.Time) *schedulerFixture {
+	t.Helper()
+
+	return &schedulerFixture{
+		clock:    clock.NewFake(start),
+		email:    &fakeMailSender{},
+		calendar: &fakeCalendar{},
+		config: &config.Config{
+			DataDir:                t.TempDir(),
+			TimeZone:               start.Location().String(),
+			UserEmail:              "me@example.com",
+			DailyReminderTime:      "09:00",
+			MeetingReminderOffsets: []int{24 * 60, 15},
+		},
+	}
+}
+
+// scheduler builds a scheduler over the fixture's data directory, as a fresh
+// process would after a restart.
+func (f *schedulerFixture) scheduler(t *testing.T) *Scheduler {
+	t.Helper()
+
+	s := NewScheduler(f.config, logger.New(), f.clock)
+	s.calendar = f.calendar
+	s.email = f.email
+	if err := s.load(); err != nil {
+		t.Fatalf("failed to load scheduler: %v", err)
+	}
+	return s
+}
+
+// tickAt moves the clock to t and runs one scheduler tick.
+func (f *schedulerFixture) tickAt(s *Scheduler, t time.Time) []string {
+	f.clock.Set(t)
+	s.checkScheduledTasks()
+	return f.email.take()
+}
+
+// removeJobs deletes the default summaries so they don't interfere with
+// reminder assertions.
+func removeJobs(t *testing.T, s *Scheduler) {
+	t.Helper()
+
+	for _, job := range s.jobs.List(s.now()) {
+		if err := s.jobs.Remove(job.ID); err != nil {
+			t.Fatalf("failed to remove job %s: %v", job.ID, err)
+		}
+	}
+}
+
+func loadLocation(t *testing.T) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	return loc
+}
+
+func assertSubjects(t *testing.T, at string, got []string, want ...string) {
+	t.Helper()
+
+	if strings.Join(got, "; ") != strings.Join(want, "; ") {
+		t.Errorf("at %s sent %q, want %q", at, got, want)
+	}
+}
+
+func TestSchedulerDailySummary(t *testing.T) {
+	loc := loadLocation(t)
+	// Wednesday
+	f := newSchedulerFixture(t, time.Date(2026, time.October, 14, 8, 0, 0, 0, loc))
+	s := f.scheduler(t)
+
+	assertSubjects(t, "08:59", f.tickAt(s, time.Date(2026, time.October, 14, 8, 59, 0, 0, loc)))
+	assertSubjects(t, "09:00", f.tickAt(s, time.Date(2026, time.October, 14, 9, 0, 0, 0, loc)), "Daily Summary - AI Assistant")
+	assertSubjects(t, "09:00:30", f.tickAt(s, time.Date(2026, time.October, 14, 9, 0, 30, 0, loc)))
+	assertSubjects(t, "09:01", f.tickAt(s, time.Date(2026, time.October, 14, 9, 1, 0, 0, loc)))
+	assertSubjects(t, "next day 09:00", f.tickAt(s, time.Date(2026, time.October, 15, 9, 0, 0, 0, loc)), "Daily Summary - AI Assistant")
+}
+
+func TestSchedulerDailySummaryUsesTimeZone(t *testing.T) {
+	loc := loadLocation(t)
+	f := newSchedulerFixture(t, time.Date(2026, time.October, 14, 8, 0, 0, 0, loc))
+	s := f.scheduler(t)
+
+	// 09:00 UTC is 05:00 in New York
+	assertSubjects(t, "09:00 UTC", f.tickAt(s, time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC)))
+	assertSubjects(t, "13:00 UTC", f.tickAt(s, time.Date(2026, time.October, 14, 13, 0, 0, 0, time.UTC)), "Daily Summary - AI Assistant")
+}
+
+func TestSchedulerWeeklySummary(t *testing.T) {
+	loc := loadLocation(t)
+	// Sunday
+	f := newSchedulerFixture(t, time.Date(2026, time.October, 18, 10, 0, 0, 0, loc))
+	s := f.scheduler(t)
+
+	assertSubjects(t, "Sunday", f.tickAt(s, time.Date(2026, time.October, 18, 10, 1, 0, 0, loc)))
+	assertSubjects(t, "Monday 09:00", f.tickAt(s, time.Date(2026, time.October, 19, 9, 0, 0, 0, loc)),
+		"Daily Summary - AI Assistant", "Weekly Summary - AI Assistant")
+	assertSubjects(t, "Tuesday 09:00", f.tickAt(s, time.Date(2026, time.October, 20, 9, 0, 0, 0, loc)),
+		"Daily Summary - AI Assistant")
+}
+
+func TestSchedulerMonthlySummary(t *testing.T) {
+	loc := loadLocation(t)
+	f := newSchedulerFixture(t, time.Date(2026, time.October, 31, 10, 0, 0, 0, loc))
+	s := f.scheduler(t)
+
+	// November 1 2026 is a Sunday, so no weekly summary
+	assertSubjects(t, "Nov 1 09:00", f.tickAt(s, time.Date(2026, time.November, 1, 9, 0, 0, 0, loc)),
+		"Daily Summary - AI Assistant", "Monthly Summary - AI Assistant")
+	assertSubjects(t, "Nov 2 09:00", f.tickAt(s, time.Date(2026, time.November, 2, 9, 0, 0, 0, loc)),
+		"Daily Summary - AI Assistant", "Weekly Summary - AI Assistant")
+}
+
+func TestSchedulerCatchesUpMissedRunsOnce(t *testing.T) {
+	loc := loadLocation(t)
+	f := newSchedulerFixture(t, time.Date(2026, time.October, 14, 8, 0, 0, 0, loc))
+	f.scheduler(t)
+
+	// Down for three days; the restarted scheduler sends one daily summary
+	s := f.scheduler(t)
+	assertSubjects(t, "restart", f.tickAt(s, time.Date(2026, time.October, 17, 12, 0, 0, 0, loc)), "Daily Summary - AI Assistant")
+	assertSubjects(t, "after catch-up", f.tickAt(s, time.Date(2026, time.October, 17, 12, 1, 0, 0, loc)))
+}
+
+func TestSchedulerDailySummaryFollowsConfig(t *testing.T) {
+	f := newSchedulerFixture(t, time.Date(2026, time.October, 14, 8, 0, 0, 0, time.UTC))
+	s := f.scheduler(t)
+	if err := s.jobs.Remove("weekly-summary"); err != nil {
+		t.Fatalf("failed to remove job: %v", err)
+	}
+
+	// Restarted with a new reminder time, the existing daily job moves
+	f.config.DailyReminderTime = "07:30"
+	s = f.scheduler(t)
+	specs := make(map[string]string)
+	for _, job := range s.jobs.List(s.now()) {
+		specs[job.ID] = job.Spec
+	}
+	if len(specs) != 2 || specs["daily-summary"] != "30 7 * * *" || specs["monthly-summary"] != "0 9 1 * *" {
+		t.Errorf("got jobs %v", specs)
+	}
+	assertSubjects(t, "Oct 15 07:30", f.tickAt(s, time.Date(2026, time.October, 15, 7, 30, 0, 0, time.UTC)), "Daily Summary - AI Assistant")
+}
+
+func TestSchedulerRetriesFailedJobsWithBackoff(t *testing.T) {
+	f := newSchedulerFixture(t, time.Date(2026, time.October, 14, 8, 0, 0, 0, time.UTC))
+	s := f.scheduler(t)
+	removeJobs(t, s)
+	job, err := s.jobs.Add(ScheduledJob{Name: "Nudge", Kind: JobKindCustom, Spec: "0 10 * * *", Task: "Remind me", Misfire: MisfireRunAll})
+	if err != nil {
+		t.Fatalf("failed to add job: %v", err)
+	}
+	var runs []time.Time
+	s.runTask = func(task string) error {
+		runs = append(runs, f.clock.Now())
+		return errors.New("calendar unavailable")
+	}
+
+	// Ticks before each retry is due don't run the job
+	for minute := 0; minute < 60; minute++ {
+		f.tickAt(s, time.Date(2026, time.October, 14, 10, minute, 0, 0, time.UTC))
+	}
+	f.tickAt(s, time.Date(2026, time.October, 14, 11, 30, 0, 0, time.UTC))
+	want := []string{"10:00", "10:01", "10:03", "10:07", "10:15"}
+	var got []string
+	for _, run := range runs {
+		got = append(got, run.Format("15:04"))
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("ran at %v, want %v", got, want)
+	}
+
+	// After five attempts the activation is given up on, keeping the error
+	jobs := s.jobs.List(s.now())
+	if len(jobs) != 1 || jobs[0].LastRun == nil || jobs[0].LastRun.Hour() != 10 || jobs[0].Failures != 0 ||
+		jobs[0].RetryAt != nil || jobs[0].LastError != "calendar unavailable" {
+		t.Fatalf("got job %+v", jobs)
+	}
+
+	// The next activation is tried again, and a success clears the error
+	s.runTask = func(string) error { return nil }
+	f.tickAt(s, time.Date(2026, time.October, 15, 10, 0, 0, 0, time.UTC))
+	if jobs := s.jobs.List(s.now()); jobs[0].ID != job.ID || jobs[0].LastError != "" || jobs[0].LastRun.Day() != 15 {
+		t.Errorf("got job %+v after a successful run", jobs[0])
+	}
+}
+
+func TestJobRegistryDueAfterLongDowntime(t *testing.T) {
+	f := newSchedulerFixture(t, time.Date(2026, time.October, 14, 8, 0, 0, 0, time.UTC))
+	s := f.scheduler(t)
+	removeJobs(t, s)
+	if _, err := s.jobs.Add(ScheduledJob{Kind: JobKindCustom, Spec: "* * * * *", Task: "Ping", Misfire: MisfireRunAll}); err != nil {
+		t.Fatalf("failed to add job: %v", err)
+	}
+
+	// A year of minutely activations is trimmed to the latest maxCatchUp
+	now := time.Date(2027, time.October, 14, 8, 0, 30, 0, time.UTC)
+	due := s.jobs.Due(now)
+	if len(due) != 1 || len(due[0].Times) != maxCatchUp {
+		t.Fatalf("got %d due jobs", len(due))
+	}
+	times := due[0].Times
+	if !times[0].Equal(now.Truncate(time.Minute).Add(-(maxCatchUp-1)*time.Minute)) || !times[maxCatchUp-1].Equal(now.Truncate(time.Minute)) {
+		t.Errorf("due from %v to %v", times[0], times[maxCatchUp-1])
+	}
+}
+
+func TestSchedulerMeetingReminders(t *testing.T) {
+	loc := loadLocation(t)
+	f := newSchedulerFixture(t, time.Date(2026, time.October, 14, 7, 0, 0, 0, loc))
+
+	start := time.Date(2026, time.October, 15, 10, 0, 0, 0, loc)
+	f.calendar.events = []api.Event{
+		{Title: "Design review", StartTime: start, EndTime: start.Add(time.Hour)},
+	}
+	s := f.scheduler(t)
+	removeJobs(t, s)
+
+	assertSubjects(t, "26h before", f.tickAt(s, start.Add(-26*time.Hour)))
+	assertSubjects(t, "24h before", f.tickAt(s, start.Add(-24*time.Hour)), "Meeting Reminder - AI Assistant")
+	assertSubjects(t, "23h59m before", f.tickAt(s, start.Add(-24*time.Hour+time.Minute)))
+	assertSubjects(t, "1h before", f.tickAt(s, start.Add(-time.Hour)))
+	assertSubjects(t, "15m before", f.tickAt(s, start.Add(-15*time.Minute)), "Meeting Reminder - AI Assistant")
+	assertSubjects(t, "14m before", f.tickAt(s, start.Add(-14*time.Minute)))
+
+	// A restarted scheduler remembers what was already sent
+	restarted := f.scheduler(t)
+	assertSubjects(t, "restart 10m before", f.tickAt(restarted, start.Add(-10*time.Minute)))
+	assertSubjects(t, "after start", f.tickAt(restarted, start.Add(time.Minute)))
+}
+
+func TestSchedulerMeetingRemindersAfterChanges(t *testing.T) {
+	loc := loadLocation(t)
+	f := newSchedulerFixture(t, time.Date(2026, time.October, 14, 7, 0, 0, 0, loc))
+	f.config.MeetingReminderOffsets = []int{15}
+
+	start := time.Date(2026, time.October, 14, 12, 0, 0, 0, loc)
+	f.calendar.events = []api.Event{
+		{ID: "review", Title: "Design review", StartTime: start, EndTime: start.Add(time.Hour)},
+	}
+	s := f.scheduler(t)
+	removeJobs(t, s)
+
+	// A reminder that fails to send is retried on the next tick
+	sender := &flakyMailSender{errs: []error{errors.New("connection refused")}}
+	s.email = sender
+	f.clock.Set(start.Add(-15 * time.Minute))
+	s.checkScheduledTasks()
+	f.clock.Set(start.Add(-14 * time.Minute))
+	s.checkScheduledTasks()
+	f.clock.Set(start.Add(-13 * time.Minute))
+	s.checkScheduledTasks()
+	if sender.attempts != 2 || len(sender.sent) != 1 {
+		t.Errorf("made %d attempts and sent %d reminders, want 2 and 1", sender.attempts, len(sender.sent))
+	}
+
+	// Moved to the afternoon, the meeting is reminded of again
+	s.email = f.email
+	moved := start.Add(3 * time.Hour)
+	f.calendar.events[0].StartTime, f.calendar.events[0].EndTime = moved, moved.Add(time.Hour)
+	assertSubjects(t, "15m before the new time", f.tickAt(s, moved.Add(-15*time.Minute)), "Meeting Reminder - AI Assistant")
+	assertSubjects(t, "14m before the new time", f.tickAt(s, moved.Add(-14*time.Minute)))
+}
+
+func TestSchedulerMeetingRemindersSkipMissedOffsets(t *testing.T) {
+	loc := loadLocation(t)
+	f := newSchedulerFixture(t, time.Date(2026, time.October, 14, 7, 0, 0, 0, loc))
+
+	// Booked ten minutes before it starts: only the 15 minute reminder goes out
+	start := time.Date(2026, time.October, 14, 12, 0, 0, 0, loc)
+	f.calendar.events = []api.Event{
+		{Title: "Quick sync", StartTime: start, EndTime: start.Add(30 * time.Minute)},
+	}
+	s := f.scheduler(t)
+	removeJobs(t, s)
+
+	assertSubjects(t, "10m before", f.tickAt(s, start.Add(-10*time.Minute)), "Meeting Reminder - AI Assistant")
+	assertSubjects(t, "9m before", f.tickAt(s, start.Add(-9*time.Minute)))
+}
+
+func TestSchedulerAllDayEvents(t *testing.T) {
+	loc := loadLocation(t)
+	f := newSchedulerFixture(t, time.Date(2026, time.October, 14, 8, 0, 0, 0, loc))
+
+	day := time.Date(2026, time.October, 15, 0, 0, 0, 0, loc)
+	standup := day.Add(9*time.Hour + 30*time.Minute)
+	f.calendar.events = []api.Event{
+		{ID: "offsite", Title: "Team offsite", StartTime: day, EndTime: day.AddDate(0, 0, 2), AllDay: true},
+		{ID: "standup", Title: "Standup", StartTime: standup, EndTime: standup.Add(15 * time.Minute)},
+	}
+	s := f.scheduler(t)
+
+	// The all-day event starts at midnight and is listed without a time
+	f.clock.Set(day.Add(9 * time.Hour))
+	if err := s.sendDailyReminder(); err != nil {
+		t.Fatalf("sendDailyReminder failed: %v", err)
+	}
+	body := f.email.sent[0].Text
+	if !strings.Contains(body, "• Team offsite (all day)\n• Standup at 09:30\n") {
+		t.Errorf("daily summary lacks the all-day event first:\n%s", body)
+	}
+
+	// On its second day, the offsite is still listed
+	f.clock.Set(day.AddDate(0, 0, 1).Add(9 * time.Hour))
+	if meetings := s.getTodaysMeetings(); len(meetings) != 1 || meetings[0].ID != "offsite" {
+		t.Errorf("second day meetings = %v, want the offsite", meetings)
+	}
+}
+
+func TestSchedulerAllDayReminders(t *testing.T) {
+	loc := loadLocation(t)
+	f := newSchedulerFixture(t, time.Date(2026, time.October, 14, 7, 0, 0, 0, loc))
+
+	day := time.Date(2026, time.October, 15, 0, 0, 0, 0, loc)
+	f.calendar.events = []api.Event{
+		{ID: "holiday", Title: "Company holiday", StartTime: day, EndTime: day.AddDate(0, 0, 1), AllDay: true},
+	}
+	s := f.scheduler(t)
+	removeJobs(t, s)
+
+	// Only the day-ahead reminder is sent, dated rather than timed
+	f.clock.Set(day.Add(-24 * time.Hour))
+	s.checkScheduledTasks()
+	if len(f.email.sent) != 1 || !strings.Contains(f.email.sent[0].Text, "Date: Thursday, October 15, 2026 (all day)") {
+		t.Fatalf("day-ahead reminder = %+v, want one dated reminder", f.email.sent)
+	}
+	f.email.take()
+	assertSubjects(t, "15m before", f.tickAt(s, day.Add(-15*time.Minute)))
+}
+
+func TestSchedulerTemplates(t *testing.T) {
+	loc := loadLocation(t)
+	f := newSchedulerFixture(t, time.Date(2026, time.October, 15, 9, 0, 0, 0, loc))
+	f.config.Locale = "es"
+	f.config.TemplatesDir = t.TempDir()
+	override := `{{define "subject"}}Weekly for {{shortDate .Start}}{{end}}Until {{date .End}}`
+	if err := os.WriteFile(filepath.Join(f.config.TemplatesDir, "weekly_summary.txt"), []byte(override), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := f.scheduler(t)
+
+	if err := s.sendDailyReminder(); err != nil {
+		t.Fatalf("sendDailyReminder failed: %v", err)
+	}
+	if err := s.sendWeeklySummary(); err != nil {
+		t.Fatalf("sendWeeklySummary failed: %v", err)
+	}
+
+	daily, weekly := f.email.sent[0], f.email.sent[1]
+	if daily.Subject != "Resumen diario - Asistente de IA" || daily.HTML == "" || !strings.Contains(daily.HTML, "jueves, 15 de octubre de 2026") {
+		t.Errorf("daily summary = %q\n%s", daily.Subject, daily.HTML)
+	}
+	// The override is used in place of the Spanish default, with Spanish
+	// dates, and has no HTML version
+	if weekly.Subject != "Weekly for lun 12 oct 2026" || weekly.Text != "Until domingo, 18 de octubre de 2026" || weekly.HTML != "" {
+		t.Errorf("weekly summary = %+v", weekly)
+	}
+}
+
+func TestSchedulerStopWithoutStart(t *testing.T) {
+	f := newSchedulerFixture(t, time.Date(2026, time.October, 14, 8, 0, 0, 0, time.UTC))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := f.scheduler(t).Stop(ctx); err != nil || ctx.Err() != nil {
+		t.Errorf("stopping an unstarted scheduler returned %v after waiting for %v", err, ctx.Err())
+	}
+}
+
+func TestServiceStopDrainsQueueWhenSchedulerHangs(t *testing.T) {
+	f := newSchedulerFixture(t, time.Date(2026, time.October, 14, 8, 0, 0, 0, time.UTC))
+	s := &Service{
+		logger:    logger.New(),
+		scheduler: f.scheduler(t),
+		inbound:   NewInbound(f.config, logger.New(), f.clock, f.email, nil),
+		queue:     NewQueue(logger.New(), f.clock, 1, 1, nil),
+	}
+	// A scheduler whose loop never finishes
+	s.scheduler.started = true
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := s.Stop(ctx); err == nil || !strings.Contains(err.Error(), "scheduler did not stop") {
+		t.Errorf("got %v", err)
+	}
+	if _, err := s.queue.Submit("later"); err != ErrQueueClosed {
+		t.Errorf("queue still open after a failed stop: %v", err)
+	}
+}
@@ -1,38 +1,66 @@
 package agent
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/azme12/ai-agent-project/internal/api"
+	"github.com/azme12/ai-agent-project/internal/availability"
+	"github.com/azme12/ai-agent-project/internal/clock"
 	"github.com/azme12/ai-agent-project/internal/config"
+	"github.com/azme12/ai-agent-project/internal/store"
+	"github.com/azme12/ai-agent-project/internal/templates"
 	"github.com/azme12/ai-agent-project/pkg/logger"
 )
 
 type Service struct {
 	config    *config.Config
 	logger    *logger.Logger
+	clock     clock.Clock
 	handler   *Handler
 	scheduler *Scheduler
-	calendar  *api.CalendarService
-	email     *api.EmailService
-	nlp       *api.GeminiService
+	calendar  api.CalendarProvider
+	email     api.MailSender
+	nlp       api.LanguageModel
+	tasks     *store.TaskStore
+	queue     *Queue
+	inbound   *Inbound
+	stopOnce  sync.Once
+	stopErr   error
 }
 
-func NewService(cfg *config.Config, log *logger.Logger, cal *api.CalendarService, em *api.EmailService, nlp *api.GeminiService) *Service {
-	handler := NewHandler(cfg, log, cal, em, nlp)
-	scheduler := NewScheduler(cfg, log)
+func NewService(cfg *config.Config, log *logger.Logger, clk clock.Clock, cal api.CalendarProvider, em api.MailSender, nlp api.LanguageModel, tasks *store.TaskStore) *Service {
+	handler := NewHandler(cfg, log, clk, cal, em, nlp)
+	scheduler := NewScheduler(cfg, log, clk)
 
 	// Set API services in scheduler
 	scheduler.calendar = cal
 	scheduler.email = em
 
-	return &Service{
+	s := &Service{
 		config:    cfg,
 		logger:    log,
+		clock:     clk,
 		handler:   handler,
 		scheduler: scheduler,
 		calendar:  cal,
 		email:     em,
 		nlp:       nlp,
+		tasks:     tasks,
+	}
+	s.queue = NewQueue(log, clk, cfg.JobWorkers, cfg.JobQueueSize, s.ProcessTask)
+	s.inbound = NewInbound(cfg, log, clk, em, s.ProcessTask)
+	scheduler.runTask = func(task string) error {
+		_, err := s.ProcessTask(task)
+		return err
 	}
+
+	return s
 }
 
 func (s *Service) Start() error {
@@ -43,23 +71,260 @@ func (s *Service) Start() error {
 		return err
 	}
 
+	// Start job workers
+	s.queue.Start()
+
+	// Accept tasks by email
+	if err := s.inbound.Start(); err != nil {
+		return err
+	}
+
 	s.logger.Info("AI Agent Service started successfully")
 	return nil
 }
 
-func (s *Service) Stop() error {
+// Stop stops the scheduler and drains the job queue, giving up when ctx
+// expires. Only the first call does any work; later calls return its result.
+func (s *Service) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		s.stopErr = s.stop(ctx)
+	})
+	return s.stopErr
+}
+
+func (s *Service) stop(ctx context.Context) error {
 	s.logger.Info("Stopping AI Agent Service")
 
-	if err := s.scheduler.Stop(); err != nil {
-		return err
+	// Everything is stopped even when a part fails, so that queued jobs are
+	// still drained. Email intake stops first, so no task is cut off mid-run.
+	var errs []error
+	if err := s.inbound.Stop(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := s.scheduler.Stop(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	// Finish queued and running jobs
+	if err := s.queue.Stop(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return joinErrors(errs)
 	}
 
 	s.logger.Info("AI Agent Service stopped")
 	return nil
 }
 
-func (s *Service) ProcessTask(task string) error {
-	return s.handler.ProcessTask(task)
+// joinErrors combines errs into one error whose message lists them all.
+func joinErrors(errs []error) error {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
+
+// ProcessTask runs a task and records it in the task history. The returned
+// record reflects the final state of the task, including failures.
+func (s *Service) ProcessTask(task string) (*store.Task, error) {
+	record, err := s.tasks.Create(task)
+	if err != nil {
+		return nil, err
+	}
+
+	result, taskErr := s.handler.ProcessTask(task)
+
+	if result != nil {
+		record.Type = result.Request.Type
+		record.NLPResponse = result.Response
+		record.Outcome = result.Outcome
+		if request, err := json.Marshal(result.Request); err == nil {
+			record.Request = request
+		}
+		if len(result.Conflicts) > 0 || len(result.Suggestions) > 0 {
+			details := map[string]interface{}{
+				"conflicts":   result.Conflicts,
+				"suggestions": result.Suggestions,
+			}
+			if data, err := json.Marshal(details); err == nil {
+				record.Result = data
+			}
+		}
+	}
+
+	record.Status = store.TaskSucceeded
+	if taskErr != nil {
+		record.Status = store.TaskFailed
+		record.Error = taskErr.Error()
+	}
+	completedAt := s.clock.Now()
+	record.CompletedAt = &completedAt
+
+	if err := s.tasks.Update(record); err != nil {
+		s.logger.Error("Failed to record task", "id", record.ID, "error", err)
+	}
+
+	return record, taskErr
+}
+
+// ReceiveEmail runs the task in an email sent to the agent and replies to
+// the sender.
+func (s *Service) ReceiveEmail(email *api.InboundEmail) (*InboundResult, error) {
+	return s.inbound.Receive(email)
+}
+
+// SubmitTask enqueues a task for asynchronous execution.
+func (s *Service) SubmitTask(task string) (*Job, error) {
+	return s.queue.Submit(task)
+}
+
+// GetJob returns the status of a submitted job.
+func (s *Service) GetJob(id string) (*Job, bool) {
+	return s.queue.Get(id)
+}
+
+// ListScheduledJobs returns the scheduler's cron jobs with their next run.
+func (s *Service) ListScheduledJobs() []ScheduledJob {
+	return s.scheduler.Jobs().List(s.scheduler.now())
+}
+
+// AddScheduledJob registers a new cron job.
+func (s *Service) AddScheduledJob(job ScheduledJob) (*ScheduledJob, error) {
+	return s.scheduler.Jobs().Add(job)
+}
+
+// RemoveScheduledJob deletes a cron job.
+func (s *Service) RemoveScheduledJob(id string) error {
+	return s.scheduler.Jobs().Remove(id)
+}
+
+// GetTask returns a task from the history.
+func (s *Service) GetTask(id string) (*store.Task, bool) {
+	return s.tasks.Get(id)
+}
+
+// ListTasks returns the task history matching filter.
+func (s *Service) ListTasks(filter store.TaskFilter) []store.Task {
+	return s.tasks.List(filter)
+}
+
+// ListEvents returns the calendar events overlapping from and to. A
+// non-empty calendar limits them to the calendar with that name.
+func (s *Service) ListEvents(from, to time.Time, calendar string) ([]api.Event, error) {
+	if calendar == "" {
+		return s.calendar.ListEvents(from, to)
+	}
+
+	selected, err := s.handler.calendarNamed(calendar)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.calendar.ListEvents(from, to)
+	if err != nil {
+		return nil, err
+	}
+	filtered := []api.Event{}
+	for _, event := range events {
+		if event.Calendar == selected.Name {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered, nil
+}
+
+// Location returns the configured time zone.
+func (s *Service) Location() *time.Location {
+	return s.config.Location()
+}
+
+// Now returns the current time from the service's clock, in the configured
+// time zone.
+func (s *Service) Now() time.Time {
+	return s.clock.Now().In(s.config.Location())
+}
+
+// Calendars returns the configured calendars with their roles.
+func (s *Service) Calendars() []config.Calendar {
+	return s.config.Calendars
+}
+
+// GetEvent returns a calendar event.
+func (s *Service) GetEvent(id string) (*api.Event, error) {
+	return s.calendar.GetEvent(id)
+}
+
+// UpdateEvent changes the given fields of a calendar event.
+func (s *Service) UpdateEvent(id string, update api.EventUpdate) (*api.Event, error) {
+	return s.calendar.UpdateEvent(id, update)
+}
+
+// CancelEvent removes a calendar event.
+func (s *Service) CancelEvent(id string) error {
+	return s.calendar.DeleteEvent(id)
+}
+
+// RescheduleEvent moves a calendar event, keeping its length when duration
+// is zero.
+func (s *Service) RescheduleEvent(id string, startTime time.Time, duration time.Duration) (*api.Event, error) {
+	return s.calendar.RescheduleEvent(id, startTime, duration)
+}
+
+// ExportCalendar writes the events starting between from and to as an
+// iCalendar feed.
+func (s *Service) ExportCalendar(w io.Writer, from, to time.Time) error {
+	return s.handler.ExportCalendar(w, from, to)
+}
+
+// ImportCalendar creates the events of an iCalendar file.
+func (s *Service) ImportCalendar(r io.Reader) (*ImportResult, error) {
+	return s.handler.ImportCalendar(r)
+}
+
+// ListTemplates says where each email template is loaded from for
+// recipient in locale. They default to the user and the configured locale.
+func (s *Service) ListTemplates(locale, recipient string) ([]templates.Info, error) {
+	locale, recipient = s.templateDefaults(locale, recipient)
+	return s.handler.templates.List(locale, recipient)
+}
+
+// PreviewTemplate renders the named email template with sample data, as it
+// would be sent to recipient in locale.
+func (s *Service) PreviewTemplate(name, locale, recipient string) (*templates.Email, error) {
+	locale, recipient = s.templateDefaults(locale, recipient)
+	return s.handler.templates.Preview(name, locale, recipient, s.handler.clock.Now())
+}
+
+func (s *Service) templateDefaults(locale, recipient string) (string, string) {
+	if locale == "" {
+		locale = s.config.Locale
+	}
+	if recipient == "" {
+		recipient = s.config.UserEmail
+	}
+	return locale, recipient
+}
+
+// SuggestSlots finds open meeting slots for req. When task is set, it fills
+// in whatever req leaves empty.
+func (s *Service) SuggestSlots(task string, req SlotRequest) ([]availability.Slot, error) {
+	if task != "" {
+		parsed, err := s.handler.SlotRequestFromTask(task)
+		if err != nil {
+			return nil, err
+		}
+		if len(req.Attendees) == 0 {
+			req.Attendees = parsed.Attendees
+		}
+		if req.Duration == 0 {
+			req.Duration = parsed.Duration
+		}
+		if req.From.IsZero() && req.To.IsZero() {
+			req.From, req.To = parsed.From, parsed.To
+		}
+	}
+
+	return s.handler.SuggestSlots(req)
 }
 
 func (s *Service) ProcessNLPCommand(command string) (string, error) {
@@ -0,0 +1,32 @@
+package agent
+
+import (
+	"github.com/azme12/ai-agent-project/internal/api"
+	"github.com/azme12/ai-agent-project/internal/templates"
+)
+
+// templateMessage renders the named template into an email to recipient.
+func templateMessage(engine *templates.Engine, name, recipient string, data interface{}) (api.Message, error) {
+	email, err := engine.Render(name, recipient, data)
+	if err != nil {
+		return api.Message{}, err
+	}
+	return api.Message{
+		To:      []string{recipient},
+		Subject: email.Subject,
+		Text:    email.Text,
+		HTML:    email.HTML,
+	}, nil
+}
+
+// templateMeeting converts event to the meeting templates show.
+func templateMeeting(event api.Event) templates.Meeting {
+	return templates.Meeting{
+		Title:     event.Title,
+		Start:     event.StartTime,
+		End:       event.EndTime,
+		AllDay:    event.AllDay,
+		Calendar:  event.Calendar,
+		Attendees: event.Attendees,
+	}
+}
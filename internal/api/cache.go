@@ -0,0 +1,181 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+)
+
+// ErrSyncTokenExpired is returned by SyncEvents when a calendar no longer
+// accepts a sync token, so that its events must be listed again.
+var ErrSyncTokenExpired = errors.New("sync token expired")
+
+// cacheWindow is how far ahead of now a full sync lists events. A day before
+// now is included so that events in progress are kept.
+const cacheWindow = 28 * 24 * time.Hour
+
+// EventChanges is the result of a sync: the events created or changed, the
+// IDs of those cancelled, and the token to sync from next time.
+type EventChanges struct {
+	Events    []Event
+	Deleted   []string
+	SyncToken string
+}
+
+// EventCache keeps a local copy of the coming weeks of a calendar, so that
+// frequent reads such as the scheduler's reminder checks cost at most one
+// incremental sync per interval instead of a full listing each. Changes made
+// through the cache are picked up by the next read. Reads outside the cached
+// window, single event lookups and free/busy queries go to the calendar.
+type EventCache struct {
+	calendar SyncingCalendar
+	clock    clock.Clock
+	interval time.Duration
+
+	mu     sync.Mutex
+	events map[string]Event
+	token  string
+	// from and to bound the cached window, and synced is when the cache
+	// was last brought up to date
+	from, to time.Time
+	synced   time.Time
+}
+
+// NewEventCache returns a cache of calendar that syncs at most once per
+// interval.
+func NewEventCache(calendar SyncingCalendar, clk clock.Clock, interval time.Duration) *EventCache {
+	return &EventCache{calendar: calendar, clock: clk, interval: interval}
+}
+
+func (c *EventCache) ScheduleMeeting(meeting Event) (*Event, error) {
+	event, err := c.calendar.ScheduleMeeting(meeting)
+	c.invalidate()
+	return event, err
+}
+
+func (c *EventCache) GetUpcomingEvents() ([]Event, error) {
+	now := c.clock.Now()
+	return c.ListEvents(now, now.AddDate(0, 0, 7))
+}
+
+// ListEvents returns the events overlapping from and to, as Google does.
+func (c *EventCache) ListEvents(from, to time.Time) ([]Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	if to.After(now.Add(cacheWindow)) || from.Before(now.Add(-24*time.Hour)) {
+		return c.calendar.ListEvents(from, to)
+	}
+	if err := c.refresh(now, to); err != nil {
+		return nil, err
+	}
+
+	events := []Event{}
+	for _, event := range c.events {
+		if event.StartTime.Before(to) && event.EndTime.After(from) {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].StartTime.Equal(events[j].StartTime) {
+			return events[i].StartTime.Before(events[j].StartTime)
+		}
+		return events[i].ID < events[j].ID
+	})
+	return events, nil
+}
+
+func (c *EventCache) GetEvent(id string) (*Event, error) {
+	return c.calendar.GetEvent(id)
+}
+
+func (c *EventCache) UpdateEvent(id string, update EventUpdate) (*Event, error) {
+	event, err := c.calendar.UpdateEvent(id, update)
+	c.invalidate()
+	return event, err
+}
+
+func (c *EventCache) DeleteEvent(id string) error {
+	err := c.calendar.DeleteEvent(id)
+	c.invalidate()
+	return err
+}
+
+func (c *EventCache) RescheduleEvent(id string, startTime time.Time, duration time.Duration) (*Event, error) {
+	event, err := c.calendar.RescheduleEvent(id, startTime, duration)
+	c.invalidate()
+	return event, err
+}
+
+func (c *EventCache) FreeBusy(attendees []string, from, to time.Time) (*FreeBusy, error) {
+	return c.calendar.FreeBusy(attendees, from, to)
+}
+
+// invalidate makes the next read sync, so that a change made through the
+// cache, including every occurrence of a recurring event, is seen at once.
+func (c *EventCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.synced = time.Time{}
+}
+
+// refresh brings the cache up to date unless it was synced within the
+// interval. A full sync replaces the cache when there is none yet, when its
+// window ends before until or when the sync token has expired.
+func (c *EventCache) refresh(now, until time.Time) error {
+	if c.token != "" && !until.After(c.to) && now.Sub(c.synced) < c.interval {
+		return nil
+	}
+
+	if c.token != "" && !until.After(c.to) {
+		changes, err := c.calendar.SyncEvents(c.token, c.from, c.to)
+		if err == nil {
+			c.apply(changes)
+			c.synced = now
+			return nil
+		}
+		if !errors.Is(err, ErrSyncTokenExpired) {
+			return err
+		}
+	}
+
+	from, to := now.Add(-24*time.Hour), now.Add(cacheWindow)
+	changes, err := c.calendar.SyncEvents("", from, to)
+	if err != nil {
+		return err
+	}
+	if changes.SyncToken == "" {
+		return fmt.Errorf("calendar returned no sync token")
+	}
+	c.events = make(map[string]Event)
+	c.from, c.to = from, to
+	c.apply(changes)
+	c.synced = now
+	return nil
+}
+
+// apply merges changes into the cache. A cancelled series removes all of
+// its occurrences, and events outside the cached window are dropped.
+func (c *EventCache) apply(changes *EventChanges) {
+	for _, id := range changes.Deleted {
+		for key, event := range c.events {
+			if event.ID == id || event.RecurringEventID == id {
+				delete(c.events, key)
+			}
+		}
+	}
+	for _, event := range changes.Events {
+		if event.StartTime.Before(c.to) && event.EndTime.After(c.from) {
+			c.events[event.ID] = event
+		} else {
+			delete(c.events, event.ID)
+		}
+	}
+	c.token = changes.SyncToken
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/config"
+)
+
+// syncServer is a stand-in for the Google Calendar events list that serves
+// one event per page and hands out sync tokens for the changes since.
+type syncServer struct {
+	mu       sync.Mutex
+	events   []CalendarEvent
+	changes  []CalendarEvent
+	requests []string
+	// expired makes the next incremental sync fail with 410 Gone
+	expired bool
+}
+
+func newSyncServer(t *testing.T, events ...CalendarEvent) (*syncServer, *httptest.Server) {
+	t.Helper()
+
+	s := &syncServer{events: events}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		q := r.URL.Query()
+		s.requests = append(s.requests, q.Encode())
+
+		if q.Get("syncToken") != "" {
+			if s.expired {
+				s.expired = false
+				w.WriteHeader(http.StatusGone)
+				return
+			}
+			json.NewEncoder(w).Encode(CalendarEventsResponse{Items: s.changes, NextSyncToken: "token-2"})
+			s.changes = nil
+			return
+		}
+
+		page := 0
+		if token := q.Get("pageToken"); token != "" {
+			page = int(token[0] - '0')
+		}
+		response := CalendarEventsResponse{Items: []CalendarEvent{}}
+		if page < len(s.events) {
+			response.Items = append(response.Items, s.events[page])
+		}
+		if page+1 < len(s.events) {
+			response.NextPageToken = string(rune('0' + page + 1))
+		} else if q.Get("orderBy") == "" {
+			response.NextSyncToken = "token-1"
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return s, server
+}
+
+// take returns the queries received so far and forgets them.
+func (s *syncServer) take() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests := s.requests
+	s.requests = nil
+	return requests
+}
+
+func calendarEvent(id, title string, start time.Time) CalendarEvent {
+	return CalendarEvent{
+		ID:      id,
+		Summary: title,
+		Start:   CalendarDateTime{DateTime: start.Format(time.RFC3339)},
+		End:     CalendarDateTime{DateTime: start.Add(time.Hour).Format(time.RFC3339)},
+	}
+}
+
+func eventTitles(events []Event) []string {
+	titles := []string{}
+	for _, event := range events {
+		titles = append(titles, event.Title)
+	}
+	return titles
+}
+
+func TestGoogleCalendarListsEveryPage(t *testing.T) {
+	start := time.Date(2026, time.October, 14, 10, 0, 0, 0, time.UTC)
+	server, ts := newSyncServer(t,
+		calendarEvent("a", "Standup", start),
+		calendarEvent("b", "Planning", start.Add(2*time.Hour)),
+		calendarEvent("c", "Retro", start.Add(4*time.Hour)),
+	)
+
+	cal := NewGoogleCalendarService(&config.Config{GoogleCalendarURL: ts.URL}, clock.NewFake(start), &rotatingTokenSource{tokens: []string{"token"}}, "primary")
+	events, err := cal.ListEvents(start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("ListEvents failed: %v", err)
+	}
+	if got := eventTitles(events); len(got) != 3 || got[2] != "Retro" {
+		t.Errorf("got events %v, want all three pages", got)
+	}
+	if requests := server.take(); len(requests) != 3 {
+		t.Errorf("got %d requests, want one per page", len(requests))
+	}
+}
+
+func TestEventCache(t *testing.T) {
+	now := time.Date(2026, time.October, 14, 8, 0, 0, 0, time.UTC)
+	server, ts := newSyncServer(t,
+		calendarEvent("a", "Standup", now.Add(2*time.Hour)),
+		calendarEvent("b", "Planning", now.Add(26*time.Hour)),
+	)
+
+	clk := clock.NewFake(now)
+	google := NewGoogleCalendarService(&config.Config{GoogleCalendarURL: ts.URL}, clk, &rotatingTokenSource{tokens: []string{"token"}}, "primary")
+	cache := NewEventCache(google, clk, 5*time.Minute)
+
+	// The first read syncs every page
+	events, err := cache.GetUpcomingEvents()
+	if err != nil {
+		t.Fatalf("GetUpcomingEvents failed: %v", err)
+	}
+	if got := eventTitles(events); len(got) != 2 || got[0] != "Standup" {
+		t.Errorf("got events %v, want Standup and Planning", got)
+	}
+	if requests := server.take(); len(requests) != 2 {
+		t.Errorf("got %d requests for the full sync, want 2 pages", len(requests))
+	}
+
+	// Reads within the interval are served from the cache
+	for i := 0; i < 4; i++ {
+		clk.Advance(time.Minute)
+		if _, err := cache.GetUpcomingEvents(); err != nil {
+			t.Fatalf("GetUpcomingEvents failed: %v", err)
+		}
+	}
+	if requests := server.take(); len(requests) != 0 {
+		t.Errorf("got requests %v, want reads from the cache", requests)
+	}
+
+	// Later reads apply the changes since the last sync
+	server.mu.Lock()
+	server.changes = []CalendarEvent{
+		{ID: "a", Status: "cancelled"},
+		calendarEvent("c", "Lunch", now.Add(4*time.Hour)),
+	}
+	server.mu.Unlock()
+	clk.Advance(time.Minute)
+	events, err = cache.GetUpcomingEvents()
+	if err != nil {
+		t.Fatalf("GetUpcomingEvents failed: %v", err)
+	}
+	if got := eventTitles(events); len(got) != 2 || got[0] != "Lunch" || got[1] != "Planning" {
+		t.Errorf("got events %v after sync, want Lunch and Planning", got)
+	}
+	if requests := server.take(); len(requests) != 1 || requests[0] != "singleEvents=true&syncToken=token-1" {
+		t.Errorf("got requests %v, want one incremental sync", requests)
+	}
+
+	// An expired token is replaced by a full sync
+	server.mu.Lock()
+	server.expired = true
+	server.mu.Unlock()
+	clk.Advance(5 * time.Minute)
+	events, err = cache.GetUpcomingEvents()
+	if err != nil {
+		t.Fatalf("GetUpcomingEvents failed: %v", err)
+	}
+	if got := eventTitles(events); len(got) != 2 || got[0] != "Standup" {
+		t.Errorf("got events %v after resync, want the listed events", got)
+	}
+	if requests := server.take(); len(requests) != 3 {
+		t.Errorf("got %d requests, want the rejected sync and 2 pages", len(requests))
+	}
+
+	// Reads beyond the cached window go to the calendar
+	if _, err := cache.ListEvents(now, now.AddDate(0, 2, 0)); err != nil {
+		t.Fatalf("ListEvents failed: %v", err)
+	}
+	if requests := server.take(); len(requests) != 2 {
+		t.Errorf("got %d requests, want a listing of 2 pages", len(requests))
+	}
+}
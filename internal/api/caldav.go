@@ -0,0 +1,690 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/config"
+	"github.com/azme12/ai-agent-project/internal/ical"
+	"github.com/azme12/ai-agent-project/internal/rrule"
+	"github.com/azme12/ai-agent-project/pkg/logger"
+)
+
+// errEventChanged is returned when an event was modified on the server
+// between reading and writing it.
+var errEventChanged = errors.New("event was changed by someone else, try again")
+
+// CalDAVCalendarService books and manages events in a CalDAV calendar, such
+// as one on Nextcloud or Radicale. Each event is stored as its own .ics
+// resource, named after its ID. Recurring events are expanded locally, so
+// the server only needs to store resources and filter them by time.
+type CalDAVCalendarService struct {
+	config *config.Config
+	logger *logger.Logger
+	clock  clock.Clock
+	client *http.Client
+	// calendarID names the calendar to use when CALDAV_URL points at a
+	// calendar home.
+	calendarID string
+
+	mu sync.Mutex
+	// collection is the URL of the calendar collection, ending in "/",
+	// once it has been discovered.
+	collection *url.URL
+}
+
+// calDAVResource is a stored calendar object: a single event, or a series
+// with the occurrences that were edited or cancelled.
+type calDAVResource struct {
+	name   string
+	etag   string
+	events []ical.Event
+}
+
+type davMultistatus struct {
+	Responses []davResponse `xml:"DAV: response"`
+}
+
+type davResponse struct {
+	Href      string        `xml:"DAV: href"`
+	Propstats []davPropstat `xml:"DAV: propstat"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"DAV: prop"`
+	Status string  `xml:"DAV: status"`
+}
+
+type davProp struct {
+	ResourceType struct {
+		Calendar *struct{} `xml:"urn:ietf:params:xml:ns:caldav calendar"`
+	} `xml:"DAV: resourcetype"`
+	DisplayName  string `xml:"DAV: displayname"`
+	ETag         string `xml:"DAV: getetag"`
+	CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+}
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<D:propfind xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:resourcetype/><D:displayname/></D:prop>
+</D:propfind>`
+
+const calendarQueryBody = `<?xml version="1.0" encoding="utf-8"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:getetag/><C:calendar-data/></D:prop>
+  <C:filter>
+    <C:comp-filter name="VCALENDAR">
+      <C:comp-filter name="VEVENT">
+        <C:time-range start="%s" end="%s"/>
+      </C:comp-filter>
+    </C:comp-filter>
+  </C:filter>
+</C:calendar-query>`
+
+func NewCalDAVCalendarService(cfg *config.Config, log *logger.Logger, clk clock.Clock, calendarID string) *CalDAVCalendarService {
+	return &CalDAVCalendarService{
+		config:     cfg,
+		logger:     log,
+		clock:      clk,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		calendarID: calendarID,
+	}
+}
+
+func (c *CalDAVCalendarService) ScheduleMeeting(meeting Event) (*Event, error) {
+	if meeting.Recurrence != "" {
+		if _, err := rrule.Parse(meeting.Recurrence); err != nil {
+			return nil, fmt.Errorf("invalid recurrence: %v", err)
+		}
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate event ID: %v", err)
+	}
+	uid := hex.EncodeToString(b)
+
+	meeting.Status = "confirmed"
+	if meeting.AllDay {
+		meeting.StartTime, meeting.EndTime = allDaySpan(meeting.StartTime, meeting.EndTime, c.config.Location())
+	}
+	res := &calDAVResource{
+		name:   uid + ".ics",
+		events: []ical.Event{ICalEvent(meeting, uid+"@ai-agent")},
+	}
+	if err := c.put(res, true); err != nil {
+		return nil, fmt.Errorf("failed to schedule meeting: %w", err)
+	}
+
+	meeting.ID = res.name
+	meeting.RecurringEventID = ""
+	return &meeting, nil
+}
+
+func (c *CalDAVCalendarService) GetUpcomingEvents() ([]Event, error) {
+	// Get events for the next 7 days
+	now := c.clock.Now()
+	return c.ListEvents(now, now.AddDate(0, 0, 7))
+}
+
+// ListEvents returns the events overlapping from and to, as Google does, with
+// recurring events expanded into single occurrences.
+func (c *CalDAVCalendarService) ListEvents(from, to time.Time) ([]Event, error) {
+	resources, err := c.query(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	var events []Event
+	for _, res := range resources {
+		for _, event := range c.expand(res, from, to) {
+			if event.StartTime.Before(to) && event.EndTime.After(from) {
+				events = append(events, event)
+			}
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].StartTime.Before(events[j].StartTime)
+	})
+	return events, nil
+}
+
+func (c *CalDAVCalendarService) GetEvent(id string) (*Event, error) {
+	res, occurrence, err := c.resource(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+
+	event, ok := c.find(res, occurrence)
+	if !ok {
+		return nil, ErrEventNotFound
+	}
+	return &event, nil
+}
+
+// UpdateEvent edits a single event, a whole series or, when id names one
+// occurrence of a series, just that occurrence.
+func (c *CalDAVCalendarService) UpdateEvent(id string, update EventUpdate) (*Event, error) {
+	res, occurrence, err := c.resource(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update event: %w", err)
+	}
+
+	event, ok := c.find(res, occurrence)
+	if !ok {
+		return nil, ErrEventNotFound
+	}
+
+	vevent := series(res)
+	if !occurrence.IsZero() {
+		if update.Recurrence != nil {
+			return nil, fmt.Errorf("cannot change the recurrence of a single occurrence")
+		}
+
+		// Edited occurrences are stored as overrides of the series
+		master := vevent
+		if vevent = c.override(res, occurrence); vevent == nil {
+			override := ICalEvent(event, master.UID)
+			override.RecurrenceID = occurrence.In(master.Start.Location())
+			res.events = append(res.events, override)
+			vevent = &res.events[len(res.events)-1]
+		}
+	}
+
+	if update.Title != nil {
+		vevent.Summary = *update.Title
+	}
+	if update.Description != nil {
+		vevent.Description = *update.Description
+	}
+	if update.Location != nil {
+		vevent.Location = *update.Location
+	}
+	if update.Attendees != nil {
+		vevent.Attendees = append([]string(nil), (*update.Attendees)...)
+	}
+	if update.StartTime != nil {
+		vevent.Start = *update.StartTime
+	}
+	if update.EndTime != nil {
+		vevent.End = *update.EndTime
+	}
+	if update.Recurrence != nil {
+		if *update.Recurrence != "" {
+			if _, err := rrule.Parse(*update.Recurrence); err != nil {
+				return nil, fmt.Errorf("invalid recurrence: %v", err)
+			}
+		}
+		vevent.RRule = *update.Recurrence
+	}
+	if vevent.End.Before(vevent.Start) {
+		return nil, fmt.Errorf("event cannot end before it starts")
+	}
+	if vevent.AllDay {
+		vevent.Start, vevent.End = allDaySpan(vevent.Start, vevent.End, c.config.Location())
+	}
+
+	if err := c.put(res, false); err != nil {
+		return nil, fmt.Errorf("failed to update event: %w", err)
+	}
+
+	updated := EventFromICal(*vevent, id)
+	if !occurrence.IsZero() {
+		updated.RecurringEventID = res.name
+	}
+	return &updated, nil
+}
+
+// DeleteEvent deletes an event, a whole series or a single occurrence of a
+// series.
+func (c *CalDAVCalendarService) DeleteEvent(id string) error {
+	res, occurrence, err := c.resource(id)
+	if err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+	if _, ok := c.find(res, occurrence); !ok {
+		return ErrEventNotFound
+	}
+
+	if occurrence.IsZero() {
+		u, err := c.resourceURL(res.name)
+		if err != nil {
+			return fmt.Errorf("failed to delete event: %w", err)
+		}
+		header := http.Header{}
+		if res.etag != "" {
+			header.Set("If-Match", res.etag)
+		}
+		if _, _, err := c.do("DELETE", u, header, nil); err != nil {
+			return fmt.Errorf("failed to delete event: %w", err)
+		}
+		return nil
+	}
+
+	// Cancelled occurrences are excluded from the series
+	master := series(res)
+	master.ExDates = append(master.ExDates, occurrence.In(master.Start.Location()))
+	var kept []ical.Event
+	for _, vevent := range res.events {
+		if vevent.RecurrenceID.IsZero() || !vevent.RecurrenceID.Equal(occurrence) {
+			kept = append(kept, vevent)
+		}
+	}
+	res.events = kept
+
+	if err := c.put(res, false); err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+	return nil
+}
+
+func (c *CalDAVCalendarService) RescheduleEvent(id string, startTime time.Time, duration time.Duration) (*Event, error) {
+	return rescheduleEvent(c, id, startTime, duration)
+}
+
+// FreeBusy reports the busy time on the agent's calendar. Other people's
+// CalDAV calendars can't be read, so every attendee is reported as
+// unavailable.
+func (c *CalDAVCalendarService) FreeBusy(attendees []string, from, to time.Time) (*FreeBusy, error) {
+	resources, err := c.query(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query free/busy: %w", err)
+	}
+
+	result := &FreeBusy{
+		Attendees:   make(map[string][]BusyPeriod),
+		Unavailable: append([]string(nil), attendees...),
+	}
+	var events []Event
+	for _, res := range resources {
+		events = append(events, c.expand(res, from, to)...)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].StartTime.Before(events[j].StartTime)
+	})
+	for _, event := range events {
+		// All-day events are shown as free, as Google Calendar does by
+		// default
+		if event.AllDay {
+			continue
+		}
+		if event.StartTime.Before(to) && event.EndTime.After(from) {
+			result.Calendar = append(result.Calendar, BusyPeriod{Start: event.StartTime, End: event.EndTime})
+		}
+	}
+	return result, nil
+}
+
+// expand returns the events of res that may overlap from and to: a single
+// event, or the occurrences of a series with its edits applied, in the
+// configured time zone. Cancelled events are left out.
+func (c *CalDAVCalendarService) expand(res *calDAVResource, from, to time.Time) []Event {
+	events := c.occurrences(res, from, to)
+	for i := range events {
+		events[i] = c.local(events[i])
+	}
+	return events
+}
+
+// occurrences implements expand, leaving events in the time zones they
+// were stored in.
+func (c *CalDAVCalendarService) occurrences(res *calDAVResource, from, to time.Time) []Event {
+	master := series(res)
+	if master == nil {
+		return nil
+	}
+	if master.RRule == "" {
+		if master.Status == "cancelled" {
+			return nil
+		}
+		return []Event{EventFromICal(*master, res.name)}
+	}
+
+	rule, err := rrule.Parse(master.RRule)
+	if err != nil {
+		return nil
+	}
+
+	var events []Event
+	seriesEvent := EventFromICal(*master, res.name)
+	length := master.End.Sub(master.Start)
+	for _, start := range rule.Occurrences(master.Start, from.Add(-length), to.Add(time.Second)) {
+		if excluded(master, start) || c.override(res, start) != nil {
+			continue
+		}
+		events = append(events, occurrence(seriesEvent, start))
+	}
+
+	// Edited occurrences may have moved into or out of the window
+	for _, vevent := range res.events {
+		if vevent.RecurrenceID.IsZero() || vevent.Status == "cancelled" || excluded(master, vevent.RecurrenceID) {
+			continue
+		}
+		if vevent.Start.Before(to) && !vevent.End.Before(from) {
+			events = append(events, c.edited(res, vevent))
+		}
+	}
+	return events
+}
+
+// find returns the event of res, or its occurrence at the given original
+// start time when that is set, in the configured time zone.
+func (c *CalDAVCalendarService) find(res *calDAVResource, start time.Time) (Event, bool) {
+	event, ok := c.lookup(res, start)
+	return c.local(event), ok
+}
+
+// local converts the times of event to the configured time zone. Stored
+// events keep the zone they were created in, which recurrences follow.
+func (c *CalDAVCalendarService) local(event Event) Event {
+	loc := c.config.Location()
+	event.StartTime, event.EndTime = event.StartTime.In(loc), event.EndTime.In(loc)
+	return event
+}
+
+// lookup implements find.
+func (c *CalDAVCalendarService) lookup(res *calDAVResource, start time.Time) (Event, bool) {
+	master := series(res)
+	if master == nil {
+		return Event{}, false
+	}
+	if start.IsZero() {
+		return EventFromICal(*master, res.name), master.Status != "cancelled"
+	}
+	if master.RRule == "" || excluded(master, start) {
+		return Event{}, false
+	}
+
+	if vevent := c.override(res, start); vevent != nil {
+		return c.edited(res, *vevent), vevent.Status != "cancelled"
+	}
+
+	rule, err := rrule.Parse(master.RRule)
+	if err != nil {
+		return Event{}, false
+	}
+	for _, t := range rule.Occurrences(master.Start, start, start.Add(time.Second)) {
+		if t.Equal(start) {
+			return occurrence(EventFromICal(*master, res.name), t), true
+		}
+	}
+	return Event{}, false
+}
+
+// edited converts an override of res to the occurrence it replaces.
+func (c *CalDAVCalendarService) edited(res *calDAVResource, vevent ical.Event) Event {
+	event := EventFromICal(vevent, res.name+"_"+vevent.RecurrenceID.UTC().Format(occurrenceLayout))
+	event.RecurringEventID = res.name
+	event.Recurrence = ""
+	return event
+}
+
+// override returns the event of res overriding the occurrence originally
+// starting at start, if there is one.
+func (c *CalDAVCalendarService) override(res *calDAVResource, start time.Time) *ical.Event {
+	for i := range res.events {
+		if !res.events[i].RecurrenceID.IsZero() && res.events[i].RecurrenceID.Equal(start) {
+			return &res.events[i]
+		}
+	}
+	return nil
+}
+
+// series returns the main event of res, which is the series of a recurring
+// event.
+func series(res *calDAVResource) *ical.Event {
+	for i := range res.events {
+		if res.events[i].RecurrenceID.IsZero() {
+			return &res.events[i]
+		}
+	}
+	return nil
+}
+
+func excluded(master *ical.Event, start time.Time) bool {
+	for _, exdate := range master.ExDates {
+		if exdate.Equal(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// resource fetches the resource an event ID refers to, along with the
+// original start time of the occurrence it names, if any.
+func (c *CalDAVCalendarService) resource(id string) (*calDAVResource, time.Time, error) {
+	name, start := id, time.Time{}
+	if seriesID, t, ok := splitOccurrenceID(id); ok {
+		name, start = seriesID, t
+	}
+	if name == "" || strings.Contains(name, "/") {
+		return nil, time.Time{}, ErrEventNotFound
+	}
+
+	u, err := c.resourceURL(name)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	header, body, err := c.do("GET", u, nil, nil)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	calendar, err := ical.Decode(bytes.NewReader(body), c.config.Location())
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid calendar data in %s: %v", name, err)
+	}
+	return &calDAVResource{name: name, etag: header.Get("ETag"), events: calendar.Events}, start, nil
+}
+
+// query returns the resources with events between from and to.
+func (c *CalDAVCalendarService) query(from, to time.Time) ([]*calDAVResource, error) {
+	collection, err := c.collectionURL()
+	if err != nil {
+		return nil, err
+	}
+
+	body := fmt.Sprintf(calendarQueryBody, from.UTC().Format(occurrenceLayout), to.UTC().Format(occurrenceLayout))
+	header := http.Header{"Depth": {"1"}, "Content-Type": {"application/xml; charset=utf-8"}}
+	_, data, err := c.do("REPORT", collection.String(), header, []byte(body))
+	if err != nil {
+		return nil, err
+	}
+
+	var multistatus davMultistatus
+	if err := xml.Unmarshal(data, &multistatus); err != nil {
+		return nil, fmt.Errorf("failed to decode calendar query: %v", err)
+	}
+
+	var resources []*calDAVResource
+	for _, response := range multistatus.Responses {
+		for _, propstat := range response.Propstats {
+			if propstat.Prop.CalendarData == "" {
+				continue
+			}
+
+			calendar, err := ical.Decode(strings.NewReader(propstat.Prop.CalendarData), c.config.Location())
+			if err != nil {
+				// One unreadable event shouldn't hide the rest
+				c.logger.Warn("Skipping unreadable CalDAV event", "href", response.Href, "error", err)
+				continue
+			}
+			resources = append(resources, &calDAVResource{
+				name:   resourceName(response.Href),
+				etag:   propstat.Prop.ETag,
+				events: calendar.Events,
+			})
+		}
+	}
+	return resources, nil
+}
+
+// put stores res, creating it when create is set. Existing resources are only
+// replaced if they haven't changed since they were read.
+func (c *CalDAVCalendarService) put(res *calDAVResource, create bool) error {
+	var buf bytes.Buffer
+	calendar := &ical.Calendar{Events: res.events}
+	if err := calendar.Encode(&buf, c.clock.Now()); err != nil {
+		return err
+	}
+
+	header := http.Header{"Content-Type": {"text/calendar; charset=utf-8"}}
+	if create {
+		header.Set("If-None-Match", "*")
+	} else if res.etag != "" {
+		header.Set("If-Match", res.etag)
+	}
+
+	u, err := c.resourceURL(res.name)
+	if err != nil {
+		return err
+	}
+	responseHeader, _, err := c.do("PUT", u, header, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	res.etag = responseHeader.Get("ETag")
+	return nil
+}
+
+// collectionURL returns the calendar collection URL. CALDAV_URL may point at
+// the calendar itself or at the collection holding the user's calendars, in
+// which case the calendar named by calendarID, or the first one for
+// "primary", is used.
+func (c *CalDAVCalendarService) collectionURL() (*url.URL, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.collection != nil {
+		return c.collection, nil
+	}
+
+	base, err := url.Parse(c.config.CalDAVURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CALDAV_URL: %v", err)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+
+	header := http.Header{"Depth": {"1"}, "Content-Type": {"application/xml; charset=utf-8"}}
+	_, data, err := c.do("PROPFIND", base.String(), header, []byte(propfindBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover calendar: %w", err)
+	}
+
+	var multistatus davMultistatus
+	if err := xml.Unmarshal(data, &multistatus); err != nil {
+		return nil, fmt.Errorf("failed to decode calendar discovery: %v", err)
+	}
+
+	var first *url.URL
+	for _, response := range multistatus.Responses {
+		href, err := base.Parse(response.Href)
+		if err != nil {
+			continue
+		}
+		for _, propstat := range response.Propstats {
+			if propstat.Prop.ResourceType.Calendar == nil {
+				continue
+			}
+
+			// The URL names a calendar
+			if strings.TrimSuffix(href.Path, "/") == strings.TrimSuffix(base.Path, "/") {
+				c.collection = base
+				return c.collection, nil
+			}
+
+			if !strings.HasSuffix(href.Path, "/") {
+				href.Path += "/"
+			}
+			if first == nil {
+				first = href
+			}
+			if resourceName(href.Path) == c.calendarID || propstat.Prop.DisplayName == c.calendarID {
+				c.collection = href
+				return c.collection, nil
+			}
+		}
+	}
+
+	if first == nil || (c.calendarID != "" && c.calendarID != "primary") {
+		return nil, fmt.Errorf("no calendar %q found at %s", c.calendarID, c.config.CalDAVURL)
+	}
+	c.collection = first
+	return c.collection, nil
+}
+
+// resourceURL returns the URL of the resource with the given name.
+func (c *CalDAVCalendarService) resourceURL(name string) (string, error) {
+	collection, err := c.collectionURL()
+	if err != nil {
+		return "", err
+	}
+	u := *collection
+	u.Path += name
+	return u.String(), nil
+}
+
+// do sends a WebDAV request and returns the response headers and body. 404
+// and 410 responses are reported as ErrEventNotFound and failed
+// preconditions as errEventChanged.
+func (c *CalDAVCalendarService) do(method, u string, header http.Header, body []byte) (http.Header, []byte, error) {
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if c.config.CalDAVUsername != "" {
+		req.SetBasicAuth(c.config.CalDAVUsername, c.config.CalDAVPassword)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return nil, nil, ErrEventNotFound
+	case resp.StatusCode == http.StatusPreconditionFailed:
+		return nil, nil, errEventChanged
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, nil, fmt.Errorf("caldav %s error: %d - %s", method, resp.StatusCode, string(data))
+	}
+	return resp.Header, data, nil
+}
+
+// resourceName returns the last path segment of href.
+func resourceName(href string) string {
+	if u, err := url.Parse(href); err == nil {
+		href = u.Path
+	}
+	name := path.Base(strings.TrimSuffix(href, "/"))
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		return unescaped
+	}
+	return name
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/config"
+	"github.com/azme12/ai-agent-project/pkg/logger"
+)
+
+// calDAVServer is an in-process CalDAV stand-in serving one user's home
+// collection with a single calendar. Calendar queries return every stored
+// resource; the client filters them by time itself.
+type calDAVServer struct {
+	mu        sync.Mutex
+	resources map[string]string
+	etags     map[string]int
+	version   int
+}
+
+const calDAVCalendarPath = "/dav/calendars/alice/work/"
+
+func newCalDAVServer(t *testing.T) (*calDAVServer, *httptest.Server) {
+	t.Helper()
+
+	s := &calDAVServer{resources: map[string]string{}, etags: map[string]int{}}
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return s, server
+}
+
+func (s *calDAVServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, password, ok := r.BasicAuth(); !ok || user != "alice" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, calDAVCalendarPath)
+	etag := func(name string) string { return fmt.Sprintf(`"%d"`, s.etags[name]) }
+
+	switch {
+	case r.Method == "PROPFIND" && r.URL.Path == "/dav/calendars/alice/":
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, `<?xml version="1.0"?>
+<d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav">
+  <d:response><d:href>/dav/calendars/alice/</d:href>
+    <d:propstat><d:prop><d:resourcetype><d:collection/></d:resourcetype></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>
+  </d:response>
+  <d:response><d:href>/dav/calendars/alice/personal/</d:href>
+    <d:propstat><d:prop><d:resourcetype><d:collection/><cal:calendar/></d:resourcetype><d:displayname>Personal</d:displayname></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>
+  </d:response>
+  <d:response><d:href>`+calDAVCalendarPath+`</d:href>
+    <d:propstat><d:prop><d:resourcetype><d:collection/><cal:calendar/></d:resourcetype><d:displayname>Work</d:displayname></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>
+  </d:response>
+</d:multistatus>`)
+
+	case r.Method == "REPORT" && r.URL.Path == calDAVCalendarPath:
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, `<?xml version="1.0"?><d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav">`)
+		for name, data := range s.resources {
+			fmt.Fprintf(w, `<d:response><d:href>%s%s</d:href><d:propstat><d:prop><d:getetag>%s</d:getetag><cal:calendar-data>%s</cal:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`,
+				calDAVCalendarPath, name, etag(name), data)
+		}
+		fmt.Fprint(w, `</d:multistatus>`)
+
+	case r.Method == http.MethodGet:
+		data, ok := s.resources[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", etag(name))
+		fmt.Fprint(w, data)
+
+	case r.Method == http.MethodPut:
+		_, exists := s.resources[name]
+		if r.Header.Get("If-None-Match") == "*" && exists {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		if match := r.Header.Get("If-Match"); match != "" && (!exists || match != etag(name)) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		s.version++
+		s.resources[name] = string(data)
+		s.etags[name] = s.version
+		w.Header().Set("ETag", etag(name))
+		w.WriteHeader(http.StatusCreated)
+
+	case r.Method == http.MethodDelete:
+		if _, ok := s.resources[name]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(s.resources, name)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestCalDAVCalendarService(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+
+	server, httpServer := newCalDAVServer(t)
+	now := time.Date(2026, time.October, 14, 10, 0, 0, 0, loc)
+	cal := NewCalDAVCalendarService(&config.Config{
+		CalDAVURL:      httpServer.URL + "/dav/calendars/alice",
+		CalDAVUsername: "alice",
+		CalDAVPassword: "secret",
+		TimeZone:       "Europe/Berlin",
+	}, logger.New(), clock.NewFake(now), "Work")
+
+	// Weekly on Mondays across the end of daylight saving time
+	monday := func(day int) time.Time { return time.Date(2026, time.October, day, 9, 0, 0, 0, loc) }
+	standup, err := cal.ScheduleMeeting(Event{
+		Title:      "Standup",
+		StartTime:  monday(19),
+		EndTime:    monday(19).Add(15 * time.Minute),
+		Attendees:  []string{"bob@example.com"},
+		Recurrence: "FREQ=WEEKLY;BYDAY=MO;COUNT=3",
+	})
+	if err != nil {
+		t.Fatalf("ScheduleMeeting failed: %v", err)
+	}
+	review, err := cal.ScheduleMeeting(Event{Title: "Review", StartTime: now.Add(2 * time.Hour), EndTime: now.Add(3 * time.Hour)})
+	if err != nil {
+		t.Fatalf("ScheduleMeeting failed: %v", err)
+	}
+	if len(server.resources) != 2 {
+		t.Fatalf("server holds %d resources, want 2", len(server.resources))
+	}
+
+	events, err := cal.ListEvents(now, now.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("ListEvents failed: %v", err)
+	}
+	if len(events) != 4 || events[0].ID != review.ID {
+		t.Fatalf("got %d events starting with %q, want review and 3 standups", len(events), events[0].ID)
+	}
+	for i, day := range []int{19, 26} {
+		if got := events[i+1]; !got.StartTime.Equal(monday(day)) || got.RecurringEventID != standup.ID {
+			t.Errorf("standup %d = %s of %q, want %s of %q", i, got.StartTime, got.RecurringEventID, monday(day), standup.ID)
+		}
+	}
+
+	// Moving one occurrence leaves the others in place
+	second := events[2]
+	moved := monday(27).Add(time.Hour)
+	updated, err := cal.RescheduleEvent(second.ID, moved, 0)
+	if err != nil {
+		t.Fatalf("RescheduleEvent failed: %v", err)
+	}
+	if !updated.StartTime.Equal(moved) || updated.EndTime.Sub(updated.StartTime) != 15*time.Minute {
+		t.Errorf("rescheduled occurrence = %s-%s", updated.StartTime, updated.EndTime)
+	}
+	if got, err := cal.GetEvent(second.ID); err != nil || !got.StartTime.Equal(moved) {
+		t.Errorf("GetEvent of moved occurrence = %v, %v", got, err)
+	}
+
+	// Cancelling another occurrence excludes it from the series
+	if err := cal.DeleteEvent(events[3].ID); err != nil {
+		t.Fatalf("DeleteEvent of occurrence failed: %v", err)
+	}
+	if _, err := cal.GetEvent(events[3].ID); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("GetEvent of cancelled occurrence = %v, want ErrEventNotFound", err)
+	}
+
+	busy, err := cal.FreeBusy([]string{"bob@example.com"}, now, now.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("FreeBusy failed: %v", err)
+	}
+	if len(busy.Calendar) != 3 || !busy.Calendar[2].Start.Equal(moved) || len(busy.Unavailable) != 1 {
+		t.Errorf("got busy %v, unavailable %v", busy.Calendar, busy.Unavailable)
+	}
+
+	// Stale writes are refused
+	stale, _, err := cal.resource(review.ID)
+	if err != nil {
+		t.Fatalf("failed to read review: %v", err)
+	}
+	title := "Design review"
+	if _, err := cal.UpdateEvent(review.ID, EventUpdate{Title: &title}); err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+	if err := cal.put(stale, false); !errors.Is(err, errEventChanged) {
+		t.Errorf("stale put = %v, want errEventChanged", err)
+	}
+
+	if err := cal.DeleteEvent(standup.ID); err != nil {
+		t.Fatalf("DeleteEvent of series failed: %v", err)
+	}
+	if _, err := cal.GetEvent(standup.ID); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("GetEvent of deleted series = %v, want ErrEventNotFound", err)
+	}
+	if events, _ := cal.ListEvents(now, now.AddDate(0, 1, 0)); len(events) != 1 || events[0].Title != title {
+		t.Errorf("got %v after deleting the series, want the renamed review", events)
+	}
+}
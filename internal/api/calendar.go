@@ -3,20 +3,32 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/azme12/ai-agent-project/internal/clock"
 	"github.com/azme12/ai-agent-project/internal/config"
+	"github.com/azme12/ai-agent-project/internal/oauth"
 )
 
-type CalendarService struct {
-	config *config.Config
-	client *http.Client
+var ErrEventNotFound = errors.New("event not found")
+
+type GoogleCalendarService struct {
+	config     *config.Config
+	clock      clock.Clock
+	auth       oauth.TokenSource
+	client     *http.Client
+	calendarID string
 }
 
 type CalendarEvent struct {
+	ID                 string                 `json:"id,omitempty"`
+	Status             string                 `json:"status,omitempty"`
 	Summary            string                 `json:"summary"`
 	Description        string                 `json:"description,omitempty"`
 	Start              CalendarDateTime       `json:"start"`
@@ -24,12 +36,17 @@ type CalendarEvent struct {
 	Attendees          []CalendarAttendee     `json:"attendees,omitempty"`
 	Reminders          CalendarReminders      `json:"reminders,omitempty"`
 	Location           string                 `json:"location,omitempty"`
+	Recurrence         []string               `json:"recurrence,omitempty"`
+	RecurringEventID   string                 `json:"recurringEventId,omitempty"`
 	ExtendedProperties map[string]interface{} `json:"extendedProperties,omitempty"`
 }
 
+// CalendarDateTime is the start or end of a Google Calendar event: a
+// DateTime for timed events or a Date for all-day events.
 type CalendarDateTime struct {
-	DateTime string `json:"dateTime"`
-	TimeZone string `json:"timeZone"`
+	Date     string `json:"date,omitempty"`
+	DateTime string `json:"dateTime,omitempty"`
+	TimeZone string `json:"timeZone,omitempty"`
 }
 
 type CalendarAttendee struct {
@@ -41,172 +58,549 @@ type CalendarReminders struct {
 	UseDefault bool `json:"useDefault"`
 }
 
+// CalendarEventsResponse is one page of an events list. The last page
+// carries a NextSyncToken for listing later changes instead.
 type CalendarEventsResponse struct {
-	Items []CalendarEvent `json:"items"`
+	Items         []CalendarEvent `json:"items"`
+	NextPageToken string          `json:"nextPageToken,omitempty"`
+	NextSyncToken string          `json:"nextSyncToken,omitempty"`
 }
 
-func NewGoogleCalendarService(cfg *config.Config) *CalendarService {
-	return &CalendarService{
-		config: cfg,
-		client: &http.Client{Timeout: 30 * time.Second},
-	}
+type FreeBusyRequest struct {
+	TimeMin  string         `json:"timeMin"`
+	TimeMax  string         `json:"timeMax"`
+	TimeZone string         `json:"timeZone,omitempty"`
+	Items    []FreeBusyItem `json:"items"`
 }
 
-func (c *CalendarService) ScheduleMeeting(attendees []string, startTime time.Time, duration time.Duration, title string) error {
-	if c.config.GoogleCalendarAPIKey == "" {
-		return c.mockScheduleMeeting(attendees, startTime, duration, title)
-	}
+type FreeBusyItem struct {
+	ID string `json:"id"`
+}
+
+type FreeBusyResponse struct {
+	Calendars map[string]struct {
+		Busy []struct {
+			Start string `json:"start"`
+			End   string `json:"end"`
+		} `json:"busy"`
+		Errors []struct {
+			Reason string `json:"reason"`
+		} `json:"errors"`
+	} `json:"calendars"`
+}
 
-	url := fmt.Sprintf("%s/calendars/%s/events", c.config.GoogleCalendarURL, c.config.CalendarID)
+func NewGoogleCalendarService(cfg *config.Config, clk clock.Clock, auth oauth.TokenSource, calendarID string) *GoogleCalendarService {
+	return &GoogleCalendarService{
+		config:     cfg,
+		clock:      clk,
+		auth:       auth,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		calendarID: calendarID,
+	}
+}
 
+func (c *GoogleCalendarService) ScheduleMeeting(meeting Event) (*Event, error) {
 	// Convert attendees to proper format
 	var calendarAttendees []CalendarAttendee
-	for _, email := range attendees {
+	for _, email := range meeting.Attendees {
 		calendarAttendees = append(calendarAttendees, CalendarAttendee{
 			Email: email,
 		})
 	}
 
 	event := CalendarEvent{
-		Summary: title,
-		Start: CalendarDateTime{
-			DateTime: startTime.Format(time.RFC3339),
-			TimeZone: c.config.TimeZone,
-		},
-		End: CalendarDateTime{
-			DateTime: startTime.Add(duration).Format(time.RFC3339),
-			TimeZone: c.config.TimeZone,
-		},
-		Attendees: calendarAttendees,
+		Summary:     meeting.Title,
+		Description: meeting.Description,
+		Location:    meeting.Location,
+		Attendees:   calendarAttendees,
 		Reminders: CalendarReminders{
 			UseDefault: true,
 		},
 	}
+	event.Start, event.End = c.calendarTimes(meeting.StartTime, meeting.EndTime, meeting.AllDay)
+	if meeting.Recurrence != "" {
+		// Google expands the rule in the start time's time zone
+		event.Recurrence = []string{"RRULE:" + meeting.Recurrence}
+	}
 
-	jsonData, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("failed to marshal event: %v", err)
+	var created CalendarEvent
+	if err := c.do("POST", c.eventsURL(""), event, &created); err != nil {
+		return nil, fmt.Errorf("failed to schedule meeting: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	fmt.Printf("Successfully scheduled meeting:\nTitle: %s\nAttendees: %v\nStart: %s\nDuration: %v\n",
+		meeting.Title, meeting.Attendees, meeting.StartTime.Format("2006-01-02 15:04:05"), meeting.EndTime.Sub(meeting.StartTime))
+
+	return c.toEvent(created)
+}
+
+func (c *GoogleCalendarService) GetUpcomingEvents() ([]Event, error) {
+	// Get events for the next 7 days
+	now := c.clock.Now()
+	return c.ListEvents(now, now.AddDate(0, 0, 7))
+}
+
+// ListEvents returns the events overlapping from and to, with recurring
+// events expanded into single occurrences.
+func (c *GoogleCalendarService) ListEvents(from, to time.Time) ([]Event, error) {
+	q := url.Values{}
+	q.Add("timeMin", from.Format(time.RFC3339))
+	q.Add("timeMax", to.Format(time.RFC3339))
+	q.Add("singleEvents", "true")
+	q.Add("orderBy", "startTime")
+
+	items, _, err := c.list(q)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %v", err)
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	// Convert to internal Event format
+	var events []Event
+	for _, calEvent := range items {
+		event, err := c.toEvent(calEvent)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *event)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.config.GoogleCalendarAPIKey)
-	req.Header.Set("Content-Type", "application/json")
+	return events, nil
+}
+
+// SyncEvents lists the events between from and to when token is empty, and
+// otherwise the events created, changed or cancelled since the sync that
+// returned token. Recurring events are expanded into single occurrences.
+func (c *GoogleCalendarService) SyncEvents(token string, from, to time.Time) (*EventChanges, error) {
+	q := url.Values{}
+	q.Add("singleEvents", "true")
+	if token == "" {
+		q.Add("timeMin", from.Format(time.RFC3339))
+		q.Add("timeMax", to.Format(time.RFC3339))
+	} else {
+		// Time bounds can't be combined with a sync token, which reports
+		// changes anywhere in the calendar
+		q.Add("syncToken", token)
+	}
 
-	resp, err := c.client.Do(req)
+	items, next, err := c.list(q)
+	if errors.Is(err, ErrEventNotFound) && token != "" {
+		// Google answers 410 Gone once a sync token has expired
+		return nil, ErrSyncTokenExpired
+	}
 	if err != nil {
-		return fmt.Errorf("failed to schedule meeting: %v", err)
+		return nil, fmt.Errorf("failed to sync events: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("calendar API error: %d - %s", resp.StatusCode, string(body))
+	changes := &EventChanges{SyncToken: next}
+	for _, calEvent := range items {
+		if calEvent.Status == "cancelled" {
+			changes.Deleted = append(changes.Deleted, calEvent.ID)
+			continue
+		}
+		event, err := c.toEvent(calEvent)
+		if err != nil {
+			return nil, err
+		}
+		changes.Events = append(changes.Events, *event)
 	}
+	return changes, nil
+}
 
-	fmt.Printf("Successfully scheduled meeting:\nTitle: %s\nAttendees: %v\nStart: %s\nDuration: %v\n",
-		title, attendees, startTime.Format("2006-01-02 15:04:05"), duration)
+// list fetches every page of an events list with the query q, returning the
+// events and the sync token of the last page.
+func (c *GoogleCalendarService) list(q url.Values) ([]CalendarEvent, string, error) {
+	var items []CalendarEvent
+	for {
+		var response CalendarEventsResponse
+		if err := c.do("GET", c.eventsURL("")+"?"+q.Encode(), nil, &response); err != nil {
+			return nil, "", err
+		}
+		items = append(items, response.Items...)
 
-	return nil
+		if response.NextPageToken == "" {
+			return items, response.NextSyncToken, nil
+		}
+		q.Set("pageToken", response.NextPageToken)
+	}
 }
 
-func (c *CalendarService) GetUpcomingEvents() ([]Event, error) {
-	if c.config.GoogleCalendarAPIKey == "" {
-		return c.mockGetUpcomingEvents()
+func (c *GoogleCalendarService) GetEvent(id string) (*Event, error) {
+	var calEvent CalendarEvent
+	if err := c.do("GET", c.eventsURL(id), nil, &calEvent); err != nil {
+		return nil, fmt.Errorf("failed to get event: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/calendars/%s/events", c.config.GoogleCalendarURL, c.config.CalendarID)
+	if calEvent.Status == "cancelled" {
+		return nil, ErrEventNotFound
+	}
 
-	// Get events for the next 7 days
-	now := time.Now()
-	endTime := now.AddDate(0, 0, 7)
+	return c.toEvent(calEvent)
+}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+func (c *GoogleCalendarService) UpdateEvent(id string, update EventUpdate) (*Event, error) {
+	patch := map[string]interface{}{}
+	if update.Title != nil {
+		patch["summary"] = *update.Title
+	}
+	if update.Description != nil {
+		patch["description"] = *update.Description
+	}
+	if update.Location != nil {
+		patch["location"] = *update.Location
+	}
+	if update.Attendees != nil {
+		attendees := []CalendarAttendee{}
+		for _, email := range *update.Attendees {
+			attendees = append(attendees, CalendarAttendee{Email: email})
+		}
+		patch["attendees"] = attendees
+	}
+	if update.StartTime != nil || update.EndTime != nil {
+		// Both ends are written so that all-day events stay all-day
+		current, err := c.GetEvent(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update event: %w", err)
+		}
+		start, end := current.StartTime, current.EndTime
+		if update.StartTime != nil {
+			start = *update.StartTime
+		}
+		if update.EndTime != nil {
+			end = *update.EndTime
+		}
+		patch["start"], patch["end"] = c.calendarTimes(start, end, current.AllDay)
+	}
+	if update.Recurrence != nil {
+		recurrence := []string{}
+		if *update.Recurrence != "" {
+			recurrence = append(recurrence, "RRULE:"+*update.Recurrence)
+		}
+		patch["recurrence"] = recurrence
 	}
 
-	q := req.URL.Query()
-	q.Add("timeMin", now.Format(time.RFC3339))
-	q.Add("timeMax", endTime.Format(time.RFC3339))
-	q.Add("singleEvents", "true")
-	q.Add("orderBy", "startTime")
-	req.URL.RawQuery = q.Encode()
+	var updated CalendarEvent
+	if err := c.do("PATCH", c.eventsURL(id), patch, &updated); err != nil {
+		return nil, fmt.Errorf("failed to update event: %w", err)
+	}
 
-	req.Header.Set("Authorization", "Bearer "+c.config.GoogleCalendarAPIKey)
+	return c.toEvent(updated)
+}
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get events: %v", err)
+func (c *GoogleCalendarService) DeleteEvent(id string) error {
+	if err := c.do("DELETE", c.eventsURL(id), nil, nil); err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
 	}
-	defer resp.Body.Close()
+	return nil
+}
+
+func (c *GoogleCalendarService) RescheduleEvent(id string, startTime time.Time, duration time.Duration) (*Event, error) {
+	return rescheduleEvent(c, id, startTime, duration)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("calendar API error: %d - %s", resp.StatusCode, string(body))
+// FreeBusy queries the busy periods of the agent's calendar and of each
+// attendee between from and to.
+func (c *GoogleCalendarService) FreeBusy(attendees []string, from, to time.Time) (*FreeBusy, error) {
+	request := FreeBusyRequest{
+		TimeMin:  from.Format(time.RFC3339),
+		TimeMax:  to.Format(time.RFC3339),
+		TimeZone: c.config.TimeZone,
+		Items:    []FreeBusyItem{{ID: c.calendarID}},
+	}
+	for _, email := range attendees {
+		request.Items = append(request.Items, FreeBusyItem{ID: email})
 	}
 
-	var response CalendarEventsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+	var response FreeBusyResponse
+	if err := c.do("POST", c.config.GoogleCalendarURL+"/freeBusy", request, &response); err != nil {
+		return nil, fmt.Errorf("failed to query free/busy: %w", err)
 	}
 
-	// Convert to internal Event format
-	var events []Event
-	for _, calEvent := range response.Items {
-		startTime, _ := time.Parse(time.RFC3339, calEvent.Start.DateTime)
-		endTime, _ := time.Parse(time.RFC3339, calEvent.End.DateTime)
+	result := &FreeBusy{Attendees: make(map[string][]BusyPeriod)}
+	for _, item := range request.Items {
+		calendar, ok := response.Calendars[item.ID]
+		if !ok || len(calendar.Errors) > 0 {
+			if item.ID == c.calendarID {
+				return nil, fmt.Errorf("free/busy unavailable for calendar %s", item.ID)
+			}
+			result.Unavailable = append(result.Unavailable, item.ID)
+			continue
+		}
 
-		var attendees []string
-		for _, attendee := range calEvent.Attendees {
-			attendees = append(attendees, attendee.Email)
+		var busy []BusyPeriod
+		for _, period := range calendar.Busy {
+			start, err := time.Parse(time.RFC3339, period.Start)
+			if err != nil {
+				return nil, fmt.Errorf("invalid busy period start %q", period.Start)
+			}
+			end, err := time.Parse(time.RFC3339, period.End)
+			if err != nil {
+				return nil, fmt.Errorf("invalid busy period end %q", period.End)
+			}
+			busy = append(busy, BusyPeriod{Start: start, End: end})
 		}
 
-		events = append(events, Event{
-			Title:     calEvent.Summary,
-			StartTime: startTime,
-			EndTime:   endTime,
-			Attendees: attendees,
-		})
+		if item.ID == c.calendarID {
+			result.Calendar = busy
+		} else {
+			result.Attendees[item.ID] = busy
+		}
 	}
 
-	return events, nil
+	return result, nil
+}
+
+func (c *GoogleCalendarService) eventsURL(id string) string {
+	u := fmt.Sprintf("%s/calendars/%s/events", c.config.GoogleCalendarURL, url.PathEscape(c.calendarID))
+	if id != "" {
+		u += "/" + url.PathEscape(id)
+	}
+	return u
 }
 
-func (c *CalendarService) mockScheduleMeeting(attendees []string, startTime time.Time, duration time.Duration, title string) error {
-	fmt.Printf("Google Calendar API key not configured. Using mock implementation.\n")
-	fmt.Printf("Scheduling meeting:\nTitle: %s\nAttendees: %v\nStart: %s\nDuration: %v\n",
-		title, attendees, startTime.Format("2006-01-02 15:04:05"), duration)
+// do sends a JSON request to the Calendar API and decodes the response into
+// out when it is non-nil. A rejected access token is refreshed and the
+// request retried once. Missing events are reported as ErrEventNotFound.
+func (c *GoogleCalendarService) do(method, u string, in, out interface{}) error {
+	var jsonData []byte
+	if in != nil {
+		var err error
+		if jsonData, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("failed to marshal request: %v", err)
+		}
+	}
+
+	send := func() (*http.Response, error) {
+		token, err := c.auth.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get access token: %w", err)
+		}
+
+		var body io.Reader
+		if in != nil {
+			body = bytes.NewReader(jsonData)
+		}
+		req, err := http.NewRequest(method, u, body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %v", err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+		if in != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return c.client.Do(req)
+	}
+
+	resp, err := send()
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		c.auth.Invalidate()
+		if resp, err = send(); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return ErrEventNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("calendar API error: %d - %s", resp.StatusCode, string(respBody))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
 	return nil
 }
 
-func (c *CalendarService) mockGetUpcomingEvents() ([]Event, error) {
-	fmt.Printf("Google Calendar API key not configured. Returning mock events.\n")
+// calendarTimes converts the span of an event to the start and end sent to
+// Google. All-day events are sent as dates, with an exclusive end date.
+func (c *GoogleCalendarService) calendarTimes(start, end time.Time, allDay bool) (CalendarDateTime, CalendarDateTime) {
+	if allDay {
+		start, end = allDaySpan(start, end, c.config.Location())
+		return CalendarDateTime{Date: start.Format(dateLayout)}, CalendarDateTime{Date: end.Format(dateLayout)}
+	}
+	return CalendarDateTime{DateTime: start.Format(time.RFC3339), TimeZone: c.config.TimeZone},
+		CalendarDateTime{DateTime: end.Format(time.RFC3339), TimeZone: c.config.TimeZone}
+}
 
-	// Return some mock events
-	now := time.Now()
-	return []Event{
-		{
-			Title:     "Team Standup",
-			StartTime: now.Add(1 * time.Hour),
-			EndTime:   now.Add(1*time.Hour + 30*time.Minute),
-			Attendees: []string{"team@company.com"},
-		},
-		{
-			Title:     "Client Meeting",
-			StartTime: now.Add(3 * time.Hour),
-			EndTime:   now.Add(3*time.Hour + 1*time.Hour),
-			Attendees: []string{"client@company.com"},
-		},
+// parseTime converts the start or end of a Google event to the configured
+// time zone. Dates of all-day events become midnight in that zone.
+func (c *GoogleCalendarService) parseTime(t CalendarDateTime) (time.Time, error) {
+	loc := c.config.Location()
+	if t.Date != "" {
+		return time.ParseInLocation(dateLayout, t.Date, loc)
+	}
+	parsed, err := time.Parse(time.RFC3339, t.DateTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parsed.In(loc), nil
+}
+
+func (c *GoogleCalendarService) toEvent(calEvent CalendarEvent) (*Event, error) {
+	startTime, err := c.parseTime(calEvent.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start of event %s: %v", calEvent.ID, err)
+	}
+	endTime, err := c.parseTime(calEvent.End)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end of event %s: %v", calEvent.ID, err)
+	}
+
+	var attendees []string
+	for _, attendee := range calEvent.Attendees {
+		attendees = append(attendees, attendee.Email)
+	}
+
+	var recurrence string
+	for _, line := range calEvent.Recurrence {
+		if strings.HasPrefix(line, "RRULE:") {
+			recurrence = strings.TrimPrefix(line, "RRULE:")
+		}
+	}
+
+	return &Event{
+		ID:          calEvent.ID,
+		Title:       calEvent.Summary,
+		Description: calEvent.Description,
+		Location:    calEvent.Location,
+		Status:      calEvent.Status,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Attendees:   attendees,
+		AllDay:      calEvent.Start.Date != "",
+
+		Recurrence:       recurrence,
+		RecurringEventID: calEvent.RecurringEventID,
 	}, nil
 }
 
 type Event struct {
-	Title     string
-	StartTime time.Time
-	EndTime   time.Time
-	Attendees []string
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Attendees   []string  `json:"attendees,omitempty"`
+	// AllDay events span whole days: StartTime is midnight of the first day
+	// and EndTime midnight after the last one.
+	AllDay bool `json:"all_day,omitempty"`
+
+	// Recurrence is the RFC 5545 RRULE of a recurring event, without the
+	// "RRULE:" prefix. Single occurrences of a recurring event carry the ID
+	// of their series in RecurringEventID instead.
+	Recurrence       string `json:"recurrence,omitempty"`
+	RecurringEventID string `json:"recurring_event_id,omitempty"`
+
+	// Calendar names the configured calendar the event is on. When
+	// scheduling, it selects the calendar to book on.
+	Calendar string `json:"calendar,omitempty"`
+}
+
+// BusyPeriod is a span of time in which a calendar is booked.
+type BusyPeriod struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// FreeBusy holds the busy periods of the agent's own calendar and of the
+// attendees it was asked about.
+type FreeBusy struct {
+	Calendar  []BusyPeriod            `json:"calendar"`
+	Attendees map[string][]BusyPeriod `json:"attendees,omitempty"`
+	// Unavailable lists attendees whose calendars could not be read, for
+	// example because they are outside the organization.
+	Unavailable []string `json:"unavailable,omitempty"`
+}
+
+// EventUpdate lists the fields to change on an existing event; nil fields are
+// left as they are.
+type EventUpdate struct {
+	Title       *string    `json:"title,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	Location    *string    `json:"location,omitempty"`
+	Attendees   *[]string  `json:"attendees,omitempty"`
+	StartTime   *time.Time `json:"start_time,omitempty"`
+	EndTime     *time.Time `json:"end_time,omitempty"`
+	// Recurrence replaces the event's RRULE; an empty rule stops it
+	// repeating.
+	Recurrence *string `json:"recurrence,omitempty"`
+}
+
+// occurrenceLayout formats the start time in occurrence IDs, as Google does.
+const occurrenceLayout = "20060102T150405Z"
+
+// dateLayout formats the dates of all-day events.
+const dateLayout = "2006-01-02"
+
+// allDaySpan widens start and end to whole days in loc, returning the
+// midnight starting the first day and the one ending the last. The span
+// covers at least one day.
+func allDaySpan(start, end time.Time, loc *time.Location) (time.Time, time.Time) {
+	midnight := func(t time.Time) time.Time {
+		t = t.In(loc)
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+
+	first, last := midnight(start), midnight(end)
+	if last.Before(end) {
+		last = last.AddDate(0, 0, 1)
+	}
+	if !last.After(first) {
+		last = first.AddDate(0, 0, 1)
+	}
+	return first, last
+}
+
+// occurrence returns the unedited occurrence of series starting at start,
+// identified as "<series>_<start>".
+func occurrence(series Event, start time.Time) Event {
+	event := series
+	event.ID = series.ID + "_" + start.UTC().Format(occurrenceLayout)
+	event.RecurringEventID = series.ID
+	event.Recurrence = ""
+	event.StartTime = start
+	event.EndTime = start.Add(series.EndTime.Sub(series.StartTime))
+	event.Attendees = append([]string(nil), series.Attendees...)
+	return event
+}
+
+// splitOccurrenceID splits an occurrence ID into the ID of its series and
+// its original start time.
+func splitOccurrenceID(id string) (string, time.Time, bool) {
+	sep := strings.LastIndex(id, "_")
+	if sep < 0 {
+		return "", time.Time{}, false
+	}
+	start, err := time.Parse(occurrenceLayout, id[sep+1:])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return id[:sep], start, true
+}
+
+// rescheduleEvent moves an event to startTime. A zero duration keeps the
+// event's current length.
+func rescheduleEvent(c CalendarProvider, id string, startTime time.Time, duration time.Duration) (*Event, error) {
+	if duration <= 0 {
+		event, err := c.GetEvent(id)
+		if err != nil {
+			return nil, err
+		}
+		duration = event.EndTime.Sub(event.StartTime)
+	}
+
+	endTime := startTime.Add(duration)
+	return c.UpdateEvent(id, EventUpdate{StartTime: &startTime, EndTime: &endTime})
 }
@@ -0,0 +1,314 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/rrule"
+)
+
+// MockCalendarService is used when no calendar backend is configured. It keeps
+// events in memory so bookings can be listed, edited and cancelled. Its sample
+// events are anchored to when it was created so they keep a stable identity
+// across scheduler ticks.
+type MockCalendarService struct {
+	clock clock.Clock
+
+	mu     sync.Mutex
+	nextID int
+	// events holds single events, recurring series and edited occurrences
+	// of series, which replace their unedited occurrence.
+	events []Event
+	// cancelled holds the IDs of deleted occurrences of series.
+	cancelled map[string]bool
+}
+
+func NewMockCalendarService(clk clock.Clock) *MockCalendarService {
+	now := clk.Now()
+	c := newEmptyMockCalendar(clk)
+	c.insert(Event{
+		Title:     "Team Standup",
+		StartTime: now.Add(1 * time.Hour),
+		EndTime:   now.Add(1*time.Hour + 30*time.Minute),
+		Attendees: []string{"team@company.com"},
+	})
+	c.insert(Event{
+		Title:     "Client Meeting",
+		StartTime: now.Add(3 * time.Hour),
+		EndTime:   now.Add(3*time.Hour + 1*time.Hour),
+		Attendees: []string{"client@company.com"},
+	})
+	return c
+}
+
+// newEmptyMockCalendar returns a mock calendar without sample events, used for
+// additional calendars.
+func newEmptyMockCalendar(clk clock.Clock) *MockCalendarService {
+	return &MockCalendarService{clock: clk, cancelled: make(map[string]bool)}
+}
+
+func (c *MockCalendarService) ScheduleMeeting(meeting Event) (*Event, error) {
+	fmt.Printf("Google Calendar API key not configured. Using mock implementation.\n")
+	fmt.Printf("Scheduling meeting:\nTitle: %s\nAttendees: %v\nStart: %s\nDuration: %v\n",
+		meeting.Title, meeting.Attendees, meeting.StartTime.Format("2006-01-02 15:04:05"), meeting.EndTime.Sub(meeting.StartTime))
+
+	if meeting.Recurrence != "" {
+		if _, err := rrule.Parse(meeting.Recurrence); err != nil {
+			return nil, fmt.Errorf("invalid recurrence: %v", err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	meeting.Attendees = append([]string(nil), meeting.Attendees...)
+	meeting.RecurringEventID = ""
+	if meeting.AllDay {
+		meeting.StartTime, meeting.EndTime = allDaySpan(meeting.StartTime, meeting.EndTime, meeting.StartTime.Location())
+	}
+	event := c.insert(meeting)
+	return &event, nil
+}
+
+func (c *MockCalendarService) GetUpcomingEvents() ([]Event, error) {
+	fmt.Printf("Google Calendar API key not configured. Returning mock events.\n")
+
+	now := c.clock.Now()
+	return c.ListEvents(now, now.AddDate(0, 0, 7))
+}
+
+// ListEvents returns the events overlapping from and to, as Google does, with
+// recurring events expanded into single occurrences.
+func (c *MockCalendarService) ListEvents(from, to time.Time) ([]Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var events []Event
+	for _, event := range c.expand(from, to) {
+		if event.StartTime.Before(to) && event.EndTime.After(from) {
+			events = append(events, event)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].StartTime.Before(events[j].StartTime)
+	})
+	return events, nil
+}
+
+func (c *MockCalendarService) GetEvent(id string) (*Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	event, ok := c.lookup(id)
+	if !ok {
+		return nil, ErrEventNotFound
+	}
+	return &event, nil
+}
+
+// UpdateEvent edits a single event, a whole series or, when id names one
+// occurrence of a series, just that occurrence.
+func (c *MockCalendarService) UpdateEvent(id string, update EventUpdate) (*Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	event, ok := c.lookup(id)
+	if !ok {
+		return nil, ErrEventNotFound
+	}
+
+	if update.Title != nil {
+		event.Title = *update.Title
+	}
+	if update.Description != nil {
+		event.Description = *update.Description
+	}
+	if update.Location != nil {
+		event.Location = *update.Location
+	}
+	if update.Attendees != nil {
+		event.Attendees = append([]string(nil), (*update.Attendees)...)
+	}
+	if update.StartTime != nil {
+		event.StartTime = *update.StartTime
+	}
+	if update.EndTime != nil {
+		event.EndTime = *update.EndTime
+	}
+	if update.Recurrence != nil {
+		if event.RecurringEventID != "" {
+			return nil, fmt.Errorf("cannot change the recurrence of a single occurrence")
+		}
+		if *update.Recurrence != "" {
+			if _, err := rrule.Parse(*update.Recurrence); err != nil {
+				return nil, fmt.Errorf("invalid recurrence: %v", err)
+			}
+		}
+		event.Recurrence = *update.Recurrence
+	}
+	if event.EndTime.Before(event.StartTime) {
+		return nil, fmt.Errorf("event cannot end before it starts")
+	}
+	if event.AllDay {
+		event.StartTime, event.EndTime = allDaySpan(event.StartTime, event.EndTime, event.StartTime.Location())
+	}
+
+	// Edited occurrences are stored as exceptions to their series
+	if i := c.find(id); i >= 0 {
+		c.events[i] = event
+	} else {
+		c.events = append(c.events, event)
+	}
+	return &event, nil
+}
+
+// DeleteEvent deletes an event, a whole series with its edited occurrences
+// or a single occurrence of a series.
+func (c *MockCalendarService) DeleteEvent(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	event, ok := c.lookup(id)
+	if !ok {
+		return ErrEventNotFound
+	}
+
+	if event.RecurringEventID != "" {
+		c.cancelled[id] = true
+	}
+
+	var kept []Event
+	for _, e := range c.events {
+		if e.ID != id && e.RecurringEventID != id {
+			kept = append(kept, e)
+		}
+	}
+	c.events = kept
+	return nil
+}
+
+func (c *MockCalendarService) RescheduleEvent(id string, startTime time.Time, duration time.Duration) (*Event, error) {
+	return rescheduleEvent(c, id, startTime, duration)
+}
+
+// FreeBusy treats every stored event, and every occurrence of a recurring
+// one, as busy time on the agent's calendar and on the calendars of its
+// attendees.
+func (c *MockCalendarService) FreeBusy(attendees []string, from, to time.Time) (*FreeBusy, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := &FreeBusy{Attendees: make(map[string][]BusyPeriod)}
+	for _, email := range attendees {
+		result.Attendees[email] = nil
+	}
+
+	for _, event := range c.expand(from, to) {
+		if !event.StartTime.Before(to) || !event.EndTime.After(from) {
+			continue
+		}
+		// All-day events such as holidays or birthdays are shown as free,
+		// as Google Calendar does by default
+		if event.AllDay {
+			continue
+		}
+
+		period := BusyPeriod{Start: event.StartTime, End: event.EndTime}
+		result.Calendar = append(result.Calendar, period)
+		for _, email := range attendees {
+			for _, attendee := range event.Attendees {
+				if strings.EqualFold(attendee, email) {
+					result.Attendees[email] = append(result.Attendees[email], period)
+					break
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// expand returns the single events and the occurrences of recurring events
+// that may overlap from and to, unsorted. Callers hold c.mu.
+func (c *MockCalendarService) expand(from, to time.Time) []Event {
+	var events []Event
+	for _, event := range c.events {
+		if event.Recurrence == "" {
+			events = append(events, event)
+			continue
+		}
+
+		rule, err := rrule.Parse(event.Recurrence)
+		if err != nil {
+			continue
+		}
+
+		length := event.EndTime.Sub(event.StartTime)
+		for _, start := range rule.Occurrences(event.StartTime, from.Add(-length), to.Add(time.Second)) {
+			occurrence := occurrence(event, start)
+			if c.cancelled[occurrence.ID] || c.find(occurrence.ID) >= 0 {
+				continue
+			}
+			events = append(events, occurrence)
+		}
+	}
+	return events
+}
+
+// lookup returns the stored event with the given ID or, for IDs of the form
+// "<series>_<start>", the unedited occurrence of a series. Callers hold
+// c.mu.
+func (c *MockCalendarService) lookup(id string) (Event, bool) {
+	if i := c.find(id); i >= 0 {
+		return c.events[i], true
+	}
+	if c.cancelled[id] {
+		return Event{}, false
+	}
+
+	seriesID, start, ok := splitOccurrenceID(id)
+	if !ok {
+		return Event{}, false
+	}
+	i := c.find(seriesID)
+	if i < 0 || c.events[i].Recurrence == "" {
+		return Event{}, false
+	}
+
+	series := c.events[i]
+	rule, err := rrule.Parse(series.Recurrence)
+	if err != nil {
+		return Event{}, false
+	}
+	for _, t := range rule.Occurrences(series.StartTime, start, start.Add(time.Second)) {
+		if t.Equal(start) {
+			return occurrence(series, t), true
+		}
+	}
+	return Event{}, false
+}
+
+// insert assigns event an ID and stores it. Callers hold c.mu or own c.
+func (c *MockCalendarService) insert(event Event) Event {
+	c.nextID++
+	event.ID = fmt.Sprintf("mock-%d", c.nextID)
+	event.Status = "confirmed"
+	c.events = append(c.events, event)
+	return event
+}
+
+// find returns the index of the event with the given ID, or -1. Callers hold
+// c.mu.
+func (c *MockCalendarService) find(id string) int {
+	for i, event := range c.events {
+		if event.ID == id {
+			return i
+		}
+	}
+	return -1
+}
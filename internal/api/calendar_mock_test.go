@@ -0,0 +1,42 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+)
+
+func TestMockCalendarListsOverlappingEvents(t *testing.T) {
+	from := time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC)
+	to := from.Add(8 * time.Hour)
+	cal := newEmptyMockCalendar(clock.NewFake(from))
+
+	for _, event := range []Event{
+		{Title: "Ended", StartTime: from.Add(-time.Hour), EndTime: from},
+		{Title: "In progress", StartTime: from.Add(-30 * time.Minute), EndTime: from.Add(30 * time.Minute)},
+		{Title: "At from", StartTime: from, EndTime: from.Add(time.Hour)},
+		{Title: "At to", StartTime: to, EndTime: to.Add(time.Hour)},
+		{Title: "Daily", StartTime: from.AddDate(0, 0, -3).Add(-time.Hour), EndTime: from.AddDate(0, 0, -3).Add(time.Hour), Recurrence: "FREQ=DAILY"},
+	} {
+		if _, err := cal.ScheduleMeeting(event); err != nil {
+			t.Fatalf("ScheduleMeeting %s failed: %v", event.Title, err)
+		}
+	}
+
+	events, err := cal.ListEvents(from, to)
+	if err != nil {
+		t.Fatalf("ListEvents failed: %v", err)
+	}
+	got := eventTitles(events)
+	want := []string{"Daily", "In progress", "At from"}
+	if len(got) != len(want) {
+		t.Fatalf("listed %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("listed %q, want %q", got, want)
+			break
+		}
+	}
+}
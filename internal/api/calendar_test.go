@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/config"
+)
+
+// rotatingTokenSource hands out a new access token after each Invalidate.
+type rotatingTokenSource struct {
+	tokens []string
+}
+
+func (s *rotatingTokenSource) Token() (string, error) { return s.tokens[0], nil }
+
+func (s *rotatingTokenSource) Invalidate() { s.tokens = s.tokens[1:] }
+
+func TestGoogleCalendarRefreshesRejectedToken(t *testing.T) {
+	var authorizations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(CalendarEvent{
+			ID:      "evt-1",
+			Summary: "Planning",
+			Start:   CalendarDateTime{DateTime: "2026-10-14T10:00:00Z"},
+			End:     CalendarDateTime{DateTime: "2026-10-14T11:00:00Z"},
+		})
+	}))
+	defer server.Close()
+
+	auth := &rotatingTokenSource{tokens: []string{"expired", "fresh"}}
+	cal := NewGoogleCalendarService(&config.Config{GoogleCalendarURL: server.URL}, clock.NewFake(time.Now()), auth, "primary")
+
+	event, err := cal.GetEvent("evt-1")
+	if err != nil {
+		t.Fatalf("GetEvent failed: %v", err)
+	}
+	if event.Title != "Planning" {
+		t.Errorf("got event %q, want Planning", event.Title)
+	}
+	if len(authorizations) != 2 || authorizations[1] != "Bearer fresh" {
+		t.Errorf("got authorizations %v, want a retry with the fresh token", authorizations)
+	}
+
+	// A token that is rejected again is reported rather than retried forever
+	auth.tokens = []string{"revoked", "revoked"}
+	if _, err := cal.GetEvent("evt-1"); err == nil {
+		t.Error("GetEvent with a revoked token succeeded")
+	}
+}
+
+func TestGoogleCalendarAllDayEvents(t *testing.T) {
+	var created CalendarEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			json.NewDecoder(r.Body).Decode(&created)
+			created.ID = "evt-3"
+			json.NewEncoder(w).Encode(created)
+			return
+		}
+		json.NewEncoder(w).Encode(CalendarEventsResponse{Items: []CalendarEvent{
+			{
+				ID:      "evt-1",
+				Summary: "Offsite",
+				Start:   CalendarDateTime{Date: "2026-10-15"},
+				End:     CalendarDateTime{Date: "2026-10-17"},
+			},
+			{
+				ID:      "evt-2",
+				Summary: "Berlin sync",
+				Start:   CalendarDateTime{DateTime: "2026-10-15T16:00:00+02:00", TimeZone: "Europe/Berlin"},
+				End:     CalendarDateTime{DateTime: "2026-10-15T17:00:00+02:00", TimeZone: "Europe/Berlin"},
+			},
+		}})
+	}))
+	defer server.Close()
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	cfg := &config.Config{GoogleCalendarURL: server.URL, TimeZone: "America/New_York"}
+	cal := NewGoogleCalendarService(cfg, clock.NewFake(time.Date(2026, time.October, 14, 9, 0, 0, 0, loc)), &rotatingTokenSource{tokens: []string{"token"}}, "primary")
+
+	events, err := cal.GetUpcomingEvents()
+	if err != nil {
+		t.Fatalf("GetUpcomingEvents failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+
+	// Dates are midnights in the configured zone, with an exclusive end
+	offsite := events[0]
+	if !offsite.AllDay || !offsite.StartTime.Equal(time.Date(2026, time.October, 15, 0, 0, 0, 0, loc)) ||
+		!offsite.EndTime.Equal(time.Date(2026, time.October, 17, 0, 0, 0, 0, loc)) {
+		t.Errorf("got offsite %+v", offsite)
+	}
+	// Timed events are converted to the configured zone
+	sync := events[1]
+	if sync.AllDay || sync.StartTime.Location().String() != "America/New_York" || sync.StartTime.Hour() != 10 {
+		t.Errorf("got Berlin sync at %s, want 10:00 New York time", sync.StartTime)
+	}
+
+	// All-day bookings are sent as dates
+	start := time.Date(2026, time.October, 20, 14, 0, 0, 0, loc)
+	if _, err := cal.ScheduleMeeting(Event{Title: "Holiday", StartTime: start, EndTime: start.Add(time.Hour), AllDay: true}); err != nil {
+		t.Fatalf("ScheduleMeeting failed: %v", err)
+	}
+	if created.Start.Date != "2026-10-20" || created.End.Date != "2026-10-21" || created.Start.DateTime != "" {
+		t.Errorf("sent start %+v and end %+v, want dates", created.Start, created.End)
+	}
+}
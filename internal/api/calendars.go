@@ -0,0 +1,217 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/config"
+)
+
+// ErrUnknownCalendar is returned when a task or request names a calendar
+// that isn't configured.
+var ErrUnknownCalendar = errors.New("unknown calendar")
+
+// ErrReadOnlyCalendar is returned when booking on or changing a calendar that
+// is only read for availability.
+var ErrReadOnlyCalendar = errors.New("calendar is read-only")
+
+// MultiCalendar combines the configured calendars into one provider. Reads
+// merge every calendar, while bookings go to the calendar an event names or
+// the default one. Events of calendars other than the default have their
+// IDs prefixed with the calendar name, as in "personal:abc123", so that
+// later changes reach the right calendar.
+type MultiCalendar struct {
+	calendars []calendarEntry
+}
+
+type calendarEntry struct {
+	config.Calendar
+	provider CalendarProvider
+}
+
+// NewMultiCalendar combines providers, given in the order of calendars.
+func NewMultiCalendar(calendars []config.Calendar, providers []CalendarProvider) *MultiCalendar {
+	m := &MultiCalendar{}
+	for i, calendar := range calendars {
+		m.calendars = append(m.calendars, calendarEntry{Calendar: calendar, provider: providers[i]})
+	}
+	return m
+}
+
+func (m *MultiCalendar) ScheduleMeeting(meeting Event) (*Event, error) {
+	entry, err := m.byName(meeting.Calendar)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Role == config.RoleRead {
+		return nil, fmt.Errorf("cannot book on %s: %w", entry.Name, ErrReadOnlyCalendar)
+	}
+
+	meeting.Calendar = ""
+	event, err := entry.provider.ScheduleMeeting(meeting)
+	if err != nil {
+		return nil, err
+	}
+	return entry.qualify(event), nil
+}
+
+func (m *MultiCalendar) GetUpcomingEvents() ([]Event, error) {
+	return m.merge(func(provider CalendarProvider) ([]Event, error) {
+		return provider.GetUpcomingEvents()
+	})
+}
+
+func (m *MultiCalendar) ListEvents(from, to time.Time) ([]Event, error) {
+	return m.merge(func(provider CalendarProvider) ([]Event, error) {
+		return provider.ListEvents(from, to)
+	})
+}
+
+func (m *MultiCalendar) GetEvent(id string) (*Event, error) {
+	entry, id := m.route(id)
+	event, err := entry.provider.GetEvent(id)
+	if err != nil {
+		return nil, err
+	}
+	return entry.qualify(event), nil
+}
+
+func (m *MultiCalendar) UpdateEvent(id string, update EventUpdate) (*Event, error) {
+	entry, id, err := m.writable(id)
+	if err != nil {
+		return nil, err
+	}
+	event, err := entry.provider.UpdateEvent(id, update)
+	if err != nil {
+		return nil, err
+	}
+	return entry.qualify(event), nil
+}
+
+func (m *MultiCalendar) DeleteEvent(id string) error {
+	entry, id, err := m.writable(id)
+	if err != nil {
+		return err
+	}
+	return entry.provider.DeleteEvent(id)
+}
+
+func (m *MultiCalendar) RescheduleEvent(id string, startTime time.Time, duration time.Duration) (*Event, error) {
+	entry, id, err := m.writable(id)
+	if err != nil {
+		return nil, err
+	}
+	event, err := entry.provider.RescheduleEvent(id, startTime, duration)
+	if err != nil {
+		return nil, err
+	}
+	return entry.qualify(event), nil
+}
+
+// FreeBusy reports the busy time of every calendar, read-only ones included,
+// as the agent's own. Attendees are looked up through the default calendar.
+func (m *MultiCalendar) FreeBusy(attendees []string, from, to time.Time) (*FreeBusy, error) {
+	def := m.defaultEntry()
+	result, err := def.provider.FreeBusy(attendees, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range m.calendars {
+		if entry.Name == def.Name {
+			continue
+		}
+		busy, err := entry.provider.FreeBusy(nil, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read calendar %s: %w", entry.Name, err)
+		}
+		result.Calendar = append(result.Calendar, busy.Calendar...)
+	}
+
+	sort.SliceStable(result.Calendar, func(i, j int) bool {
+		return result.Calendar[i].Start.Before(result.Calendar[j].Start)
+	})
+	return result, nil
+}
+
+// merge lists the events of every calendar, ordered by start time.
+func (m *MultiCalendar) merge(list func(CalendarProvider) ([]Event, error)) ([]Event, error) {
+	merged := []Event{}
+	for _, entry := range m.calendars {
+		events, err := list(entry.provider)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read calendar %s: %w", entry.Name, err)
+		}
+		for i := range events {
+			merged = append(merged, *entry.qualify(&events[i]))
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].StartTime.Before(merged[j].StartTime)
+	})
+	return merged, nil
+}
+
+// byName returns the calendar with the given name, or the default calendar
+// when name is empty.
+func (m *MultiCalendar) byName(name string) (*calendarEntry, error) {
+	if name == "" {
+		return m.defaultEntry(), nil
+	}
+	for i := range m.calendars {
+		if strings.EqualFold(m.calendars[i].Name, name) {
+			return &m.calendars[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownCalendar, name)
+}
+
+func (m *MultiCalendar) defaultEntry() *calendarEntry {
+	for i := range m.calendars {
+		if m.calendars[i].Role == config.RoleDefault {
+			return &m.calendars[i]
+		}
+	}
+	return &m.calendars[0]
+}
+
+// route returns the calendar an event ID belongs to and the ID its provider
+// knows the event by.
+func (m *MultiCalendar) route(id string) (*calendarEntry, string) {
+	if i := strings.Index(id, ":"); i > 0 {
+		for j := range m.calendars {
+			entry := &m.calendars[j]
+			if entry.Role != config.RoleDefault && entry.Name == id[:i] {
+				return entry, id[i+1:]
+			}
+		}
+	}
+	return m.defaultEntry(), id
+}
+
+// writable routes id like route, refusing events of read-only calendars.
+func (m *MultiCalendar) writable(id string) (*calendarEntry, string, error) {
+	entry, id := m.route(id)
+	if entry.Role == config.RoleRead {
+		return nil, "", fmt.Errorf("cannot change events of %s: %w", entry.Name, ErrReadOnlyCalendar)
+	}
+	return entry, id, nil
+}
+
+// qualify labels event with its calendar and prefixes its IDs unless it is
+// on the default calendar.
+func (e *calendarEntry) qualify(event *Event) *Event {
+	qualified := *event
+	qualified.Calendar = e.Name
+	if e.Role != config.RoleDefault {
+		qualified.ID = e.Name + ":" + event.ID
+		if event.RecurringEventID != "" {
+			qualified.RecurringEventID = e.Name + ":" + event.RecurringEventID
+		}
+	}
+	return &qualified
+}
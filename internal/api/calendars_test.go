@@ -0,0 +1,97 @@
+package api
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/config"
+)
+
+func TestMultiCalendar(t *testing.T) {
+	now := time.Date(2026, time.October, 14, 8, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+	work, personal, holidays := newEmptyMockCalendar(clk), newEmptyMockCalendar(clk), newEmptyMockCalendar(clk)
+	cal := NewMultiCalendar([]config.Calendar{
+		{Name: "work", ID: "primary", Role: config.RoleDefault},
+		{Name: "personal", ID: "me@example.com", Role: config.RoleWrite},
+		{Name: "holidays", ID: "holidays@example.com", Role: config.RoleRead},
+	}, []CalendarProvider{work, personal, holidays})
+
+	at := func(hour int) Event {
+		start := now.Add(time.Duration(hour) * time.Hour)
+		return Event{Title: "Event", StartTime: start, EndTime: start.Add(time.Hour)}
+	}
+	if _, err := holidays.ScheduleMeeting(at(1)); err != nil {
+		t.Fatalf("failed to seed holidays: %v", err)
+	}
+
+	booked, err := cal.ScheduleMeeting(at(3))
+	if err != nil {
+		t.Fatalf("ScheduleMeeting failed: %v", err)
+	}
+	if booked.ID != "mock-1" || booked.Calendar != "work" {
+		t.Errorf("default booking = %q on %q, want mock-1 on work", booked.ID, booked.Calendar)
+	}
+
+	dentist := at(2)
+	dentist.Calendar = "Personal"
+	own, err := cal.ScheduleMeeting(dentist)
+	if err != nil {
+		t.Fatalf("ScheduleMeeting on personal failed: %v", err)
+	}
+	if own.ID != "personal:mock-1" || own.Calendar != "personal" {
+		t.Errorf("personal booking = %q on %q, want personal:mock-1", own.ID, own.Calendar)
+	}
+
+	for name, want := range map[string]error{"holidays": ErrReadOnlyCalendar, "gym": ErrUnknownCalendar} {
+		event := at(4)
+		event.Calendar = name
+		if _, err := cal.ScheduleMeeting(event); !errors.Is(err, want) {
+			t.Errorf("booking on %s = %v, want %v", name, err, want)
+		}
+	}
+
+	events, err := cal.ListEvents(now, now.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("ListEvents failed: %v", err)
+	}
+	var got []string
+	for _, event := range events {
+		got = append(got, event.Calendar+" "+event.ID)
+	}
+	want := []string{"holidays holidays:mock-1", "personal personal:mock-1", "work mock-1"}
+	if len(got) != len(want) {
+		t.Fatalf("merged events = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("merged event %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	// Prefixed IDs reach their own calendar
+	moved, err := cal.RescheduleEvent("personal:mock-1", now.Add(5*time.Hour), 0)
+	if err != nil {
+		t.Fatalf("RescheduleEvent failed: %v", err)
+	}
+	if moved.ID != "personal:mock-1" || !moved.StartTime.Equal(now.Add(5*time.Hour)) {
+		t.Errorf("rescheduled %q to %s", moved.ID, moved.StartTime)
+	}
+	if event, err := work.GetEvent("mock-1"); err != nil || !event.StartTime.Equal(now.Add(3*time.Hour)) {
+		t.Errorf("work event changed by personal reschedule: %v, %v", event, err)
+	}
+	if err := cal.DeleteEvent("holidays:mock-1"); !errors.Is(err, ErrReadOnlyCalendar) {
+		t.Errorf("deleting a holiday = %v, want ErrReadOnlyCalendar", err)
+	}
+
+	// Every calendar counts as busy time
+	busy, err := cal.FreeBusy(nil, now, now.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("FreeBusy failed: %v", err)
+	}
+	if len(busy.Calendar) != 3 || !busy.Calendar[0].Start.Equal(now.Add(time.Hour)) || !busy.Calendar[2].Start.Equal(now.Add(5*time.Hour)) {
+		t.Errorf("got busy periods %v", busy.Calendar)
+	}
+}
@@ -2,16 +2,19 @@ package api
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/mail"
+	"strings"
 	"time"
 
 	"github.com/azme12/ai-agent-project/internal/config"
 )
 
-type EmailService struct {
+type SendGridEmailService struct {
 	config *config.Config
 	client *http.Client
 }
@@ -19,12 +22,19 @@ type EmailService struct {
 type SendGridEmail struct {
 	Personalizations []SendGridPersonalization `json:"personalizations"`
 	From             SendGridFrom              `json:"from"`
+	ReplyTo          *SendGridTo               `json:"reply_to,omitempty"`
 	Subject          string                    `json:"subject"`
 	Content          []SendGridContent         `json:"content"`
+	Attachments      []SendGridAttachment      `json:"attachments,omitempty"`
+	Headers          map[string]string         `json:"headers,omitempty"`
 }
 
+// SendGridPersonalization addresses one copy of an email. SendGrid rejects
+// an address that appears twice within a personalization.
 type SendGridPersonalization struct {
-	To []SendGridTo `json:"to"`
+	To  []SendGridTo `json:"to"`
+	CC  []SendGridTo `json:"cc,omitempty"`
+	BCC []SendGridTo `json:"bcc,omitempty"`
 }
 
 type SendGridTo struct {
@@ -42,41 +52,79 @@ type SendGridContent struct {
 	Value string `json:"value"`
 }
 
-func NewSendGridEmailService(cfg *config.Config) *EmailService {
-	return &EmailService{
+type SendGridAttachment struct {
+	Content     string `json:"content"`
+	Type        string `json:"type,omitempty"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition,omitempty"`
+}
+
+func NewSendGridEmailService(cfg *config.Config) *SendGridEmailService {
+	return &SendGridEmailService{
 		config: cfg,
 		client: &http.Client{Timeout: 30 * time.Second},
 	}
 }
 
-func (e *EmailService) SendEmail(to, subject, body string) error {
-	if e.config.SendGridAPIKey == "" {
-		return e.mockSendEmail(to, subject, body)
+func (e *SendGridEmailService) Send(message Message) error {
+	url := fmt.Sprintf("%s/mail/send", e.config.SendGridURL)
+
+	r, err := message.validate()
+	if err != nil {
+		return err
+	}
+	if len(r.to) == 0 {
+		return fmt.Errorf("%w: sendgrid requires at least one To recipient", ErrRejected)
 	}
 
-	url := fmt.Sprintf("%s/mail/send", e.config.SendGridURL)
+	// Each address is sent to once, in the most visible field it appears in
+	seen := make(map[string]bool)
+	personalization := SendGridPersonalization{}
+	for _, list := range []struct {
+		addresses []*mail.Address
+		field     *[]SendGridTo
+	}{
+		{r.to, &personalization.To},
+		{r.cc, &personalization.CC},
+		{r.bcc, &personalization.BCC},
+	} {
+		for _, address := range list.addresses {
+			key := strings.ToLower(address.Address)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			*list.field = append(*list.field, SendGridTo{Email: address.Address, Name: address.Name})
+		}
+	}
 
 	emailData := SendGridEmail{
-		Personalizations: []SendGridPersonalization{
-			{
-				To: []SendGridTo{
-					{
-						Email: to,
-					},
-				},
-			},
-		},
+		Personalizations: []SendGridPersonalization{personalization},
 		From: SendGridFrom{
 			Email: e.config.FromEmail,
 			Name:  e.config.FromName,
 		},
-		Subject: subject,
-		Content: []SendGridContent{
-			{
-				Type:  "text/plain",
-				Value: body,
-			},
-		},
+		Subject: message.Subject,
+		Headers: message.Headers,
+	}
+	if r.replyTo != nil {
+		emailData.ReplyTo = &SendGridTo{Email: r.replyTo.Address, Name: r.replyTo.Name}
+	}
+	// SendGrid requires text/plain to come before text/html
+	if message.Text != "" {
+		emailData.Content = append(emailData.Content, SendGridContent{Type: "text/plain", Value: message.Text})
+	}
+	if message.HTML != "" {
+		emailData.Content = append(emailData.Content, SendGridContent{Type: "text/html", Value: message.HTML})
+	}
+
+	for _, attachment := range message.Attachments {
+		emailData.Attachments = append(emailData.Attachments, SendGridAttachment{
+			Content:     base64.StdEncoding.EncodeToString(attachment.Content),
+			Type:        attachment.ContentType,
+			Filename:    attachment.Filename,
+			Disposition: "attachment",
+		})
 	}
 
 	jsonData, err := json.Marshal(emailData)
@@ -100,15 +148,24 @@ func (e *EmailService) SendEmail(to, subject, body string) error {
 
 	if resp.StatusCode != http.StatusAccepted {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("sendgrid API error: %d - %s", resp.StatusCode, string(body))
+		err := fmt.Errorf("sendgrid API error: %d - %s", resp.StatusCode, string(body))
+		if rejected(resp.StatusCode) {
+			return fmt.Errorf("%w: %v", ErrRejected, err)
+		}
+		return err
 	}
 
-	fmt.Printf("Successfully sent email to: %s\nSubject: %s\nBody: %s\n", to, subject, body)
+	fmt.Printf("Successfully sent email to: %s\nSubject: %s\n", strings.Join(r.all(), ", "), message.Subject)
 	return nil
 }
 
-func (e *EmailService) mockSendEmail(to, subject, body string) error {
-	fmt.Printf("SendGrid API key not configured. Using mock implementation.\n")
-	fmt.Printf("Sending email to: %s\nSubject: %s\nBody: %s\n", to, subject, body)
-	return nil
+// rejected reports whether a SendGrid response status refuses the message
+// itself. Throttling, timeouts and credential errors may clear up, as may
+// server errors.
+func rejected(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return status >= 400 && status < 500
 }
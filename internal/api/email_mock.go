@@ -0,0 +1,34 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MockEmailService prints outgoing emails instead of sending them.
+type MockEmailService struct{}
+
+func NewMockEmailService() *MockEmailService {
+	return &MockEmailService{}
+}
+
+func (e *MockEmailService) Send(message Message) error {
+	r, err := message.validate()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Email provider not configured. Using mock implementation.\n")
+	fmt.Printf("Sending email to: %s\n", strings.Join(message.To, ", "))
+	if len(r.cc) > 0 {
+		fmt.Printf("Cc: %s\n", strings.Join(message.CC, ", "))
+	}
+	if len(r.bcc) > 0 {
+		fmt.Printf("Bcc: %s\n", strings.Join(message.BCC, ", "))
+	}
+	fmt.Printf("Subject: %s\nBody: %s\n", message.Subject, message.Text)
+	for _, attachment := range message.Attachments {
+		fmt.Printf("Attachment: %s (%s, %d bytes)\n", attachment.Filename, attachment.ContentType, len(attachment.Content))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/azme12/ai-agent-project/internal/config"
+)
+
+func TestSendGridEmailService(t *testing.T) {
+	var sent SendGridEmail
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sender := NewSendGridEmailService(&config.Config{SendGridURL: server.URL, SendGridAPIKey: "key", FromEmail: "agent@example.com"})
+	err := sender.Send(Message{
+		To:      []string{"Bob <bob@example.com>", "carol@example.com"},
+		CC:      []string{"dave@example.com", "BOB@example.com"},
+		BCC:     []string{"erin@example.com"},
+		ReplyTo: "alice@example.com",
+		Subject: "Planning",
+		Text:    "See the agenda.",
+		HTML:    "<p>See the <b>agenda</b>.</p>",
+		Headers: map[string]string{"In-Reply-To": "<abc@example.com>"},
+	})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if len(sent.Personalizations) != 1 {
+		t.Fatalf("got %d personalizations, want 1", len(sent.Personalizations))
+	}
+	p := sent.Personalizations[0]
+	// Bob is dropped from CC, as SendGrid rejects repeated addresses
+	if len(p.To) != 2 || p.To[0].Name != "Bob" || len(p.CC) != 1 || p.CC[0].Email != "dave@example.com" || len(p.BCC) != 1 {
+		t.Errorf("got personalization %+v", p)
+	}
+	if sent.ReplyTo == nil || sent.ReplyTo.Email != "alice@example.com" || sent.Headers["In-Reply-To"] != "<abc@example.com>" {
+		t.Errorf("got reply-to %+v and headers %v", sent.ReplyTo, sent.Headers)
+	}
+	if len(sent.Content) != 2 || sent.Content[0].Type != "text/plain" || sent.Content[1].Type != "text/html" {
+		t.Errorf("got content %+v, want text before HTML", sent.Content)
+	}
+
+	if err := sender.Send(Message{BCC: []string{"erin@example.com"}, Text: "Hi"}); err == nil {
+		t.Error("Send without a To recipient succeeded")
+	}
+}
+
+func TestSendGridRejections(t *testing.T) {
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sender := NewSendGridEmailService(&config.Config{SendGridURL: server.URL, SendGridAPIKey: "key", FromEmail: "agent@example.com"})
+	message := Message{To: []string{"bob@example.com"}, Subject: "Hi", Text: "Hello"}
+
+	// Only errors in the message itself are rejections; the rest may pass
+	tests := map[int]bool{
+		http.StatusBadRequest:            true,
+		http.StatusRequestEntityTooLarge: true,
+		http.StatusUnauthorized:          false,
+		http.StatusTooManyRequests:       false,
+		http.StatusServiceUnavailable:    false,
+	}
+	for code, want := range tests {
+		status = code
+		err := sender.Send(message)
+		if err == nil {
+			t.Fatalf("%d: Send succeeded", code)
+		}
+		if got := errors.Is(err, ErrRejected); got != want {
+			t.Errorf("%d: rejected = %v, want %v (%v)", code, got, want, err)
+		}
+	}
+}
@@ -18,7 +18,23 @@ type GeminiService struct {
 }
 
 type GeminiRequest struct {
-	Contents []GeminiContent `json:"contents"`
+	Contents         []GeminiContent         `json:"contents"`
+	GenerationConfig *GeminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type GeminiGenerationConfig struct {
+	ResponseMimeType string        `json:"responseMimeType,omitempty"`
+	ResponseSchema   *GeminiSchema `json:"responseSchema,omitempty"`
+}
+
+// GeminiSchema is the OpenAPI subset Gemini accepts for constrained output.
+type GeminiSchema struct {
+	Type        string                   `json:"type"`
+	Description string                   `json:"description,omitempty"`
+	Enum        []string                 `json:"enum,omitempty"`
+	Properties  map[string]*GeminiSchema `json:"properties,omitempty"`
+	Items       *GeminiSchema            `json:"items,omitempty"`
+	Required    []string                 `json:"required,omitempty"`
 }
 
 type GeminiContent struct {
@@ -37,6 +53,35 @@ type GeminiCandidate struct {
 	Content GeminiContent `json:"content"`
 }
 
+// intentSchema constrains ExtractIntent responses to the Intent shape.
+var intentSchema = &GeminiSchema{
+	Type: "OBJECT",
+	Properties: map[string]*GeminiSchema{
+		"type": {
+			Type:        "STRING",
+			Enum:        IntentTypes,
+			Description: "The action the user is asking for.",
+		},
+		"title":            {Type: "STRING", Description: "Meeting or reminder title."},
+		"attendees":        {Type: "ARRAY", Items: &GeminiSchema{Type: "STRING"}, Description: "Attendee email addresses."},
+		"start_time":       {Type: "STRING", Description: "Start time in RFC 3339 format including the UTC offset. For reschedule, the new start time."},
+		"duration_minutes": {Type: "INTEGER", Description: "Meeting duration in minutes."},
+		"to":               {Type: "ARRAY", Items: &GeminiSchema{Type: "STRING"}, Description: "Recipient email addresses."},
+		"cc":               {Type: "ARRAY", Items: &GeminiSchema{Type: "STRING"}, Description: "For email, addresses to copy."},
+		"bcc":              {Type: "ARRAY", Items: &GeminiSchema{Type: "STRING"}, Description: "For email, addresses to blind copy."},
+		"subject":          {Type: "STRING", Description: "Email subject."},
+		"body":             {Type: "STRING", Description: "Email body."},
+		"response":         {Type: "STRING", Description: "A short confirmation for the user describing what will be done."},
+		"event_query":      {Type: "STRING", Description: "For reschedule and cancel, the words identifying the existing event, such as \"3pm with Sarah\"."},
+		"keep_time_of_day": {Type: "BOOLEAN", Description: "For reschedule, true when only a new day is given and the event keeps its time of day."},
+		"end_time":         {Type: "STRING", Description: "For suggest, the end of the window to search in RFC 3339 format."},
+		"shift_minutes":    {Type: "INTEGER", Description: "For reschedule, minutes to move the event by when no new time is given; negative moves it earlier."},
+		"recurrence":       {Type: "STRING", Description: "For schedule, an RFC 5545 RRULE without the \"RRULE:\" prefix when the meeting repeats, such as \"FREQ=WEEKLY;BYDAY=MO\". Supports DAILY, WEEKLY and MONTHLY with INTERVAL, BYDAY, BYMONTHDAY, COUNT and UNTIL."},
+		"calendar":         {Type: "STRING", Description: "For schedule, the name of the calendar to book on when the command names one; otherwise empty."},
+	},
+	Required: []string{"type", "response"},
+}
+
 func NewGeminiService(cfg *config.Config) *GeminiService {
 	return &GeminiService{
 		config: cfg,
@@ -45,26 +90,79 @@ func NewGeminiService(cfg *config.Config) *GeminiService {
 }
 
 func (g *GeminiService) ProcessCommand(command string) (string, error) {
-	if g.config.GeminiAPIKey == "" {
-		return g.mockProcessCommand(command)
+	request := GeminiRequest{
+		Contents: []GeminiContent{
+			{
+				Parts: []GeminiPart{
+					{
+						Text: fmt.Sprintf(`You are an AI executive assistant. Process this command and respond with a clear, actionable response: %s
+
+Please respond in a helpful, professional manner. If the command involves scheduling, emailing, or task management, provide specific details about what actions should be taken.`, command),
+					},
+				},
+			},
+		},
+	}
+
+	return g.generateContent(request)
+}
+
+// calendarsPrompt names the calendars meetings can be booked on, so the model
+// can recognize them in commands. It is empty with a single calendar.
+func (g *GeminiService) calendarsPrompt() string {
+	if len(g.config.Calendars) < 2 {
+		return ""
 	}
 
-	url := fmt.Sprintf("%s/models/gemini-pro:generateContent", g.config.GeminiURL)
+	var names []string
+	for _, calendar := range g.config.Calendars {
+		if calendar.Role != config.RoleRead {
+			names = append(names, fmt.Sprintf("%q", calendar.Name))
+		}
+	}
+	return fmt.Sprintf("\nThe user's calendars are %s; set calendar when the command names one of them.", strings.Join(names, ", "))
+}
 
+// ExtractIntent asks Gemini for a schema-constrained JSON description of the
+// command. now anchors relative expressions such as "tomorrow at 3pm".
+func (g *GeminiService) ExtractIntent(command string, now time.Time) (*Intent, error) {
 	request := GeminiRequest{
 		Contents: []GeminiContent{
 			{
 				Parts: []GeminiPart{
 					{
-						Text: fmt.Sprintf(`You are an AI executive assistant. Process this command and respond with a clear, actionable response: %s
+						Text: fmt.Sprintf(`You are an AI executive assistant. Extract the structured task from this command: %s
 
-Please respond in a helpful, professional manner. If the command involves scheduling, emailing, or task management, provide specific details about what actions should be taken.`, command),
+The current time is %s (%s). Resolve relative dates and times against it and return start_time in RFC 3339 format.
+Use "schedule" for new meetings, "reschedule" for moving an existing event, "cancel" for cancelling one, "suggest" for finding open times without booking, "email" for messages to send, "reminder" for reminders and "general" for anything else.
+For meetings that repeat, such as "every Monday" or "weekly standup", set recurrence and use the first occurrence as start_time.
+Only include email addresses that appear in the command.%s`, command, now.Format(time.RFC3339), now.Weekday(), g.calendarsPrompt()),
 					},
 				},
 			},
 		},
+		GenerationConfig: &GeminiGenerationConfig{
+			ResponseMimeType: "application/json",
+			ResponseSchema:   intentSchema,
+		},
+	}
+
+	text, err := g.generateContent(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var intent Intent
+	if err := json.Unmarshal([]byte(text), &intent); err != nil {
+		return nil, fmt.Errorf("failed to decode intent: %v", err)
 	}
 
+	return &intent, nil
+}
+
+func (g *GeminiService) generateContent(request GeminiRequest) (string, error) {
+	url := fmt.Sprintf("%s/models/%s:generateContent", g.config.GeminiURL, g.config.GeminiModel)
+
 	jsonData, err := json.Marshal(request)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal request: %v", err)
@@ -108,23 +206,3 @@ Please respond in a helpful, professional manner. If the command involves schedu
 
 	return response.Candidates[0].Content.Parts[0].Text, nil
 }
-
-func (g *GeminiService) mockProcessCommand(command string) (string, error) {
-	fmt.Printf("Gemini API key not configured. Using mock implementation.\n")
-	fmt.Printf("Processing command with Gemini: %s\n", command)
-
-	// Mock responses based on command type
-	lowerCommand := strings.ToLower(command)
-
-	if strings.Contains(lowerCommand, "schedule") || strings.Contains(lowerCommand, "meeting") {
-		return "I'll help schedule a meeting. Please provide the attendees, date, time, and meeting title.", nil
-	} else if strings.Contains(lowerCommand, "email") || strings.Contains(lowerCommand, "send") {
-		return "I'll help send an email. Please provide the recipient, subject, and message content.", nil
-	} else if strings.Contains(lowerCommand, "remind") || strings.Contains(lowerCommand, "task") {
-		return "I'll set a reminder. Please provide the task details and deadline.", nil
-	} else if strings.Contains(lowerCommand, "calendar") || strings.Contains(lowerCommand, "schedule") {
-		return "I'll check the calendar. What specific information would you like to know about the schedule?", nil
-	}
-
-	return fmt.Sprintf("I understand you want me to: %s. How can I help with this?", command), nil
-}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/config"
+)
+
+// geminiServer answers generateContent requests with a fixed status and
+// body, keeping the last request it received.
+type geminiServer struct {
+	status int
+	body   string
+
+	path    string
+	key     string
+	request GeminiRequest
+}
+
+func newGeminiServer(t *testing.T, g *geminiServer) *httptest.Server {
+	t.Helper()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.path = r.URL.Path
+		g.key = r.URL.Query().Get("key")
+		g.request = GeminiRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&g.request); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.WriteHeader(g.status)
+		w.Write([]byte(g.body))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// candidate wraps text in a generateContent response.
+func candidate(text string) string {
+	data, _ := json.Marshal(GeminiResponse{Candidates: []GeminiCandidate{{Content: GeminiContent{Parts: []GeminiPart{{Text: text}}}}}})
+	return string(data)
+}
+
+func TestGeminiExtractIntent(t *testing.T) {
+	now := time.Date(2026, time.October, 14, 10, 0, 0, 0, time.UTC)
+	server := &geminiServer{
+		status: http.StatusOK,
+		body:   candidate(`{"type":"schedule","title":"Planning","attendees":["bob@example.com"],"start_time":"2026-10-15T15:00:00Z","duration_minutes":45,"response":"Booking planning","calendar":"personal"}`),
+	}
+	ts := newGeminiServer(t, server)
+	gemini := NewGeminiService(&config.Config{
+		GeminiURL:    ts.URL,
+		GeminiModel:  "gemini-test",
+		GeminiAPIKey: "secret",
+		Calendars: []config.Calendar{
+			{Name: "work", Role: config.RoleDefault},
+			{Name: "personal", Role: config.RoleWrite},
+			{Name: "holidays", Role: config.RoleRead},
+		},
+	})
+
+	intent, err := gemini.ExtractIntent("Plan with bob@example.com tomorrow at 3pm on my personal calendar", now)
+	if err != nil {
+		t.Fatalf("ExtractIntent failed: %v", err)
+	}
+	if intent.Type != "schedule" || intent.Title != "Planning" || intent.StartTime != "2026-10-15T15:00:00Z" || intent.Duration != 45 ||
+		len(intent.Attendees) != 1 || intent.Response != "Booking planning" || intent.Calendar != "personal" {
+		t.Errorf("got intent %+v", intent)
+	}
+
+	// The request asks for JSON in the intent schema
+	if server.path != "/models/gemini-test:generateContent" || server.key != "secret" {
+		t.Errorf("requested %s with key %q", server.path, server.key)
+	}
+	generation := server.request.GenerationConfig
+	if generation == nil || generation.ResponseMimeType != "application/json" || generation.ResponseSchema == nil {
+		t.Fatalf("got generation config %+v", generation)
+	}
+	schema := generation.ResponseSchema
+	if schema.Type != "OBJECT" || strings.Join(schema.Required, ",") != "type,response" {
+		t.Errorf("got schema %+v", schema)
+	}
+	if types := schema.Properties["type"]; types == nil || strings.Join(types.Enum, ",") != strings.Join(IntentTypes, ",") {
+		t.Errorf("got type property %+v", types)
+	}
+	for _, name := range []string{"start_time", "duration_minutes", "event_query", "recurrence", "calendar"} {
+		if schema.Properties[name] == nil {
+			t.Errorf("schema lacks %s", name)
+		}
+	}
+
+	// The prompt carries the command, the current time and the writable
+	// calendars
+	prompt := server.request.Contents[0].Parts[0].Text
+	for _, want := range []string{"on my personal calendar", "2026-10-14T10:00:00Z (Wednesday)", `"work", "personal";`} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt lacks %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, "holidays") {
+		t.Errorf("prompt offers the read-only calendar:\n%s", prompt)
+	}
+}
+
+func TestGeminiExtractIntentErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		err    string
+	}{
+		{"malformed intent", http.StatusOK, candidate(`{"type": "schedule",`), "failed to decode intent"},
+		{"intent of the wrong shape", http.StatusOK, candidate(`{"type": ["schedule"]}`), "failed to decode intent"},
+		{"malformed response", http.StatusOK, `{"candidates": [`, "failed to decode response"},
+		{"no candidates", http.StatusOK, `{"candidates": []}`, "no response from Gemini API"},
+		{"no parts", http.StatusOK, `{"candidates": [{"content": {"parts": []}}]}`, "empty response from Gemini API"},
+		{"API error", http.StatusTooManyRequests, `{"error": "quota"}`, "gemini API error: 429"},
+	}
+
+	for _, tt := range tests {
+		ts := newGeminiServer(t, &geminiServer{status: tt.status, body: tt.body})
+		gemini := NewGeminiService(&config.Config{GeminiURL: ts.URL, GeminiModel: "gemini-test"})
+
+		intent, err := gemini.ExtractIntent("Plan something", time.Now())
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got %+v, %v; want an error containing %q", tt.name, intent, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package api
+
+import "github.com/azme12/ai-agent-project/internal/ical"
+
+// ICalEvent converts event to an iCalendar VEVENT identified by uid.
+func ICalEvent(event Event, uid string) ical.Event {
+	return ical.Event{
+		UID:         uid,
+		Summary:     event.Title,
+		Description: event.Description,
+		Location:    event.Location,
+		Status:      event.Status,
+		Start:       event.StartTime,
+		End:         event.EndTime,
+		Attendees:   append([]string(nil), event.Attendees...),
+		AllDay:      event.AllDay,
+		RRule:       event.Recurrence,
+	}
+}
+
+// EventFromICal converts an iCalendar VEVENT to an event with the given ID.
+func EventFromICal(vevent ical.Event, id string) Event {
+	return Event{
+		ID:          id,
+		Title:       vevent.Summary,
+		Description: vevent.Description,
+		Location:    vevent.Location,
+		Status:      vevent.Status,
+		StartTime:   vevent.Start,
+		EndTime:     vevent.End,
+		Attendees:   append([]string(nil), vevent.Attendees...),
+		AllDay:      vevent.AllDay,
+		Recurrence:  vevent.RRule,
+	}
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/config"
+)
+
+const (
+	// imapTimeout bounds each IMAP command, so a slow handler doesn't
+	// time out the session
+	imapTimeout = time.Minute
+	// maxIMAPLiteral bounds the size of a message read from the server
+	maxIMAPLiteral = 50 << 20
+)
+
+// IMAPMailbox reads the emails delivered to a mailbox on an IMAP server,
+// connecting over implicit TLS or upgrading with STARTTLS as configured.
+type IMAPMailbox struct {
+	config *config.Config
+	// tlsConfig verifies the server certificate; tests trust their own
+	tlsConfig *tls.Config
+}
+
+func NewIMAPMailbox(cfg *config.Config) *IMAPMailbox {
+	return &IMAPMailbox{
+		config:    cfg,
+		tlsConfig: &tls.Config{ServerName: cfg.IMAPHost},
+	}
+}
+
+// Receive hands each unseen email in the mailbox to handle as raw RFC 5322
+// data, and marks it seen once handled. It stops at the first email handle
+// fails on, which stays unseen to be offered again on the next call.
+func (m *IMAPMailbox) Receive(handle func(data []byte) error) error {
+	c, err := m.dial()
+	if err != nil {
+		return err
+	}
+	defer c.conn.Close()
+
+	if _, err := c.command("SELECT %s", quote(m.config.IMAPMailbox)); err != nil {
+		return err
+	}
+	responses, err := c.command("UID SEARCH UNSEEN")
+	if err != nil {
+		return err
+	}
+	var uids []string
+	for _, response := range responses {
+		if fields := strings.Fields(response.line); len(fields) >= 2 && strings.EqualFold(fields[1], "SEARCH") {
+			uids = append(uids, fields[2:]...)
+		}
+	}
+
+	for _, uid := range uids {
+		// PEEK leaves the message unseen until it is handled
+		responses, err := c.command("UID FETCH %s (BODY.PEEK[])", uid)
+		if err != nil {
+			return err
+		}
+		var data []byte
+		for _, response := range responses {
+			if strings.Contains(strings.ToUpper(response.line), " FETCH ") && len(response.literals) > 0 {
+				data = response.literals[0]
+				break
+			}
+		}
+		if data == nil {
+			return fmt.Errorf("message %s has no body", uid)
+		}
+
+		if err := handle(data); err != nil {
+			return err
+		}
+		if _, err := c.command(`UID STORE %s +FLAGS.SILENT (\Seen)`, uid); err != nil {
+			return err
+		}
+	}
+
+	c.command("LOGOUT")
+	return nil
+}
+
+// dial connects and logs in.
+func (m *IMAPMailbox) dial() (*imapConn, error) {
+	addr := net.JoinHostPort(m.config.IMAPHost, strconv.Itoa(m.config.IMAPPort))
+	dialer := &net.Dialer{Timeout: imapTimeout}
+
+	var conn net.Conn
+	var err error
+	if m.config.IMAPSecurity == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, m.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v", addr, err)
+	}
+
+	c := &imapConn{conn: conn, r: bufio.NewReader(conn)}
+	conn.SetDeadline(time.Now().Add(imapTimeout))
+	greeting, _, err := c.read()
+	if err != nil || !strings.HasPrefix(strings.ToUpper(greeting), "* OK") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected IMAP greeting from %s: %q %v", addr, greeting, err)
+	}
+
+	if m.config.IMAPSecurity == "starttls" {
+		if _, err := c.command("STARTTLS"); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, m.tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("STARTTLS failed: %v", err)
+		}
+		c.conn, c.r = tlsConn, bufio.NewReader(tlsConn)
+	}
+
+	if _, err := c.command("LOGIN %s %s", quote(m.config.IMAPUsername), quote(m.config.IMAPPassword)); err != nil {
+		c.conn.Close()
+		return nil, fmt.Errorf("authentication failed: %v", err)
+	}
+	return c, nil
+}
+
+// imapConn runs commands one at a time on an IMAP connection.
+type imapConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// imapResponse is an untagged response, with the literals it carries taken
+// out of its line.
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+// command sends a command and collects the untagged responses to it until
+// the server completes it, failing unless it completes with OK.
+func (c *imapConn) command(format string, args ...interface{}) ([]imapResponse, error) {
+	c.tag++
+	tag := fmt.Sprintf("A%d", c.tag)
+	command := fmt.Sprintf(format, args...)
+	name := strings.SplitN(format, " %", 2)[0]
+
+	c.conn.SetDeadline(time.Now().Add(imapTimeout))
+	if _, err := io.WriteString(c.conn, tag+" "+command+"\r\n"); err != nil {
+		return nil, fmt.Errorf("%s failed: %v", name, err)
+	}
+
+	var responses []imapResponse
+	for {
+		line, literals, err := c.read()
+		if err != nil {
+			return nil, fmt.Errorf("%s failed: %v", name, err)
+		}
+		if strings.HasPrefix(line, tag+" ") {
+			status := strings.TrimPrefix(line, tag+" ")
+			if !strings.HasPrefix(strings.ToUpper(status), "OK") {
+				return nil, fmt.Errorf("%s failed: %s", name, status)
+			}
+			return responses, nil
+		}
+		if strings.HasPrefix(line, "* ") {
+			responses = append(responses, imapResponse{line: line, literals: literals})
+		}
+	}
+}
+
+// read reads a response line, along with the literals ending its parts.
+func (c *imapConn) read() (string, [][]byte, error) {
+	var line strings.Builder
+	var literals [][]byte
+	for {
+		part, err := c.r.ReadString('\n')
+		if err != nil {
+			return "", nil, err
+		}
+		part = strings.TrimRight(part, "\r\n")
+		line.WriteString(part)
+
+		size, ok := literalSize(part)
+		if !ok {
+			return line.String(), literals, nil
+		}
+		if size > maxIMAPLiteral {
+			return "", nil, fmt.Errorf("literal of %d bytes is too large", size)
+		}
+		literal := make([]byte, size)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return "", nil, err
+		}
+		literals = append(literals, literal)
+	}
+}
+
+// literalSize returns the size of the literal announced at the end of a
+// line, as in "BODY[] {342}".
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	start := strings.LastIndex(line, "{")
+	if start < 0 {
+		return 0, false
+	}
+	size, err := strconv.Atoi(strings.TrimSuffix(line[start+1:len(line)-1], "+"))
+	if err != nil || size < 0 {
+		return 0, false
+	}
+	return size, true
+}
+
+// quote returns s as an IMAP quoted string. Line breaks, which a quoted
+// string can't hold, are dropped.
+func quote(s string) string {
+	s = strings.NewReplacer("\r", "", "\n", "").Replace(s)
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/azme12/ai-agent-project/internal/config"
+)
+
+// imapServer is an IMAP stand-in holding a mailbox of messages by UID, for a
+// client that logs in as alice with the password se"cret.
+type imapServer struct {
+	addr string
+
+	mu       sync.Mutex
+	messages map[int]string
+	seen     map[int]bool
+}
+
+func newIMAPServer(t *testing.T, messages map[int]string) *imapServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &imapServer{addr: listener.Addr().String(), messages: messages, seen: make(map[int]bool)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *imapServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	authenticated := false
+	reply("* OK IMAP4rev1 ready")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		parts := strings.SplitN(strings.TrimRight(line, "\r\n"), " ", 2)
+		if len(parts) < 2 {
+			return
+		}
+		tag, command := parts[0], parts[1]
+
+		s.mu.Lock()
+		switch {
+		case strings.HasPrefix(command, "LOGIN "):
+			authenticated = command == `LOGIN "alice" "se\"cret"`
+			if !authenticated {
+				reply(tag + " NO invalid credentials")
+				s.mu.Unlock()
+				continue
+			}
+		case !authenticated:
+			reply(tag + " BAD log in first")
+			s.mu.Unlock()
+			continue
+		case command == `SELECT "INBOX"`:
+			reply(fmt.Sprintf("* %d EXISTS", len(s.messages)))
+		case command == "UID SEARCH UNSEEN":
+			var uids []int
+			for uid := range s.messages {
+				if !s.seen[uid] {
+					uids = append(uids, uid)
+				}
+			}
+			sort.Ints(uids)
+			line := "* SEARCH"
+			for _, uid := range uids {
+				line += " " + strconv.Itoa(uid)
+			}
+			reply(line)
+		case strings.HasPrefix(command, "UID FETCH "):
+			uid, _ := strconv.Atoi(strings.Fields(command)[2])
+			message := s.messages[uid]
+			reply(fmt.Sprintf("* 1 FETCH (UID %d BODY[] {%d}", uid, len(message)))
+			io.WriteString(conn, message)
+			reply(")")
+		case strings.HasPrefix(command, "UID STORE "):
+			uid, _ := strconv.Atoi(strings.Fields(command)[2])
+			s.seen[uid] = true
+		case command == "LOGOUT":
+			reply("* BYE")
+			reply(tag + " OK done")
+			s.mu.Unlock()
+			return
+		default:
+			reply(tag + " BAD unknown command")
+			s.mu.Unlock()
+			continue
+		}
+		s.mu.Unlock()
+		reply(tag + " OK done")
+	}
+}
+
+func (s *imapServer) config(password string) *config.Config {
+	host, port, _ := net.SplitHostPort(s.addr)
+	portNumber, _ := strconv.Atoi(port)
+	return &config.Config{
+		IMAPHost:     host,
+		IMAPPort:     portNumber,
+		IMAPUsername: "alice",
+		IMAPPassword: password,
+		IMAPSecurity: "none",
+		IMAPMailbox:  "INBOX",
+	}
+}
+
+func TestIMAPMailbox(t *testing.T) {
+	server := newIMAPServer(t, map[int]string{
+		4: "From: bob@example.com\r\nSubject: First\r\n\r\nLine one\r\n",
+		7: "From: bob@example.com\r\nSubject: Second\r\n\r\n{5}\r\n",
+	})
+	mailbox := NewIMAPMailbox(server.config(`se"cret`))
+
+	// The second message fails and stays unseen
+	var received []string
+	failure := errors.New("disk full")
+	err := mailbox.Receive(func(data []byte) error {
+		received = append(received, string(data))
+		if len(received) == 2 {
+			return failure
+		}
+		return nil
+	})
+	if err != failure {
+		t.Errorf("Receive returned %v, want the handler's error", err)
+	}
+	if len(received) != 2 || received[0] != server.messages[4] || received[1] != server.messages[7] {
+		t.Fatalf("received %q", received)
+	}
+
+	received = nil
+	if err := mailbox.Receive(func(data []byte) error {
+		received = append(received, string(data))
+		return nil
+	}); err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	if len(received) != 1 || received[0] != server.messages[7] {
+		t.Errorf("received %q on the second poll", received)
+	}
+
+	if err := NewIMAPMailbox(server.config("wrong")).Receive(func([]byte) error { return nil }); err == nil || !strings.Contains(err.Error(), "authentication failed") {
+		t.Errorf("got %v with a wrong password", err)
+	}
+}
@@ -0,0 +1,344 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// InboundEmail is an email received by the agent, from the SendGrid Inbound
+// Parse webhook or an IMAP mailbox.
+type InboundEmail struct {
+	// From is the bare address of the sender
+	From       string
+	To         []string
+	Subject    string
+	Text       string
+	HTML       string
+	MessageID  string
+	InReplyTo  string
+	References []string
+	// AutoSubmitted is set for automatic replies and bulk mail, which must
+	// not be answered
+	AutoSubmitted bool
+	// Authentication is "pass" when DMARC passed or a DKIM signature of the
+	// From domain verified, "fail" when the receiving server checked the
+	// email otherwise, and empty when it is unknown
+	Authentication string
+}
+
+// PlainText returns the text body, or the HTML body as text when there is
+// no text version.
+func (e *InboundEmail) PlainText() string {
+	if strings.TrimSpace(e.Text) != "" || e.HTML == "" {
+		return e.Text
+	}
+	return htmlToText(e.HTML)
+}
+
+// ParseEmail reads a raw RFC 5322 email, taking the first text/plain and
+// text/html parts of its body and skipping attachments. Authentication is
+// taken from the first Authentication-Results header, or from the first one
+// added by authServ when it is set, since the sender can add their own.
+func ParseEmail(r io.Reader, authServ string) (*InboundEmail, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email: %v", err)
+	}
+
+	email, err := parseHeader(msg.Header)
+	if err != nil {
+		return nil, err
+	}
+	if err := readBody(email, msg.Header, msg.Body); err != nil {
+		return nil, fmt.Errorf("failed to read email body: %v", err)
+	}
+	email.Authentication = authenticationResults(msg.Header["Authentication-Results"], authServ, email.From)
+	return email, nil
+}
+
+// ParseSendGridInbound reads the form posted by the SendGrid Inbound Parse
+// webhook, either with the message parsed into fields or, when "Send Raw"
+// is enabled, whole in the "email" field. Authentication comes from
+// SendGrid's DKIM results alone: SPF only vouches for the envelope sender,
+// and Authentication-Results headers in the message may be forged.
+func ParseSendGridInbound(form url.Values) (*InboundEmail, error) {
+	var email *InboundEmail
+	var err error
+	if raw := form.Get("email"); raw != "" {
+		email, err = ParseEmail(strings.NewReader(raw), "")
+	} else {
+		email, err = parseSendGridFields(form)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	email.Authentication = ""
+	if form.Get("SPF") != "" || form.Get("dkim") != "" {
+		email.Authentication = "fail"
+	}
+	// The dkim field lists each signature as "{@example.com : pass}"
+	for _, signature := range strings.Split(strings.Trim(form.Get("dkim"), "{}"), ",") {
+		parts := strings.SplitN(signature, ":", 2)
+		if len(parts) == 2 && strings.EqualFold(strings.TrimSpace(parts[1]), "pass") && aligned(email.From, parts[0]) {
+			email.Authentication = "pass"
+		}
+	}
+	return email, nil
+}
+
+func parseSendGridFields(form url.Values) (*InboundEmail, error) {
+	// The headers are parsed as a message without a body
+	headers := strings.TrimRight(form.Get("headers"), "\r\n") + "\r\n\r\n"
+	msg, err := mail.ReadMessage(strings.NewReader(headers))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email headers: %v", err)
+	}
+	header := msg.Header
+	// The fields are authoritative where the headers are missing
+	for name, field := range map[string]string{"From": "from", "To": "to", "Subject": "subject"} {
+		if header.Get(name) == "" && form.Get(field) != "" {
+			header[name] = []string{form.Get(field)}
+		}
+	}
+
+	email, err := parseHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fields are sent in the charsets SendGrid lists for them
+	var charsets map[string]string
+	json.Unmarshal([]byte(form.Get("charsets")), &charsets)
+	email.Text = decodeCharset([]byte(form.Get("text")), charsets["text"])
+	email.HTML = decodeCharset([]byte(form.Get("html")), charsets["html"])
+	return email, nil
+}
+
+var wordDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		if !knownCharset(charset) {
+			return nil, fmt.Errorf("unsupported charset %s", charset)
+		}
+		return strings.NewReader(decodeCharset(data, charset)), nil
+	},
+}
+
+// parseHeader reads the addresses, subject and threading headers.
+func parseHeader(header mail.Header) (*InboundEmail, error) {
+	from, err := header.AddressList("From")
+	if err != nil || len(from) == 0 {
+		return nil, fmt.Errorf("email has no valid sender: %v", err)
+	}
+
+	email := &InboundEmail{From: strings.ToLower(from[0].Address)}
+	if to, err := header.AddressList("To"); err == nil {
+		for _, address := range to {
+			email.To = append(email.To, address.Address)
+		}
+	}
+	email.Subject = header.Get("Subject")
+	if subject, err := wordDecoder.DecodeHeader(email.Subject); err == nil {
+		email.Subject = subject
+	}
+	email.MessageID = strings.TrimSpace(header.Get("Message-Id"))
+	email.InReplyTo = strings.TrimSpace(header.Get("In-Reply-To"))
+	email.References = strings.Fields(header.Get("References"))
+
+	// RFC 3834 automatic responses, and mailing list or bulk mail
+	autoSubmitted := strings.ToLower(header.Get("Auto-Submitted"))
+	precedence := strings.ToLower(header.Get("Precedence"))
+	email.AutoSubmitted = (autoSubmitted != "" && autoSubmitted != "no") ||
+		precedence == "bulk" || precedence == "list" || precedence == "junk" ||
+		header.Get("List-Id") != ""
+	return email, nil
+}
+
+var resultCommentRegex = regexp.MustCompile(`\([^)]*\)`)
+
+// authenticationResults summarizes the RFC 8601 Authentication-Results
+// header added by the receiving server, which is the topmost one, or the
+// topmost one from authServ when it is set. The email passes when DMARC
+// passed or a DKIM signature of the From domain verified; SPF alone isn't
+// enough, since it checks the envelope sender rather than From.
+func authenticationResults(headers []string, authServ, from string) string {
+	for _, header := range headers {
+		results := strings.Split(resultCommentRegex.ReplaceAllString(strings.ToLower(header), ""), ";")
+		id := strings.Fields(results[0])
+		if authServ != "" && (len(id) == 0 || id[0] != strings.ToLower(authServ)) {
+			continue
+		}
+
+		status := ""
+		for _, result := range results[1:] {
+			fields := strings.Fields(result)
+			if len(fields) == 0 {
+				continue
+			}
+			method := strings.SplitN(fields[0], "=", 2)
+			if len(method) != 2 || method[0] == "none" {
+				continue
+			}
+			status = "fail"
+			if method[1] != "pass" {
+				continue
+			}
+			if method[0] == "dmarc" {
+				return "pass"
+			}
+			for _, property := range fields[1:] {
+				if method[0] == "dkim" && (strings.HasPrefix(property, "header.d=") || strings.HasPrefix(property, "header.i=")) &&
+					aligned(from, property[len("header.d="):]) {
+					return "pass"
+				}
+			}
+		}
+		return status
+	}
+	return ""
+}
+
+// aligned reports whether the domain of a signature, given as "example.com",
+// "@example.com" or "user@example.com", is that of the address from or one
+// of its parents.
+func aligned(from, domain string) bool {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = domain[strings.LastIndex(domain, "@")+1:]
+	from = strings.ToLower(from)
+	from = from[strings.LastIndex(from, "@")+1:]
+	return domain != "" && (from == domain || strings.HasSuffix(from, "."+domain))
+}
+
+// partHeader is a message or MIME part header.
+type partHeader interface {
+	Get(key string) string
+}
+
+// readBody fills in the text and HTML bodies of email from a MIME entity,
+// descending into multipart entities.
+func readBody(email *InboundEmail, header partHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+	if disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition")); disposition == "attachment" {
+		return nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := readBody(email, part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return nil
+	}
+	if (mediaType == "text/plain" && email.Text != "") || (mediaType == "text/html" && email.HTML != "") {
+		return nil
+	}
+
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: body})
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	text := decodeCharset(data, params["charset"])
+	if mediaType == "text/plain" {
+		email.Text = text
+	} else {
+		email.HTML = text
+	}
+	return nil
+}
+
+// newlineStripper drops the line breaks of base64 encoded content.
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (s *newlineStripper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	j := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' {
+			p[j] = b
+			j++
+		}
+	}
+	return j, err
+}
+
+func knownCharset(charset string) bool {
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "utf8", "us-ascii", "iso-8859-1", "latin1", "windows-1252":
+		return true
+	}
+	return false
+}
+
+// decodeCharset converts text to UTF-8. Latin-1 is converted byte by byte;
+// other charsets are assumed to be UTF-8 already.
+func decodeCharset(data []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+	return string(bytes.ToValidUTF8(data, []byte("�")))
+}
+
+var (
+	htmlBreakRegex = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|tr|h[1-6])>`)
+	htmlDropRegex  = regexp.MustCompile(`(?is)<(style|script|head)\b.*?</(style|script|head)>`)
+	htmlTagRegex   = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankRunRegex  = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText reduces an HTML body to its text, keeping line breaks.
+func htmlToText(s string) string {
+	s = htmlDropRegex.ReplaceAllString(s, "")
+	s = htmlBreakRegex.ReplaceAllString(s, "\n")
+	s = htmlTagRegex.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankRunRegex.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"net/mail"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseEmail(t *testing.T) {
+	// A message as the agent builds it: quoted-printable text and HTML
+	// alternatives, an encoded subject and an attachment
+	message := &Message{
+		To:          []string{"agent@example.com"},
+		Subject:     "Réunion: planning",
+		Text:        "Schedule a meeting with Zoë tomorrow at 10\n\n" + strings.Repeat("long line ", 12) + "\n",
+		HTML:        "<p>Schedule a meeting with <b>Zoë</b></p>",
+		Attachments: []Attachment{{Filename: "notes.txt", ContentType: "text/plain", Content: []byte("not the body")}},
+		Headers: map[string]string{
+			"Message-Id":             "<reply@example.com>",
+			"In-Reply-To":            "<first@example.com>",
+			"References":             "<first@example.com>",
+			"Authentication-Results": "mx.example.com; spf=pass smtp.mailfrom=example.com; dkim=fail; dmarc=pass",
+		},
+	}
+	r, err := message.validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := buildMessage(mail.Address{Name: "Bob", Address: "Bob@Example.com"}, message, r, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	email, err := ParseEmail(strings.NewReader(string(data)), "")
+	if err != nil {
+		t.Fatalf("ParseEmail failed: %v", err)
+	}
+	if email.From != "bob@example.com" || email.Subject != "Réunion: planning" || len(email.To) != 1 {
+		t.Errorf("got sender %q, subject %q and recipients %v", email.From, email.Subject, email.To)
+	}
+	if email.Text != strings.ReplaceAll(message.Text, "\n", "\r\n") {
+		t.Errorf("got text %q", email.Text)
+	}
+	if email.HTML != message.HTML {
+		t.Errorf("got HTML %q", email.HTML)
+	}
+	if email.MessageID != "<reply@example.com>" || email.InReplyTo != "<first@example.com>" || len(email.References) != 1 {
+		t.Errorf("got threading %q, %q, %v", email.MessageID, email.InReplyTo, email.References)
+	}
+	if email.Authentication != "pass" || email.AutoSubmitted {
+		t.Errorf("got authentication %q, automatic %v", email.Authentication, email.AutoSubmitted)
+	}
+
+	// An automatic reply with a base64 Latin-1 body
+	raw := "From: mailer-daemon@example.com\r\n" +
+		"Subject: Out of office\r\n" +
+		"Auto-Submitted: auto-replied\r\n" +
+		"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+		"Content-Transfer-Encoding: base64\r\n\r\n" +
+		"QWJzZW50IGp1c3F1J2F1\r\nIGx1bmRpLCBk6WJ1dA==\r\n"
+	email, err = ParseEmail(strings.NewReader(raw), "")
+	if err != nil {
+		t.Fatalf("ParseEmail failed: %v", err)
+	}
+	if !email.AutoSubmitted || email.Text != "Absent jusqu'au lundi, début" {
+		t.Errorf("got automatic %v and text %q", email.AutoSubmitted, email.Text)
+	}
+
+	if _, err := ParseEmail(strings.NewReader("Subject: nobody\r\n\r\nbody"), ""); err == nil {
+		t.Error("parsed an email without a sender")
+	}
+}
+
+func TestParseSendGridInbound(t *testing.T) {
+	form := url.Values{
+		"headers":  {"Message-ID: <abc@example.com>\nFrom: Bob <bob@example.com>\nTo: agent@example.com\nSubject: Re: Standup\n"},
+		"from":     {"Bob <bob@example.com>"},
+		"subject":  {"Re: Standup"},
+		"html":     {"<p>Move the standup to 10am&nbsp;tomorrow</p><p>Thanks</p>"},
+		"charsets": {`{"to":"UTF-8","html":"UTF-8","subject":"UTF-8","from":"UTF-8"}`},
+		"SPF":      {"pass"},
+		"dkim":     {"{@example.com : pass}"},
+	}
+	email, err := ParseSendGridInbound(form)
+	if err != nil {
+		t.Fatalf("ParseSendGridInbound failed: %v", err)
+	}
+	if email.From != "bob@example.com" || email.Subject != "Re: Standup" || email.MessageID != "<abc@example.com>" {
+		t.Errorf("got %+v", email)
+	}
+	if email.Authentication != "pass" {
+		t.Errorf("got authentication %q", email.Authentication)
+	}
+	if text := email.PlainText(); text != "Move the standup to 10am tomorrow\nThanks" {
+		t.Errorf("got text %q", text)
+	}
+
+	// Raw mode posts the whole message, and SendGrid's checks decide
+	form = url.Values{
+		"email": {"From: carol@example.com\r\nSubject: Lunch\r\n\r\nBook lunch on Friday\r\n"},
+		"SPF":   {"softfail"},
+	}
+	email, err = ParseSendGridInbound(form)
+	if err != nil {
+		t.Fatalf("ParseSendGridInbound failed: %v", err)
+	}
+	if email.From != "carol@example.com" || email.Text != "Book lunch on Friday\r\n" || email.Authentication != "fail" {
+		t.Errorf("got %+v", email)
+	}
+}
+
+func TestInboundAuthentication(t *testing.T) {
+	spoofed := url.Values{
+		"headers": {"From: boss@company.com\nSubject: Wire the money\n"},
+		"text":    {"Email accounting"},
+		// The signature and SPF are for the sender's own domain
+		"SPF":  {"pass"},
+		"dkim": {"{@evil.com : pass}"},
+	}
+	if email, err := ParseSendGridInbound(spoofed); err != nil || email.Authentication != "fail" {
+		t.Errorf("spoofed From got %+v, %v", email, err)
+	}
+	signed := url.Values{
+		"headers": {"From: bob@mail.example.com\n"},
+		"dkim":    {"{@evil.com : pass, @example.com : pass}"},
+	}
+	if email, err := ParseSendGridInbound(signed); err != nil || email.Authentication != "pass" {
+		t.Errorf("signature of the parent domain got %+v, %v", email, err)
+	}
+	// A raw email's own headers aren't trusted
+	raw := url.Values{"email": {"From: bob@example.com\r\nAuthentication-Results: mx; dmarc=pass\r\n\r\nhi\r\n"}}
+	if email, err := ParseSendGridInbound(raw); err != nil || email.Authentication != "" {
+		t.Errorf("raw email got %+v, %v", email, err)
+	}
+
+	tests := []struct {
+		name     string
+		headers  []string
+		authServ string
+		want     string
+	}{
+		{"dmarc", []string{"mx.example.net; spf=fail; dkim=none; dmarc=pass (p=reject) header.from=example.com"}, "", "pass"},
+		{"aligned dkim", []string{"mx.example.net; dkim=pass header.i=@example.com; spf=softfail"}, "", "pass"},
+		{"foreign dkim and spf", []string{"mx.example.net; dkim=pass header.d=evil.com; spf=pass smtp.mailfrom=evil.com"}, "", "fail"},
+		{"lookalike dkim", []string{"mx.example.net; dkim=pass header.d=example.com.evil.org"}, "", "fail"},
+		{"unchecked", nil, "", ""},
+		{"nothing checked", []string{"mx.example.net; none"}, "", ""},
+		// A header the sender forged below the server's own is skipped
+		{"trusted server", []string{"mx.example.net; dkim=fail", "evil.com; dmarc=pass"}, "mx.example.net", "fail"},
+		{"forged server", []string{"evil.com; dmarc=pass"}, "mx.example.net", ""},
+	}
+	for _, test := range tests {
+		if got := authenticationResults(test.headers, test.authServ, "bob@example.com"); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package api
+
+import "errors"
+
+// ErrModelNotConfigured is returned by language models that cannot extract
+// structured intents, telling callers to fall back to offline parsing.
+var ErrModelNotConfigured = errors.New("language model not configured")
+
+// IntentTypes lists the task types a language model may return.
+var IntentTypes = []string{"schedule", "reschedule", "cancel", "suggest", "email", "reminder", "general"}
+
+// Intent is the structured task a language model extracts from a command.
+type Intent struct {
+	Type      string   `json:"type"`
+	Title     string   `json:"title"`
+	Attendees []string `json:"attendees"`
+	StartTime string   `json:"start_time"`
+	Duration  int      `json:"duration_minutes"`
+	To        []string `json:"to"`
+	Subject   string   `json:"subject"`
+	Body      string   `json:"body"`
+	Response  string   `json:"response"`
+
+	// EventQuery describes the existing event a reschedule or cancel
+	// refers to, such as "3pm with Sarah".
+	EventQuery string `json:"event_query"`
+	// KeepTimeOfDay is set when a reschedule only names a new day, so the
+	// event keeps its current time of day.
+	KeepTimeOfDay bool `json:"keep_time_of_day"`
+	// ShiftMinutes moves an event relative to its current start instead.
+	ShiftMinutes int `json:"shift_minutes"`
+	// EndTime ends the search window of a suggest task, which starts at
+	// StartTime.
+	EndTime string `json:"end_time"`
+	// Recurrence is the RRULE of a schedule task for a repeating meeting.
+	Recurrence string `json:"recurrence"`
+	// Calendar names the calendar a schedule task books on.
+	Calendar string `json:"calendar"`
+	// CC and BCC are the copied recipients of an email.
+	CC  []string `json:"cc"`
+	BCC []string `json:"bcc"`
+}
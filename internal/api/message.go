@@ -0,0 +1,123 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// ErrRejected is returned when the email provider refuses a message, such
+// as for an unknown recipient, so that sending it again won't help.
+var ErrRejected = errors.New("message rejected")
+
+// reservedHeaders are set from the fields of a Message and can't be given
+// as custom headers.
+var reservedHeaders = map[string]bool{
+	"From": true, "To": true, "Cc": true, "Bcc": true, "Reply-To": true, "Subject": true,
+	"Date": true, "Mime-Version": true, "Content-Type": true, "Content-Transfer-Encoding": true,
+}
+
+// Message is an outgoing email. Addresses may be bare ("bob@example.com")
+// or carry a display name ("Bob <bob@example.com>"). Text and HTML are
+// alternative versions of the body, so clients show the richest one they
+// support; at least one is required.
+type Message struct {
+	To      []string `json:"to,omitempty"`
+	CC      []string `json:"cc,omitempty"`
+	BCC     []string `json:"bcc,omitempty"`
+	ReplyTo string   `json:"reply_to,omitempty"`
+	Subject string   `json:"subject"`
+	Text    string   `json:"text,omitempty"`
+	HTML    string   `json:"html,omitempty"`
+
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Headers are added to the message, such as In-Reply-To or List-Id
+	// headers. They can't replace the headers set from the other fields.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Attachment is a file sent along with an email, such as a calendar invite.
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	Content     []byte `json:"content,omitempty"`
+}
+
+// recipients holds the parsed addresses of a message.
+type recipients struct {
+	to, cc, bcc []*mail.Address
+	replyTo     *mail.Address
+}
+
+// all returns every envelope recipient, each address once.
+func (r *recipients) all() []string {
+	seen := make(map[string]bool)
+	var addresses []string
+	for _, list := range [][]*mail.Address{r.to, r.cc, r.bcc} {
+		for _, address := range list {
+			key := strings.ToLower(address.Address)
+			if !seen[key] {
+				seen[key] = true
+				addresses = append(addresses, address.Address)
+			}
+		}
+	}
+	return addresses
+}
+
+// Validate checks that the message can be sent, without sending it.
+func (m *Message) Validate() error {
+	_, err := m.validate()
+	return err
+}
+
+// validate checks the message and parses its addresses.
+func (m *Message) validate() (*recipients, error) {
+	if len(m.To)+len(m.CC)+len(m.BCC) == 0 {
+		return nil, fmt.Errorf("email has no recipients")
+	}
+	if m.Text == "" && m.HTML == "" {
+		return nil, fmt.Errorf("email has no body")
+	}
+
+	var r recipients
+	var err error
+	if r.to, err = parseAddresses(m.To); err != nil {
+		return nil, err
+	}
+	if r.cc, err = parseAddresses(m.CC); err != nil {
+		return nil, err
+	}
+	if r.bcc, err = parseAddresses(m.BCC); err != nil {
+		return nil, err
+	}
+	if m.ReplyTo != "" {
+		if r.replyTo, err = mail.ParseAddress(m.ReplyTo); err != nil {
+			return nil, fmt.Errorf("invalid reply-to address %q: %v", m.ReplyTo, err)
+		}
+	}
+
+	for name, value := range m.Headers {
+		if reservedHeaders[textproto.CanonicalMIMEHeaderKey(name)] {
+			return nil, fmt.Errorf("header %s is set from the message", name)
+		}
+		if strings.ContainsAny(name, "\r\n: ") || strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("invalid header %q", name)
+		}
+	}
+	return &r, nil
+}
+
+func parseAddresses(list []string) ([]*mail.Address, error) {
+	var addresses []*mail.Address
+	for _, s := range list {
+		address, err := mail.ParseAddress(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid email address %q: %v", s, err)
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
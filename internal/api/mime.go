@@ -0,0 +1,204 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/store"
+)
+
+// base64LineLength is the longest line of base64 encoded content, as RFC
+// 2045 requires.
+const base64LineLength = 76
+
+// buildMessage composes message as an RFC 5322 email, ready to be sent over
+// SMTP. The body is a text/plain or text/html part, or a
+// multipart/alternative of both, wrapped in multipart/mixed when there are
+// attachments. BCC recipients are left out of the headers. Non-ASCII names
+// and subjects are encoded as RFC 2047 words and text as quoted-printable.
+func buildMessage(from mail.Address, message *Message, r *recipients, date time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", from.String())
+	if len(r.to) > 0 {
+		header("To", formatAddresses(r.to))
+	}
+	if len(r.cc) > 0 {
+		header("Cc", formatAddresses(r.cc))
+	}
+	if r.replyTo != nil {
+		header("Reply-To", r.replyTo.String())
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", message.Subject))
+	header("Date", date.Format(time.RFC1123Z))
+
+	// Custom headers are written in a stable order, and may set the
+	// Message-ID
+	headers := make(map[string]string)
+	var names []string
+	for name, value := range message.Headers {
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		headers[name] = value
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if _, ok := headers["Message-Id"]; !ok {
+		header("Message-ID", messageID(from.Address))
+	}
+	for _, name := range names {
+		header(name, mime.QEncoding.Encode("utf-8", headers[name]))
+	}
+	header("MIME-Version", "1.0")
+
+	if len(message.Attachments) == 0 {
+		err := writeBody(message, func(h textproto.MIMEHeader) (io.Writer, error) {
+			header("Content-Type", h.Get("Content-Type"))
+			header("Content-Transfer-Encoding", h.Get("Content-Transfer-Encoding"))
+			buf.WriteString("\r\n")
+			return &buf, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	// The boundary is folded onto its own line to keep lines short
+	header("Content-Type", "multipart/mixed;\r\n boundary="+mw.Boundary())
+	buf.WriteString("\r\n")
+
+	if err := writeBody(message, mw.CreatePart); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range message.Attachments {
+		contentType := attachment.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to write attachment %s: %v", attachment.Filename, err)
+		}
+		if err := writeBase64(part, attachment.Content); err != nil {
+			return nil, fmt.Errorf("failed to write attachment %s: %v", attachment.Filename, err)
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write message: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBody writes the text and HTML versions of the body of message into
+// the part created by createPart, as a multipart/alternative when there are
+// both.
+func writeBody(message *Message, createPart func(textproto.MIMEHeader) (io.Writer, error)) error {
+	textPart := func(subtype string) textproto.MIMEHeader {
+		return textproto.MIMEHeader{
+			"Content-Type":              {"text/" + subtype + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		}
+	}
+
+	if message.HTML == "" || message.Text == "" {
+		subtype, text := "plain", message.Text
+		if message.HTML != "" {
+			subtype, text = "html", message.HTML
+		}
+		w, err := createPart(textPart(subtype))
+		if err != nil {
+			return fmt.Errorf("failed to write message body: %v", err)
+		}
+		return writeQuotedPrintable(w, text)
+	}
+
+	// Clients show the last alternative they support, so HTML goes last
+	var alternatives bytes.Buffer
+	aw := multipart.NewWriter(&alternatives)
+	w, err := createPart(textproto.MIMEHeader{
+		"Content-Type":              {"multipart/alternative;\r\n boundary=" + aw.Boundary()},
+		"Content-Transfer-Encoding": {"7bit"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write message body: %v", err)
+	}
+	for _, version := range []struct{ subtype, text string }{{"plain", message.Text}, {"html", message.HTML}} {
+		part, err := aw.CreatePart(textPart(version.subtype))
+		if err != nil {
+			return fmt.Errorf("failed to write message body: %v", err)
+		}
+		if err := writeQuotedPrintable(part, version.text); err != nil {
+			return err
+		}
+	}
+	if err := aw.Close(); err != nil {
+		return fmt.Errorf("failed to write message body: %v", err)
+	}
+	_, err = w.Write(alternatives.Bytes())
+	return err
+}
+
+// formatAddresses joins addresses for a header, one per line.
+func formatAddresses(addresses []*mail.Address) string {
+	var formatted []string
+	for _, address := range addresses {
+		formatted = append(formatted, address.String())
+	}
+	return strings.Join(formatted, ",\r\n ")
+}
+
+// messageID returns a unique Message-ID in the domain of the sender.
+func messageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = from[i+1:]
+	}
+	return fmt.Sprintf("<%s@%s>", store.NewID(), domain)
+}
+
+func writeQuotedPrintable(w io.Writer, text string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(text)); err != nil {
+		return fmt.Errorf("failed to encode message body: %v", err)
+	}
+	if err := qp.Close(); err != nil {
+		return fmt.Errorf("failed to encode message body: %v", err)
+	}
+	return nil
+}
+
+// writeBase64 writes data base64 encoded in lines of base64LineLength.
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := base64LineLength
+		if n > len(encoded) {
+			n = len(encoded)
+		}
+		if _, err := fmt.Fprintf(w, "%s\r\n", encoded[:n]); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/config"
+	"github.com/azme12/ai-agent-project/internal/oauth"
+	"github.com/azme12/ai-agent-project/pkg/logger"
+)
+
+// CalendarProvider is implemented by every calendar backend the agent can
+// book meetings on and manage events in. Lookups of unknown or cancelled
+// events return ErrEventNotFound.
+type CalendarProvider interface {
+	// ScheduleMeeting creates meeting, which repeats when its Recurrence is
+	// set. The created event is returned with its ID.
+	ScheduleMeeting(meeting Event) (*Event, error)
+	GetUpcomingEvents() ([]Event, error)
+	ListEvents(from, to time.Time) ([]Event, error)
+	GetEvent(id string) (*Event, error)
+	UpdateEvent(id string, update EventUpdate) (*Event, error)
+	DeleteEvent(id string) error
+	// RescheduleEvent moves an event to startTime; a zero duration keeps its
+	// current length.
+	RescheduleEvent(id string, startTime time.Time, duration time.Duration) (*Event, error)
+	// FreeBusy reports when the agent's calendar and the given attendees are
+	// busy between from and to.
+	FreeBusy(attendees []string, from, to time.Time) (*FreeBusy, error)
+}
+
+// SyncingCalendar is a calendar that can also report what changed since an
+// earlier read, which lets an EventCache keep a local copy of its events.
+type SyncingCalendar interface {
+	CalendarProvider
+	// SyncEvents lists the events between from and to when token is empty,
+	// and otherwise the changes since the sync that returned token. An
+	// expired token returns ErrSyncTokenExpired.
+	SyncEvents(token string, from, to time.Time) (*EventChanges, error)
+}
+
+// MailSender is implemented by every outgoing email backend.
+type MailSender interface {
+	Send(message Message) error
+}
+
+// LanguageModel is implemented by every natural language backend.
+type LanguageModel interface {
+	ProcessCommand(command string) (string, error)
+	ExtractIntent(command string, now time.Time) (*Intent, error)
+}
+
+// NewCalendarProvider returns the calendar backend selected by
+// cfg.CalendarProvider, combining the configured calendars. auth
+// authenticates Google requests and is nil when no Google credentials are
+// configured. An empty provider picks Google when they are, CalDAV when a
+// CalDAV URL is, and the mock implementation otherwise.
+func NewCalendarProvider(cfg *config.Config, log *logger.Logger, clk clock.Clock, auth oauth.TokenSource) (CalendarProvider, error) {
+	var newCalendar func(id string) CalendarProvider
+	switch cfg.CalendarProvider {
+	case "":
+		switch {
+		case auth != nil:
+			newCalendar = func(id string) CalendarProvider {
+				return cached(cfg, clk, NewGoogleCalendarService(cfg, clk, auth, id))
+			}
+		case cfg.CalDAVURL != "":
+			newCalendar = func(id string) CalendarProvider { return NewCalDAVCalendarService(cfg, log, clk, id) }
+		}
+	case "google":
+		if auth == nil {
+			return nil, fmt.Errorf("google calendar provider requires GOOGLE_CLIENT_ID, GOOGLE_SERVICE_ACCOUNT_FILE or GOOGLE_CALENDAR_API_KEY")
+		}
+		newCalendar = func(id string) CalendarProvider {
+			return cached(cfg, clk, NewGoogleCalendarService(cfg, clk, auth, id))
+		}
+	case "caldav":
+		if cfg.CalDAVURL == "" {
+			return nil, fmt.Errorf("caldav calendar provider requires CALDAV_URL")
+		}
+		newCalendar = func(id string) CalendarProvider { return NewCalDAVCalendarService(cfg, log, clk, id) }
+	case "mock":
+	default:
+		return nil, fmt.Errorf("unknown calendar provider: %s", cfg.CalendarProvider)
+	}
+
+	calendars := cfg.Calendars
+	if len(calendars) == 0 {
+		calendars = []config.Calendar{cfg.DefaultCalendar()}
+	}
+	var providers []CalendarProvider
+	for i, c := range calendars {
+		switch {
+		case newCalendar != nil:
+			providers = append(providers, newCalendar(c.ID))
+		case i == 0:
+			providers = append(providers, NewMockCalendarService(clk))
+		default:
+			// Only the first mock calendar holds sample events
+			providers = append(providers, newEmptyMockCalendar(clk))
+		}
+	}
+	return NewMultiCalendar(calendars, providers), nil
+}
+
+// cached wraps calendar in an EventCache unless CALENDAR_SYNC_SECONDS turns
+// caching off.
+func cached(cfg *config.Config, clk clock.Clock, calendar SyncingCalendar) CalendarProvider {
+	if cfg.CalendarSyncSeconds <= 0 {
+		return calendar
+	}
+	return NewEventCache(calendar, clk, time.Duration(cfg.CalendarSyncSeconds)*time.Second)
+}
+
+// NewMailSender returns the email backend selected by cfg.EmailProvider.
+// An empty provider picks SendGrid when an API key is configured, SMTP when
+// a server is, and the mock implementation otherwise.
+func NewMailSender(cfg *config.Config, clk clock.Clock) (MailSender, error) {
+	switch cfg.EmailProvider {
+	case "":
+		switch {
+		case cfg.SendGridAPIKey != "":
+			return NewSendGridEmailService(cfg), nil
+		case cfg.SMTPHost != "":
+			return NewSMTPEmailService(cfg, clk), nil
+		}
+		return NewMockEmailService(), nil
+	case "sendgrid":
+		if cfg.SendGridAPIKey == "" {
+			return nil, fmt.Errorf("sendgrid email provider requires SENDGRID_API_KEY")
+		}
+		return NewSendGridEmailService(cfg), nil
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("smtp email provider requires SMTP_HOST")
+		}
+		return NewSMTPEmailService(cfg, clk), nil
+	case "mock":
+		return NewMockEmailService(), nil
+	default:
+		return nil, fmt.Errorf("unknown email provider: %s", cfg.EmailProvider)
+	}
+}
+
+// NewLanguageModel returns the NLP backend selected by cfg.NLPProvider.
+// An empty provider picks Gemini when an API key is configured and the
+// mock implementation otherwise.
+func NewLanguageModel(cfg *config.Config) (LanguageModel, error) {
+	switch cfg.NLPProvider {
+	case "":
+		if cfg.GeminiAPIKey == "" {
+			return NewMockLanguageModel(), nil
+		}
+		return NewGeminiService(cfg), nil
+	case "gemini":
+		if cfg.GeminiAPIKey == "" {
+			return nil, fmt.Errorf("gemini NLP provider requires GEMINI_API_KEY")
+		}
+		return NewGeminiService(cfg), nil
+	case "mock":
+		return NewMockLanguageModel(), nil
+	default:
+		return nil, fmt.Errorf("unknown NLP provider: %s", cfg.NLPProvider)
+	}
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/config"
+)
+
+// smtpTimeout bounds a whole SMTP session, from connecting to QUIT.
+const smtpTimeout = 30 * time.Second
+
+// SMTPEmailService relays email through an SMTP server, upgrading the
+// connection with STARTTLS or connecting over implicit TLS as configured.
+type SMTPEmailService struct {
+	config *config.Config
+	clock  clock.Clock
+	// tlsConfig verifies the server certificate; tests trust their own
+	tlsConfig *tls.Config
+}
+
+func NewSMTPEmailService(cfg *config.Config, clk clock.Clock) *SMTPEmailService {
+	return &SMTPEmailService{
+		config:    cfg,
+		clock:     clk,
+		tlsConfig: &tls.Config{ServerName: cfg.SMTPHost},
+	}
+}
+
+func (e *SMTPEmailService) Send(message Message) error {
+	r, err := message.validate()
+	if err != nil {
+		return err
+	}
+	from := mail.Address{Name: e.config.FromName, Address: e.config.FromEmail}
+	data, err := buildMessage(from, &message, r, e.clock.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := e.send(from.Address, r.all(), data); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	fmt.Printf("Successfully sent email to: %s\nSubject: %s\n", strings.Join(r.all(), ", "), message.Subject)
+	return nil
+}
+
+// send delivers message to recipients in one SMTP session.
+func (e *SMTPEmailService) send(from string, recipients []string, message []byte) error {
+	addr := net.JoinHostPort(e.config.SMTPHost, strconv.Itoa(e.config.SMTPPort))
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	var conn net.Conn
+	var err error
+	if e.config.SMTPSecurity == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, e.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %v", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, e.config.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if e.config.SMTPSecurity == "starttls" {
+		// Refuse to go on in plain text when the server can't upgrade
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(e.tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS failed: %v", err)
+		}
+	}
+
+	if e.config.SMTPUsername != "" {
+		var auth smtp.Auth
+		if strings.EqualFold(e.config.SMTPAuth, "login") {
+			auth = &loginAuth{username: e.config.SMTPUsername, password: e.config.SMTPPassword, host: e.config.SMTPHost}
+		} else {
+			auth = smtp.PlainAuth("", e.config.SMTPUsername, e.config.SMTPPassword, e.config.SMTPHost)
+		}
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("authentication failed: %v", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return permanent(err)
+	}
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return permanent(fmt.Errorf("recipient %s rejected: %w", recipient, err))
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return permanent(err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return permanent(err)
+	}
+	return client.Quit()
+}
+
+// permanent marks err as ErrRejected when it is a permanent (5xx) SMTP
+// reply, as opposed to a temporary (4xx) one that is worth retrying.
+func permanent(err error) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	return err
+}
+
+// loginAuth implements the LOGIN mechanism, which some servers such as
+// Office 365 offer instead of PLAIN. Like smtp.PlainAuth, it only sends
+// credentials over TLS or to localhost.
+type loginAuth struct {
+	username, password, host string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge %q", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
@@ -0,0 +1,337 @@
+package api
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/config"
+)
+
+// smtpServer is an SMTP stand-in that accepts mail for anyone after the
+// client authenticates as alice/secret, recording each message it receives.
+type smtpServer struct {
+	addr      string
+	tlsConfig *tls.Config
+	// implicitTLS makes the server speak TLS from the start instead of
+	// offering STARTTLS
+	implicitTLS bool
+
+	mu       sync.Mutex
+	messages []smtpMessage
+}
+
+type smtpMessage struct {
+	from       string
+	recipients []string
+	mechanism  string
+	tls        bool
+	data       string
+}
+
+func newSMTPServer(t *testing.T, implicitTLS bool) (*smtpServer, *tls.Config) {
+	t.Helper()
+
+	// Borrow the self-signed certificate of a TLS test server
+	ts := httptest.NewTLSServer(nil)
+	t.Cleanup(ts.Close)
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &smtpServer{
+		addr:        listener.Addr().String(),
+		tlsConfig:   &tls.Config{Certificates: ts.TLS.Certificates},
+		implicitTLS: implicitTLS,
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s, &tls.Config{ServerName: "127.0.0.1", RootCAs: roots}
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	msg := smtpMessage{}
+	if s.implicitTLS {
+		conn = tls.Server(conn, s.tlsConfig)
+		msg.tls = true
+	}
+	r, w := bufio.NewReader(conn), conn
+	reply := func(line string) { io.WriteString(w, line+"\r\n") }
+	read := func() string {
+		line, _ := r.ReadString('\n')
+		return strings.TrimRight(line, "\r\n")
+	}
+
+	authenticated := false
+	reply("220 mail.example.com ESMTP")
+	for {
+		line := read()
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case verb == "EHLO":
+			reply("250-mail.example.com")
+			if !msg.tls {
+				reply("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN LOGIN")
+		case verb == "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			r, w = bufio.NewReader(tlsConn), tlsConn
+			msg.tls = true
+		case strings.HasPrefix(line, "AUTH PLAIN "):
+			credentials, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, "AUTH PLAIN "))
+			authenticated = string(credentials) == "\x00alice\x00secret"
+			msg.mechanism = "PLAIN"
+		case line == "AUTH LOGIN":
+			reply("334 " + base64.StdEncoding.EncodeToString([]byte("Username:")))
+			username, _ := base64.StdEncoding.DecodeString(read())
+			reply("334 " + base64.StdEncoding.EncodeToString([]byte("Password:")))
+			password, _ := base64.StdEncoding.DecodeString(read())
+			authenticated = string(username) == "alice" && string(password) == "secret"
+			msg.mechanism = "LOGIN"
+		case verb == "MAIL":
+			msg.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+			reply("250 ok")
+		case verb == "RCPT":
+			msg.recipients = append(msg.recipients, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			reply("250 ok")
+		case verb == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for line := read(); line != "."; line = read() {
+				data.WriteString(strings.TrimPrefix(line, ".") + "\r\n")
+			}
+			msg.data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			reply("250 queued")
+		case verb == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 unknown command")
+			continue
+		}
+
+		if verb == "AUTH" {
+			if !authenticated {
+				reply("535 authentication failed")
+				continue
+			}
+			reply("235 authenticated")
+		}
+	}
+}
+
+func (s *smtpServer) received() []smtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]smtpMessage(nil), s.messages...)
+}
+
+func (s *smtpServer) config(security, auth, password string) *config.Config {
+	host, port, _ := net.SplitHostPort(s.addr)
+	portNumber, _ := strconv.Atoi(port)
+	return &config.Config{
+		FromEmail:    "agent@example.com",
+		FromName:     "Agent Zoë",
+		SMTPHost:     host,
+		SMTPPort:     portNumber,
+		SMTPUsername: "alice",
+		SMTPPassword: password,
+		SMTPSecurity: security,
+		SMTPAuth:     auth,
+	}
+}
+
+func TestSMTPEmailService(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name        string
+		implicitTLS bool
+		security    string
+		auth        string
+		mechanism   string
+	}{
+		{name: "STARTTLS with PLAIN", security: "starttls", auth: "plain", mechanism: "PLAIN"},
+		{name: "implicit TLS with LOGIN", implicitTLS: true, security: "tls", auth: "login", mechanism: "LOGIN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, tlsConfig := newSMTPServer(t, tt.implicitTLS)
+			sender := NewSMTPEmailService(server.config(tt.security, tt.auth, "secret"), clk)
+			sender.tlsConfig = tlsConfig
+
+			message := Message{To: []string{"bob@example.com"}, BCC: []string{"carol@example.com"}, Subject: "Standup moved", Text: "See you at 10."}
+			if err := sender.Send(message); err != nil {
+				t.Fatalf("Send failed: %v", err)
+			}
+			messages := server.received()
+			if len(messages) != 1 {
+				t.Fatalf("server received %d messages, want 1", len(messages))
+			}
+			msg := messages[0]
+			if !msg.tls || msg.mechanism != tt.mechanism || msg.from != "agent@example.com" || strings.Join(msg.recipients, ",") != "bob@example.com,carol@example.com" ||
+				!strings.Contains(msg.data, "Subject: Standup moved\r\n") {
+				t.Errorf("got tls %v, auth %q, from %q to %v", msg.tls, msg.mechanism, msg.from, msg.recipients)
+			}
+
+			sender = NewSMTPEmailService(server.config(tt.security, tt.auth, "wrong"), clk)
+			sender.tlsConfig = tlsConfig
+			if err := sender.Send(message); err == nil {
+				t.Error("Send with a wrong password succeeded")
+			}
+		})
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	date := time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC)
+	text := "Hi Zoë,\n\n" + strings.TrimSpace(strings.Repeat("the agenda is attached ", 6)) + "\n"
+	html := `<p style="color: #333">Hi Zoë, the <b>agenda</b> is attached.</p>`
+	invite := []byte("BEGIN:VCALENDAR\r\n" + strings.Repeat("X", 200) + "\r\nEND:VCALENDAR\r\n")
+
+	message := &Message{
+		To:          []string{"Bob <bob@example.com>", "carol@example.com"},
+		CC:          []string{"dave@example.com"},
+		BCC:         []string{"secret@example.com"},
+		ReplyTo:     "Alice <alice@example.com>",
+		Subject:     "Réunion: planning",
+		Text:        text,
+		HTML:        html,
+		Attachments: []Attachment{{Filename: "invite.ics", ContentType: "text/calendar; charset=utf-8; method=REQUEST", Content: invite}},
+		Headers:     map[string]string{"in-reply-to": "<abc@example.com>"},
+	}
+	r, err := message.validate()
+	if err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+	if got := strings.Join(r.all(), ","); got != "bob@example.com,carol@example.com,dave@example.com,secret@example.com" {
+		t.Errorf("got envelope recipients %s", got)
+	}
+
+	data, err := buildMessage(mail.Address{Name: "Agent Zoë", Address: "agent@example.com"}, message, r, date)
+	if err != nil {
+		t.Fatalf("buildMessage failed: %v", err)
+	}
+	for _, line := range strings.Split(string(data), "\r\n") {
+		if len(line) > 78 {
+			t.Errorf("line longer than 78 characters: %q", line)
+		}
+	}
+	if strings.Contains(string(data), "secret@example.com") {
+		t.Error("BCC recipient appears in the message")
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("message does not parse: %v", err)
+	}
+	decoder := new(mime.WordDecoder)
+	subject, _ := decoder.DecodeHeader(msg.Header.Get("Subject"))
+	from, _ := msg.Header.AddressList("From")
+	if subject != "Réunion: planning" || len(from) != 1 || from[0].Name != "Agent Zoë" {
+		t.Errorf("got subject %q from %v", subject, from)
+	}
+	to, _ := msg.Header.AddressList("To")
+	cc, _ := msg.Header.AddressList("Cc")
+	replyTo, _ := msg.Header.AddressList("Reply-To")
+	if len(to) != 2 || to[0].Name != "Bob" || len(cc) != 1 || len(replyTo) != 1 || replyTo[0].Address != "alice@example.com" {
+		t.Errorf("got to %v, cc %v, reply-to %v", to, cc, replyTo)
+	}
+	if msg.Header.Get("In-Reply-To") != "<abc@example.com>" {
+		t.Errorf("got In-Reply-To %q", msg.Header.Get("In-Reply-To"))
+	}
+	if sent, _ := msg.Header.Date(); !sent.Equal(date) || !strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("got date %s and message ID %s", sent, msg.Header.Get("Message-ID"))
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if mediaType != "multipart/mixed" {
+		t.Fatalf("got content type %s, want multipart/mixed", mediaType)
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+
+	body, err := parts.NextPart()
+	if err != nil {
+		t.Fatalf("missing body: %v", err)
+	}
+	mediaType, params, _ = mime.ParseMediaType(body.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("got body type %s, want multipart/alternative", mediaType)
+	}
+	alternatives := multipart.NewReader(body, params["boundary"])
+	for _, want := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		// The reader decodes quoted-printable parts
+		part, err := alternatives.NextPart()
+		if err != nil {
+			t.Fatalf("missing %s part: %v", want.contentType, err)
+		}
+		content, _ := io.ReadAll(part)
+		if part.Header.Get("Content-Type") != want.contentType || strings.ReplaceAll(string(content), "\r\n", "\n") != want.content {
+			t.Errorf("got %s part %q, want %q", part.Header.Get("Content-Type"), content, want.content)
+		}
+	}
+
+	attachment, err := parts.NextPart()
+	if err != nil {
+		t.Fatalf("missing attachment: %v", err)
+	}
+	content, _ := io.ReadAll(base64.NewDecoder(base64.StdEncoding, attachment))
+	if attachment.FileName() != "invite.ics" || string(content) != string(invite) ||
+		attachment.Header.Get("Content-Type") != "text/calendar; charset=utf-8; method=REQUEST" {
+		t.Errorf("got attachment %s (%s) with %d bytes", attachment.FileName(), attachment.Header.Get("Content-Type"), len(content))
+	}
+}
+
+func TestMessageValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		message Message
+	}{
+		{"no recipients", Message{Subject: "Hi", Text: "Hello"}},
+		{"no body", Message{To: []string{"bob@example.com"}, Subject: "Hi"}},
+		{"invalid address", Message{To: []string{"bob@"}, Text: "Hello"}},
+		{"reserved header", Message{To: []string{"bob@example.com"}, Text: "Hello", Headers: map[string]string{"bcc": "eve@example.com"}}},
+		{"header injection", Message{To: []string{"bob@example.com"}, Text: "Hello", Headers: map[string]string{"X-Note": "a\r\nBcc: eve@example.com"}}},
+	}
+	for _, tt := range tests {
+		if _, err := tt.message.validate(); err == nil {
+			t.Errorf("%s: validate succeeded", tt.name)
+		}
+	}
+}
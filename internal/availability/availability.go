@@ -0,0 +1,126 @@
+// Package availability finds open meeting slots around busy calendar time.
+package availability
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/api"
+)
+
+const (
+	// SlotStep is the granularity of suggested start times.
+	SlotStep = 30 * time.Minute
+	// SearchHorizon is how far ahead open slots are looked for.
+	SearchHorizon = 14 * 24 * time.Hour
+)
+
+// Slot is a candidate meeting time. Score is set by Rank; higher is better.
+type Slot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Score int       `json:"score,omitempty"`
+}
+
+// WorkingHours is the part of each week in which meetings may be suggested.
+type WorkingHours struct {
+	// Start and End are offsets from midnight.
+	Start, End time.Duration
+	Days       [7]bool
+	Location   *time.Location
+}
+
+// ParseWorkingHours parses a "09:00-17:00" range on the given weekdays
+// (0 is Sunday) in loc.
+func ParseWorkingHours(spec string, days []int, loc *time.Location) (WorkingHours, error) {
+	w := WorkingHours{Location: loc}
+
+	parts := strings.Split(spec, "-")
+	if len(parts) != 2 {
+		return w, fmt.Errorf("working hours must look like 09:00-17:00, got %q", spec)
+	}
+
+	var err error
+	if w.Start, err = parseClock(parts[0]); err != nil {
+		return w, err
+	}
+	if w.End, err = parseClock(parts[1]); err != nil {
+		return w, err
+	}
+	if w.End <= w.Start {
+		return w, fmt.Errorf("working hours %q end before they start", spec)
+	}
+
+	for _, d := range days {
+		if d < 0 || d > 7 {
+			return w, fmt.Errorf("invalid working day %d", d)
+		}
+		w.Days[d%7] = true
+	}
+	return w, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	hm := strings.Split(strings.TrimSpace(s), ":")
+	if len(hm) != 2 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	hour, herr := strconv.Atoi(hm[0])
+	minute, merr := strconv.Atoi(hm[1])
+	if herr != nil || merr != nil || hour < 0 || hour > 24 || minute < 0 || minute > 59 || hour*60+minute > 24*60 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
+
+// Contains reports whether [start, end) lies within a single working day.
+func (w WorkingHours) Contains(start, end time.Time) bool {
+	dayStart, dayEnd, ok := w.day(start)
+	return ok && !start.Before(dayStart) && !end.After(dayEnd)
+}
+
+// day returns the working period of the day containing t, if it is a
+// working day.
+func (w WorkingHours) day(t time.Time) (time.Time, time.Time, bool) {
+	t = t.In(w.Location)
+	if !w.Days[t.Weekday()] {
+		return time.Time{}, time.Time{}, false
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, w.Location)
+	return at(midnight, w.Start), at(midnight, w.End), true
+}
+
+// at returns the wall-clock time offset from midnight, so working hours stay
+// put across daylight saving changes.
+func at(midnight time.Time, offset time.Duration) time.Time {
+	return time.Date(midnight.Year(), midnight.Month(), midnight.Day(), 0, int(offset.Minutes()), 0, 0, midnight.Location())
+}
+
+// Overlapping returns the busy periods that intersect [start, end).
+func Overlapping(busy []api.BusyPeriod, start, end time.Time) []api.BusyPeriod {
+	var overlapping []api.BusyPeriod
+	for _, period := range busy {
+		if period.Start.Before(end) && period.End.After(start) {
+			overlapping = append(overlapping, period)
+		}
+	}
+	return overlapping
+}
+
+// NextSlots returns up to n slots of the given duration that start at or
+// after from, fall within working hours and overlap none of the busy
+// periods, earliest first. Start times are aligned to SlotStep.
+func NextSlots(busy []api.BusyPeriod, hours WorkingHours, from time.Time, duration time.Duration, n int) []Slot {
+	slots := Candidates([]Participant{{Hours: hours, Busy: busy}}, Search{
+		From:     from,
+		To:       from.Add(SearchHorizon),
+		Duration: duration,
+		Location: hours.Location,
+	})
+	if len(slots) > n {
+		slots = slots[:n]
+	}
+	return slots
+}
@@ -0,0 +1,122 @@
+package availability
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/api"
+)
+
+func TestNextSlots(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	hours, err := ParseWorkingHours("09:00-17:00", []int{1, 2, 3, 4, 5}, loc)
+	if err != nil {
+		t.Fatalf("failed to parse working hours: %v", err)
+	}
+
+	day := func(d, h, m int) time.Time {
+		return time.Date(2026, time.October, d, h, m, 0, 0, loc)
+	}
+
+	// Friday afternoon is booked until 16:00
+	busy := []api.BusyPeriod{
+		{Start: day(16, 13, 0), End: day(16, 14, 15)},
+		{Start: day(16, 14, 0), End: day(16, 16, 0)},
+	}
+
+	tests := []struct {
+		name     string
+		from     time.Time
+		duration time.Duration
+		want     []time.Time
+	}{
+		{"after busy block", day(16, 13, 0), 30 * time.Minute, []time.Time{day(16, 16, 0), day(16, 16, 30), day(19, 9, 0)}},
+		{"skips the weekend", day(16, 16, 30), time.Hour, []time.Time{day(19, 9, 0), day(19, 9, 30), day(19, 10, 0)}},
+		{"aligns to the step", day(16, 10, 10), 30 * time.Minute, []time.Time{day(16, 10, 30), day(16, 11, 0), day(16, 11, 30)}},
+		{"before working hours", day(16, 6, 0), 4 * time.Hour, []time.Time{day(16, 9, 0), day(19, 9, 0), day(19, 9, 30)}},
+	}
+
+	for _, tt := range tests {
+		slots := NextSlots(busy, hours, tt.from, tt.duration, 3)
+		if len(slots) != len(tt.want) {
+			t.Errorf("%s: got %d slots, want %d", tt.name, len(slots), len(tt.want))
+			continue
+		}
+		for i, slot := range slots {
+			if !slot.Start.Equal(tt.want[i]) || slot.End.Sub(slot.Start) != tt.duration {
+				t.Errorf("%s: slot %d is %s-%s, want start %s", tt.name, i, slot.Start, slot.End, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestParseWorkingHoursRejectsInvalidRanges(t *testing.T) {
+	for _, spec := range []string{"", "9-5", "17:00-09:00", "09:00-25:00"} {
+		if _, err := ParseWorkingHours(spec, []int{1}, time.UTC); err == nil {
+			t.Errorf("ParseWorkingHours(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestFindSlotsAcrossTimeZones(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+
+	weekdays := []int{1, 2, 3, 4, 5}
+	nyHours, _ := ParseWorkingHours("09:00-17:00", weekdays, ny)
+	berlinHours, _ := ParseWorkingHours("09:00-17:00", weekdays, berlin)
+
+	day := func(d, h, m int) time.Time {
+		return time.Date(2026, time.October, d, h, m, 0, 0, ny)
+	}
+
+	// New York 09:00-11:00 overlaps Berlin 15:00-17:00. Berlin is busy until
+	// 09:30 New York time and the organizer from 10:00.
+	participants := []Participant{
+		{Email: "me@example.com", Hours: nyHours, Busy: []api.BusyPeriod{{Start: day(14, 10, 0), End: day(14, 11, 0)}}},
+		{Email: "anna@example.de", Hours: berlinHours, Busy: []api.BusyPeriod{{Start: day(14, 8, 0), End: day(14, 9, 30)}}},
+	}
+	search := Search{From: day(14, 8, 0), To: day(16, 0, 0), Duration: 30 * time.Minute, Location: ny}
+
+	candidates := Candidates(participants, search)
+	var starts []string
+	for _, slot := range candidates {
+		starts = append(starts, slot.Start.In(ny).Format("Jan 2 15:04"))
+	}
+	want := "Oct 14 09:30, Oct 15 09:00, Oct 15 09:30, Oct 15 10:00, Oct 15 10:30"
+	if got := strings.Join(starts, ", "); got != want {
+		t.Errorf("candidates %s, want %s", got, want)
+	}
+
+	// A ten minute buffer rules out the slot right after Berlin's meeting
+	search.Buffer = 10 * time.Minute
+	if slots := Candidates(participants, search); len(slots) != 4 || !slots[0].Start.Equal(day(15, 9, 0)) {
+		t.Errorf("buffered candidates start %v, want four from Oct 15 09:00", slots)
+	}
+	search.Buffer = 0
+
+	// Every slot is at the edge of someone's day; today's beats tomorrow's
+	best := FindSlots(participants, search, 2)
+	if len(best) != 2 || !best[0].Start.Equal(day(14, 9, 30)) || best[0].Score <= best[1].Score {
+		t.Errorf("best slots %v, want Oct 14 09:30 first", best)
+	}
+
+	// With longer hours in Berlin, 10:00 is clear of both days' edges and
+	// ranks first
+	participants[1].Hours, _ = ParseWorkingHours("08:00-18:00", weekdays, berlin)
+	search.From = day(15, 0, 0)
+	best = FindSlots(participants, search, 1)
+	if len(best) != 1 || !best[0].Start.Equal(day(15, 10, 0)) {
+		t.Errorf("best slot %v, want Oct 15 10:00", best)
+	}
+}
@@ -0,0 +1,113 @@
+package availability
+
+import (
+	"sort"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/api"
+)
+
+const (
+	// edgeOfDay is how close to the start or end of someone's working day a
+	// slot has to be to count as inconvenient for them.
+	edgeOfDay = time.Hour
+	// Ranking weights, in points.
+	dayPenalty  = 10
+	edgePenalty = 4
+)
+
+// Participant is one person whose calendar constrains a meeting.
+type Participant struct {
+	Email string
+	Hours WorkingHours
+	Busy  []api.BusyPeriod
+}
+
+// Search describes the meeting to find slots for.
+type Search struct {
+	From, To time.Time
+	Duration time.Duration
+	// Buffer is free time every participant keeps before and after the
+	// meeting.
+	Buffer time.Duration
+	// Location aligns candidate start times and counts days for ranking.
+	Location *time.Location
+}
+
+// Candidates returns every slot in the search window that falls within all
+// participants' working hours and leaves each of them Buffer of free time
+// around it, earliest first. Start times are aligned to SlotStep in the
+// search location.
+func Candidates(participants []Participant, search Search) []Slot {
+	var slots []Slot
+	for start := align(search.From.In(search.Location)); !start.Add(search.Duration).After(search.To); start = start.Add(SlotStep) {
+		end := start.Add(search.Duration)
+		if fits(participants, start, end, search.Buffer) {
+			slots = append(slots, Slot{Start: start, End: end})
+		}
+	}
+	return slots
+}
+
+func fits(participants []Participant, start, end time.Time, buffer time.Duration) bool {
+	for _, p := range participants {
+		if !p.Hours.Contains(start, end) {
+			return false
+		}
+		if len(Overlapping(p.Busy, start.Add(-buffer), end.Add(buffer))) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// align rounds t up to the next multiple of SlotStep after midnight.
+func align(t time.Time) time.Time {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+	if rem := offset % SlotStep; rem != 0 {
+		offset += SlotStep - rem
+	}
+	return midnight.Add(offset)
+}
+
+// Rank scores slots and orders them best first. Sooner days score higher,
+// and slots that fall in the first or last hour of a participant's working
+// day lose points for each participant affected. Ties keep the earlier slot.
+func Rank(slots []Slot, participants []Participant, search Search) []Slot {
+	ranked := append([]Slot(nil), slots...)
+	from := search.From.In(search.Location)
+	firstDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, search.Location)
+
+	for i, slot := range ranked {
+		start := slot.Start.In(search.Location)
+		day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, search.Location)
+		days := int(day.Sub(firstDay).Hours()+12) / 24
+
+		score := 100 - dayPenalty*days
+		for _, p := range participants {
+			dayStart, dayEnd, ok := p.Hours.day(slot.Start)
+			if !ok {
+				continue
+			}
+			if slot.Start.Sub(dayStart) < edgeOfDay || dayEnd.Sub(slot.End) < edgeOfDay {
+				score -= edgePenalty
+			}
+		}
+		ranked[i].Score = score
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	return ranked
+}
+
+// FindSlots returns the n best ranked slots for the participants.
+func FindSlots(participants []Participant, search Search, n int) []Slot {
+	slots := Rank(Candidates(participants, search), participants, search)
+	if len(slots) > n {
+		slots = slots[:n]
+	}
+	return slots
+}
@@ -1,68 +1,434 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	// API Keys
+	// GoogleCalendarAPIKey is a Google access token, used as is when neither
+	// an OAuth client nor a service account is configured.
 	GoogleCalendarAPIKey string
 	SendGridAPIKey       string
 	GeminiAPIKey         string
 
+	// Google OAuth
+	// With a client ID, the user authorizes the agent at
+	// /oauth/google/start and the refresh token is stored in DataDir,
+	// encrypted with TokenEncryptionKey. A service account key file takes
+	// precedence and may impersonate a user of a Workspace domain.
+	GoogleClientID           string
+	GoogleClientSecret       string
+	GoogleRedirectURL        string
+	GoogleAuthURL            string
+	GoogleTokenURL           string
+	GoogleServiceAccountFile string
+	GoogleImpersonateUser    string
+	TokenEncryptionKey       string
+
+	// Provider Selection (empty selects by API key availability)
+	CalendarProvider string
+	EmailProvider    string
+	NLPProvider      string
+
 	// Server Configuration
 	ServerPort string
 	LogLevel   string
+	DataDir    string
+
+	// ShutdownTimeoutSeconds bounds how long shutdown waits for in-flight
+	// requests, reminders and jobs.
+	ShutdownTimeoutSeconds int
+
+	// Job Queue Configuration
+	JobWorkers   int
+	JobQueueSize int
 
 	// API URLs and Endpoints
 	GoogleCalendarURL string
 	SendGridURL       string
 	GeminiURL         string
+	GeminiModel       string
 
 	// Email Configuration
 	FromEmail string
 	FromName  string
 	UserEmail string // User's email for receiving notifications
+	// SMTP relays email through a mail server instead of SendGrid.
+	// SMTPSecurity is "starttls", "tls" for implicit TLS or "none", and
+	// SMTPAuth is "plain" or "login".
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPSecurity string
+	SMTPAuth     string
+	// SendInvites emails attendees of scheduled meetings an .ics invite.
+	SendInvites bool
+	// Outgoing email is queued in an outbox and retried with backoff
+	// starting at OutboxRetrySeconds, up to OutboxMaxAttempts times.
+	OutboxMaxAttempts  int
+	OutboxRetrySeconds int
+	// TemplatesDir holds templates overriding the built-in emails, and
+	// Locale is the language they are written in, such as "en" or "es".
+	TemplatesDir string
+	Locale       string
+	// Tasks can be emailed to the agent by the addresses or domains in
+	// InboundAllowedSenders, which default to UserEmail. Emails arrive on
+	// the inbound webhook, enabled by InboundWebhookToken, or are polled
+	// from an IMAP mailbox every IMAPPollSeconds. IMAPSecurity is "tls"
+	// for implicit TLS, "starttls" or "none". Emails must pass DMARC or
+	// carry a DKIM signature of their From domain, as reported by the
+	// Authentication-Results header of InboundAuthServ when it is set,
+	// unless InboundAllowUnauthenticated accepts those never checked.
+	InboundAllowedSenders       []string
+	InboundWebhookToken         string
+	InboundAuthServ             string
+	InboundAllowUnauthenticated bool
+	IMAPHost                    string
+	IMAPPort                    int
+	IMAPUsername                string
+	IMAPPassword                string
+	IMAPSecurity                string
+	IMAPMailbox                 string
+	IMAPPollSeconds             int
 
 	// Calendar Configuration
 	CalendarID string
 	TimeZone   string
+	// location caches TimeZone once Load has resolved it.
+	location *time.Location
+	// Calendars lists every calendar the agent reads, defaulting to
+	// CalendarID alone. Meetings are booked on the default calendar unless
+	// a task names another writable one.
+	Calendars []Calendar
+	// CalDAVURL points at a CalDAV calendar, or at the collection holding a
+	// user's calendars, of which CalendarID is used.
+	CalDAVURL      string
+	CalDAVUsername string
+	CalDAVPassword string
+	// CalendarSyncSeconds is how often cached Google calendars are brought
+	// up to date with an incremental sync; 0 reads the API every time.
+	CalendarSyncSeconds int
+
+	// Availability Configuration
+	// WorkingHours ("09:00-17:00") and WorkingDays (0 is Sunday) bound the
+	// open slots suggested when a meeting conflicts.
+	WorkingHours    string
+	WorkingDays     []int
+	SlotSuggestions int
+	// MeetingBufferMinutes is the free time kept around suggested slots.
+	MeetingBufferMinutes int
+	// ConflictPolicy is "reject" to refuse booking over busy time or "warn"
+	// to book anyway and report the conflicts.
+	ConflictPolicy string
 
 	// Scheduler Configuration
 	DailyReminderTime      string
 	MeetingReminderMinutes int
+	// MeetingReminderOffsets lists every reminder to send before a meeting,
+	// in minutes. It defaults to MeetingReminderMinutes alone.
+	MeetingReminderOffsets []int
 }
 
 func Load() (*Config, error) {
-	return &Config{
+	cfg := &Config{
 		// API Keys
 		GoogleCalendarAPIKey: getEnv("GOOGLE_CALENDAR_API_KEY", ""),
 		SendGridAPIKey:       getEnv("SENDGRID_API_KEY", ""),
 		GeminiAPIKey:         getEnv("GEMINI_API_KEY", ""),
 
+		// Google OAuth
+		GoogleClientID:           getEnv("GOOGLE_CLIENT_ID", ""),
+		GoogleClientSecret:       getEnv("GOOGLE_CLIENT_SECRET", ""),
+		GoogleAuthURL:            getEnv("GOOGLE_AUTH_URL", "https://accounts.google.com/o/oauth2/v2/auth"),
+		GoogleTokenURL:           getEnv("GOOGLE_TOKEN_URL", "https://oauth2.googleapis.com/token"),
+		GoogleServiceAccountFile: getEnv("GOOGLE_SERVICE_ACCOUNT_FILE", ""),
+		GoogleImpersonateUser:    getEnv("GOOGLE_IMPERSONATE_USER", ""),
+		TokenEncryptionKey:       getEnv("TOKEN_ENCRYPTION_KEY", ""),
+
+		// Provider Selection
+		CalendarProvider: getEnv("CALENDAR_PROVIDER", ""),
+		EmailProvider:    getEnv("EMAIL_PROVIDER", ""),
+		NLPProvider:      getEnv("NLP_PROVIDER", ""),
+
 		// Server Configuration
 		ServerPort: getEnv("SERVER_PORT", "8080"),
 		LogLevel:   getEnv("LOG_LEVEL", "info"),
+		DataDir:    getEnv("DATA_DIR", "data"),
+
+		ShutdownTimeoutSeconds: getEnvAsInt("SHUTDOWN_TIMEOUT_SECONDS", 30),
+
+		// Job Queue Configuration
+		JobWorkers:   getEnvAsInt("JOB_WORKERS", 4),
+		JobQueueSize: getEnvAsInt("JOB_QUEUE_SIZE", 100),
 
 		// API URLs and Endpoints
 		GoogleCalendarURL: getEnv("GOOGLE_CALENDAR_URL", "https://www.googleapis.com/calendar/v3"),
 		SendGridURL:       getEnv("SENDGRID_URL", "https://api.sendgrid.com/v3"),
 		GeminiURL:         getEnv("GEMINI_URL", "https://generativelanguage.googleapis.com/v1beta"),
+		GeminiModel:       getEnv("GEMINI_MODEL", "gemini-1.5-flash"),
 
 		// Email Configuration
-		FromEmail: getEnv("FROM_EMAIL", "azmetefera07@gmail.com"),
-		FromName:  getEnv("FROM_NAME", "AI Assistant"),
-		UserEmail: getEnv("USER_EMAIL", "azmetefera07@gmail.com"),
+		FromEmail:   getEnv("FROM_EMAIL", "azmetefera07@gmail.com"),
+		FromName:    getEnv("FROM_NAME", "AI Assistant"),
+		UserEmail:   getEnv("USER_EMAIL", "azmetefera07@gmail.com"),
+		SendInvites: getEnvAsBool("SEND_INVITES", true),
+		Locale:      getEnv("LOCALE", "en"),
+
+		OutboxMaxAttempts:  getEnvAsInt("OUTBOX_MAX_ATTEMPTS", 8),
+		OutboxRetrySeconds: getEnvAsInt("OUTBOX_RETRY_SECONDS", 30),
+
+		// SMTP Configuration
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnvAsInt("SMTP_PORT", 587),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPSecurity: getEnv("SMTP_SECURITY", "starttls"),
+		SMTPAuth:     getEnv("SMTP_AUTH", "plain"),
+
+		// Inbound Email Configuration
+		InboundWebhookToken: getEnv("INBOUND_WEBHOOK_TOKEN", ""),
+		InboundAuthServ:     getEnv("INBOUND_AUTHSERV_ID", ""),
+		IMAPHost:            getEnv("IMAP_HOST", ""),
+		IMAPPort:            getEnvAsInt("IMAP_PORT", 993),
+		IMAPUsername:        getEnv("IMAP_USERNAME", ""),
+		IMAPPassword:        getEnv("IMAP_PASSWORD", ""),
+		IMAPSecurity:        getEnv("IMAP_SECURITY", "tls"),
+		IMAPMailbox:         getEnv("IMAP_MAILBOX", "INBOX"),
+		IMAPPollSeconds:     getEnvAsInt("IMAP_POLL_SECONDS", 60),
 
 		// Calendar Configuration
-		CalendarID: getEnv("CALENDAR_ID", "primary"),
-		TimeZone:   getEnv("TIMEZONE", "UTC"),
+		CalendarID:     getEnv("CALENDAR_ID", "primary"),
+		TimeZone:       getEnv("TIMEZONE", "UTC"),
+		CalDAVURL:      getEnv("CALDAV_URL", ""),
+		CalDAVUsername: getEnv("CALDAV_USERNAME", ""),
+		CalDAVPassword: getEnv("CALDAV_PASSWORD", ""),
+
+		CalendarSyncSeconds: getEnvAsInt("CALENDAR_SYNC_SECONDS", 300),
+
+		// Availability Configuration
+		WorkingHours:         getEnv("WORKING_HOURS", "09:00-17:00"),
+		WorkingDays:          getEnvAsIntList("WORKING_DAYS", []int{1, 2, 3, 4, 5}),
+		SlotSuggestions:      getEnvAsInt("SLOT_SUGGESTIONS", 3),
+		MeetingBufferMinutes: getEnvAsInt("MEETING_BUFFER_MINUTES", 0),
+		ConflictPolicy:       getEnv("CONFLICT_POLICY", "reject"),
 
 		// Scheduler Configuration
 		DailyReminderTime:      getEnv("DAILY_REMINDER_TIME", "09:00"),
 		MeetingReminderMinutes: getEnvAsInt("MEETING_REMINDER_MINUTES", 15),
-	}, nil
+	}
+
+	calendars, err := parseCalendars(getEnv("CALENDARS", ""), cfg.CalendarID)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Calendars = calendars
+
+	cfg.TemplatesDir = getEnv("TEMPLATES_DIR", filepath.Join(cfg.DataDir, "templates"))
+	cfg.GoogleRedirectURL = getEnv("GOOGLE_REDIRECT_URL", "http://localhost:"+cfg.ServerPort+"/oauth/google/callback")
+	cfg.MeetingReminderOffsets = getEnvAsIntList("MEETING_REMINDER_OFFSETS", []int{cfg.MeetingReminderMinutes})
+	cfg.InboundAllowedSenders = getEnvAsList("INBOUND_ALLOWED_SENDERS", []string{cfg.UserEmail})
+	cfg.InboundAllowUnauthenticated = getEnvAsBool("INBOUND_ALLOW_UNAUTHENTICATED", false)
+
+	location, err := time.LoadLocation(cfg.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone: %s", cfg.TimeZone)
+	}
+	cfg.location = location
+
+	if err := validateWorkingHours(cfg.WorkingHours, cfg.WorkingDays); err != nil {
+		return nil, err
+	}
+
+	switch cfg.ConflictPolicy {
+	case "reject", "warn":
+	default:
+		return nil, fmt.Errorf("unknown conflict policy: %s", cfg.ConflictPolicy)
+	}
+
+	switch cfg.SMTPSecurity {
+	case "starttls", "tls", "none":
+	default:
+		return nil, fmt.Errorf("unknown SMTP security: %s", cfg.SMTPSecurity)
+	}
+	switch cfg.SMTPAuth {
+	case "plain", "login":
+	default:
+		return nil, fmt.Errorf("unknown SMTP auth mechanism: %s", cfg.SMTPAuth)
+	}
+	switch cfg.IMAPSecurity {
+	case "tls", "starttls", "none":
+	default:
+		return nil, fmt.Errorf("unknown IMAP security: %s", cfg.IMAPSecurity)
+	}
+
+	return cfg, nil
+}
+
+// Calendar roles say how the agent uses a calendar.
+const (
+	// RoleDefault is the calendar meetings are booked on by default.
+	RoleDefault = "default"
+	// RoleWrite calendars can be chosen for booking.
+	RoleWrite = "write"
+	// RoleRead calendars are only read, for availability and summaries.
+	RoleRead = "read"
+)
+
+// Calendar is one of the calendars the agent works with. Name is how tasks
+// and the API refer to it and ID identifies it to the calendar provider.
+type Calendar struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+	Role string `json:"role"`
+}
+
+// DefaultCalendar returns the calendar meetings are booked on by default.
+func (c *Config) DefaultCalendar() Calendar {
+	for _, calendar := range c.Calendars {
+		if calendar.Role == RoleDefault {
+			return calendar
+		}
+	}
+	return Calendar{Name: c.CalendarID, ID: c.CalendarID, Role: RoleDefault}
+}
+
+// parseCalendars parses a comma separated list of calendars written as
+// [name=]id[:role], such as
+// "work=primary:default,family=family@group.calendar.google.com:read".
+// The name defaults to the ID and the role to write. Without a default
+// calendar, the first writable one becomes the default; an empty list
+// yields defaultID alone.
+func parseCalendars(value, defaultID string) ([]Calendar, error) {
+	if strings.TrimSpace(value) == "" {
+		return []Calendar{{Name: defaultID, ID: defaultID, Role: RoleDefault}}, nil
+	}
+
+	var calendars []Calendar
+	names := make(map[string]bool)
+	defaults := 0
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		calendar := Calendar{Role: RoleWrite}
+		if i := strings.LastIndex(entry, ":"); i >= 0 {
+			calendar.Role = strings.ToLower(strings.TrimSpace(entry[i+1:]))
+			entry = entry[:i]
+		}
+		calendar.ID = strings.TrimSpace(entry)
+		if i := strings.Index(entry, "="); i >= 0 {
+			calendar.Name = strings.TrimSpace(entry[:i])
+			calendar.ID = strings.TrimSpace(entry[i+1:])
+		}
+		if calendar.Name == "" {
+			calendar.Name = calendar.ID
+		}
+
+		switch {
+		case calendar.ID == "":
+			return nil, fmt.Errorf("calendar %q has no ID", calendar.Name)
+		case strings.Contains(calendar.Name, ":"):
+			return nil, fmt.Errorf("calendar name %q must not contain ':'", calendar.Name)
+		case names[strings.ToLower(calendar.Name)]:
+			return nil, fmt.Errorf("duplicate calendar name %q", calendar.Name)
+		}
+		switch calendar.Role {
+		case RoleDefault:
+			defaults++
+		case RoleWrite, RoleRead:
+		default:
+			return nil, fmt.Errorf("unknown role %q for calendar %s", calendar.Role, calendar.Name)
+		}
+
+		names[strings.ToLower(calendar.Name)] = true
+		calendars = append(calendars, calendar)
+	}
+
+	if defaults > 1 {
+		return nil, fmt.Errorf("only one calendar can be the default")
+	}
+	if defaults == 0 {
+		for i := range calendars {
+			if calendars[i].Role == RoleWrite {
+				calendars[i].Role = RoleDefault
+				defaults++
+				break
+			}
+		}
+	}
+	if defaults == 0 {
+		return nil, fmt.Errorf("CALENDARS needs a calendar that meetings can be booked on")
+	}
+	return calendars, nil
+}
+
+// Location returns the configured time zone, resolved once by Load.
+// Configs built without Load fall back to loading TimeZone, and to UTC
+// when it is empty or unknown.
+func (c *Config) Location() *time.Location {
+	if c.location != nil {
+		return c.location
+	}
+	if c.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// validateWorkingHours checks a "09:00-17:00" range and its weekdays (0 is
+// Sunday, 7 also counts as Sunday), so that a bad WORKING_HOURS fails at
+// startup rather than in every scheduling task.
+func validateWorkingHours(spec string, days []int) error {
+	parts := strings.Split(spec, "-")
+	if len(parts) != 2 {
+		return fmt.Errorf("working hours must look like 09:00-17:00, got %q", spec)
+	}
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return fmt.Errorf("invalid working hours %q: %v", spec, err)
+	}
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return fmt.Errorf("invalid working hours %q: %v", spec, err)
+	}
+	if end <= start {
+		return fmt.Errorf("working hours %q end before they start", spec)
+	}
+
+	for _, d := range days {
+		if d < 0 || d > 7 {
+			return fmt.Errorf("invalid working day %d", d)
+		}
+	}
+	return nil
+}
+
+// parseClock parses an HH:MM time of day into minutes after midnight;
+// 24:00 is allowed as the end of the day.
+func parseClock(s string) (int, error) {
+	hm := strings.Split(strings.TrimSpace(s), ":")
+	if len(hm) != 2 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	hour, herr := strconv.Atoi(hm[0])
+	minute, merr := strconv.Atoi(hm[1])
+	if herr != nil || merr != nil || hour < 0 || minute < 0 || minute > 59 || hour*60+minute > 24*60 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return hour*60 + minute, nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -80,3 +446,46 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsIntList parses a comma separated list of integers, ignoring
+// malformed entries.
+func getEnvAsIntList(key string, defaultValue []int) []int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var values []int
+	for _, part := range strings.Split(value, ",") {
+		if intValue, err := strconv.Atoi(strings.TrimSpace(part)); err == nil {
+			values = append(values, intValue)
+		}
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
+
+// getEnvAsList parses a comma separated list, ignoring empty entries.
+func getEnvAsList(key string, defaultValue []string) []string {
+	var values []string
+	for _, part := range strings.Split(os.Getenv(key), ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
@@ -0,0 +1,133 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	invalid := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"@reboot",
+	}
+	for _, spec := range invalid {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, loc)
+	}
+	chile, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	// Clocks in Chile skip from midnight to 01:00 on September 6 2026
+	santiago := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, chile)
+	}
+	// Wednesday, October 14 2026
+	wednesday := at(2026, time.October, 14, 17, 50)
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"range and step", "*/15 9-17 * * mon-fri", wednesday, at(2026, time.October, 15, 9, 0)},
+		{"step from a start", "5/20 * * * *", at(2026, time.October, 14, 10, 5), at(2026, time.October, 14, 10, 25)},
+		{"list", "0,30 8 * * *", at(2026, time.October, 14, 8, 0), at(2026, time.October, 14, 8, 30)},
+		{"month names", "0 0 1 jan,jul *", wednesday, at(2027, time.January, 1, 0, 0)},
+		{"sunday as 7", "0 0 * * 7", wednesday, at(2026, time.October, 18, 0, 0)},
+		{"seconds are ignored", "* * * * *", wednesday.Add(30 * time.Second), at(2026, time.October, 14, 17, 51)},
+		{"@weekly", "@weekly", wednesday, at(2026, time.October, 18, 0, 0)},
+		{"@monthly", "@monthly", wednesday, at(2026, time.November, 1, 0, 0)},
+		{"@hourly", "@hourly", wednesday, at(2026, time.October, 14, 18, 0)},
+		{"@yearly", "@yearly", wednesday, at(2027, time.January, 1, 0, 0)},
+		// With both day fields restricted, either one matching is enough
+		{"day of week or month", "0 9 13 * fri", wednesday, at(2026, time.October, 16, 9, 0)},
+		{"day of month or week", "0 9 15 * mon", wednesday, at(2026, time.October, 15, 9, 0)},
+		{"day of month alone", "0 9 13 * *", wednesday, at(2026, time.November, 13, 9, 0)},
+		// 02:30 doesn't exist on March 8 2026 and 01:30 happens twice on
+		// November 1 2026
+		{"spring forward", "30 2 * * *", at(2026, time.March, 7, 3, 0), at(2026, time.March, 9, 2, 30)},
+		{"fall back", "30 1 * * *", at(2026, time.October, 31, 12, 0), at(2026, time.November, 1, 1, 30)},
+		{"repeated hour fires once", "30 1 * * *", at(2026, time.November, 1, 1, 30), at(2026, time.November, 2, 1, 30)},
+		{"hourly across fall back", "0 * * * *", at(2026, time.November, 1, 0, 30), at(2026, time.November, 1, 1, 0)},
+		{"midnight gap", "0 12 * * sun", santiago(2026, time.September, 5, 13), santiago(2026, time.September, 6, 12)},
+		{"leap day within five years", "0 0 29 2 *", wednesday, at(2028, time.February, 29, 0, 0)},
+		{"never fires", "0 0 30 2 *", wednesday, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		schedule, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("%s: Parse(%q) failed: %v", tt.name, tt.spec, err)
+		}
+		if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%s: Next(%v) = %v, want %v", tt.name, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestPrevMirrorsNext(t *testing.T) {
+	specs := []string{"*/15 9-17 * * mon-fri", "30 1 * * *", "30 2 * * *", "0 * * * *", "*/20 0-3 * * *", "0 0 * * sun", "0 9 13 * fri", "@yearly"}
+	for _, zone := range []string{"America/New_York", "America/Santiago"} {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			t.Fatalf("failed to load location: %v", err)
+		}
+		// Spans a daylight saving change in both zones
+		start := time.Date(2026, time.August, 20, 0, 0, 0, 0, loc)
+		end := time.Date(2026, time.November, 10, 0, 0, 0, 0, loc)
+
+		for _, spec := range specs {
+			schedule, err := Parse(spec)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", spec, err)
+			}
+
+			var forward []time.Time
+			for next := schedule.Next(start); next.Before(end); next = schedule.Next(next) {
+				forward = append(forward, next)
+			}
+			var backward []time.Time
+			for prev := schedule.Prev(end); prev.After(start); prev = schedule.Prev(prev) {
+				backward = append([]time.Time{prev}, backward...)
+			}
+
+			if len(forward) != len(backward) {
+				t.Errorf("%s in %s: %d activations forward, %d backward", spec, zone, len(forward), len(backward))
+				continue
+			}
+			for i := range forward {
+				if !forward[i].Equal(backward[i]) {
+					t.Errorf("%s in %s: activation %d is %v forward, %v backward", spec, zone, i, forward[i], backward[i])
+					break
+				}
+			}
+		}
+	}
+
+	never, _ := Parse("0 0 30 2 *")
+	if prev := never.Prev(time.Date(2026, time.October, 14, 0, 0, 0, 0, time.UTC)); !prev.IsZero() {
+		t.Errorf("Prev of a schedule that never fires = %v", prev)
+	}
+}
@@ -0,0 +1,460 @@
+// Package ical reads and writes the VEVENTs of RFC 5545 iCalendar files.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/rrule"
+)
+
+// ProductID identifies the agent in the calendars it writes.
+const ProductID = "-//AI Agent Project//Executive Assistant//EN"
+
+// Methods of calendars sent by email, as defined by RFC 5546.
+const (
+	MethodPublish = "PUBLISH"
+	MethodRequest = "REQUEST"
+)
+
+const (
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+	dateLayout  = "20060102"
+	// lineLimit is the longest content line in octets, excluding CRLF.
+	lineLimit = 75
+)
+
+// Calendar is an iCalendar object holding events.
+type Calendar struct {
+	// Method is set on calendars sent by email, such as invitations.
+	Method string
+	// Organizer is the email address organizing every event in an
+	// invitation.
+	Organizer string
+	Events    []Event
+}
+
+// Event is a VEVENT. Recurring events are a series event with an RRule plus
+// any number of events overriding single occurrences, which share the
+// series' UID and set RecurrenceID.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	// Status is "confirmed", "tentative" or "cancelled".
+	Status    string
+	Start     time.Time
+	End       time.Time
+	Attendees []string
+	// AllDay events are written as dates. Start and End are then midnights
+	// and End is exclusive, so a one-day event ends the next day.
+	AllDay bool
+
+	// RRule is the recurrence rule of a series, without the "RRULE:"
+	// prefix, and ExDates the start times of its deleted occurrences.
+	RRule   string
+	ExDates []time.Time
+	// RecurrenceID is the original start time of the occurrence an event
+	// overrides.
+	RecurrenceID time.Time
+}
+
+// Encode writes c as an iCalendar file. stamp is the DTSTAMP of every event.
+func (c *Calendar) Encode(w io.Writer, stamp time.Time) error {
+	bw := bufio.NewWriter(w)
+	write := func(name, value string) {
+		writeLine(bw, name+":"+value)
+	}
+
+	write("BEGIN", "VCALENDAR")
+	write("VERSION", "2.0")
+	write("PRODID", ProductID)
+	write("CALSCALE", "GREGORIAN")
+	if c.Method != "" {
+		write("METHOD", c.Method)
+	}
+
+	for _, event := range c.Events {
+		write("BEGIN", "VEVENT")
+		write("UID", event.UID)
+		write("DTSTAMP", stamp.UTC().Format(utcLayout))
+		if !event.RecurrenceID.IsZero() {
+			writeLine(bw, formatTime("RECURRENCE-ID", event.RecurrenceID, event.AllDay))
+		}
+		writeLine(bw, formatTime("DTSTART", event.Start, event.AllDay))
+		writeLine(bw, formatTime("DTEND", event.End, event.AllDay))
+		write("SUMMARY", escape(event.Summary))
+		if event.Description != "" {
+			write("DESCRIPTION", escape(event.Description))
+		}
+		if event.Location != "" {
+			write("LOCATION", escape(event.Location))
+		}
+		if event.RRule != "" {
+			write("RRULE", event.RRule)
+		}
+		for _, exdate := range event.ExDates {
+			writeLine(bw, formatTime("EXDATE", exdate, event.AllDay))
+		}
+		if c.Organizer != "" {
+			write("ORGANIZER", "mailto:"+c.Organizer)
+		}
+		for _, attendee := range event.Attendees {
+			if c.Method == MethodRequest {
+				writeLine(bw, "ATTENDEE;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:"+attendee)
+			} else {
+				write("ATTENDEE", "mailto:"+attendee)
+			}
+		}
+		status := strings.ToUpper(event.Status)
+		if status == "" {
+			status = "CONFIRMED"
+		}
+		write("STATUS", status)
+		write("END", "VEVENT")
+	}
+
+	write("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// formatTime formats a DATE-TIME property, or a DATE property for all-day
+// events. Times in a named time zone keep it as TZID so that recurrences
+// follow its daylight saving changes; other times are written in UTC.
+func formatTime(name string, t time.Time, date bool) string {
+	if date {
+		return name + ";VALUE=DATE:" + t.Format(dateLayout)
+	}
+	if zone := t.Location().String(); zone != "UTC" && zone != "Local" {
+		return name + ";TZID=" + zone + ":" + t.Format(localLayout)
+	}
+	return name + ":" + t.UTC().Format(utcLayout)
+}
+
+// writeLine writes a content line, folding it into lines of at most
+// lineLimit octets without splitting UTF-8 sequences.
+func writeLine(w *bufio.Writer, line string) {
+	limit := lineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space
+		limit = lineLimit - 1
+	}
+	w.WriteString(line + "\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+var (
+	escaper   = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	unescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func unescape(s string) string {
+	return unescaper.Replace(s)
+}
+
+// property is a parsed content line.
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Decode reads the events of an iCalendar file. Times without a time zone,
+// and in time zones unknown to the system, are read in loc. All-day events
+// span whole days in loc.
+func Decode(r io.Reader, loc *time.Location) (*Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := &Calendar{}
+	var (
+		inCalendar bool
+		event      *Event
+		props      []property
+		// depth counts components nested in the VEVENT, such as VALARM
+		depth int
+		index int
+	)
+
+	for n, line := range lines {
+		prop, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VCALENDAR"):
+			inCalendar = true
+		case !inCalendar:
+			return nil, fmt.Errorf("line %d: content outside VCALENDAR", n+1)
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT") && event == nil:
+			event = &Event{}
+			props = nil
+			index++
+		case prop.name == "BEGIN" && event != nil:
+			depth++
+		case prop.name == "END" && event != nil && depth > 0:
+			depth--
+		case prop.name == "END" && strings.EqualFold(prop.value, "VEVENT") && event != nil:
+			if err := decodeEvent(event, props, loc); err != nil {
+				return nil, fmt.Errorf("event %d: %v", index, err)
+			}
+			calendar.Events = append(calendar.Events, *event)
+			event = nil
+		case prop.name == "END" && strings.EqualFold(prop.value, "VCALENDAR"):
+			if event != nil {
+				return nil, fmt.Errorf("event %d is not terminated", index)
+			}
+			return calendar, nil
+		case event != nil && depth == 0:
+			props = append(props, prop)
+		case event == nil && prop.name == "METHOD":
+			calendar.Method = strings.ToUpper(prop.value)
+		}
+	}
+
+	if !inCalendar {
+		return nil, fmt.Errorf("no VCALENDAR found")
+	}
+	return nil, fmt.Errorf("VCALENDAR is not terminated")
+}
+
+// decodeEvent fills event from the properties of a VEVENT.
+func decodeEvent(event *Event, props []property, loc *time.Location) error {
+	var (
+		duration time.Duration
+		allDay   bool
+		hasEnd   bool
+	)
+
+	for _, prop := range props {
+		switch prop.name {
+		case "UID":
+			event.UID = prop.value
+		case "SUMMARY":
+			event.Summary = unescape(prop.value)
+		case "DESCRIPTION":
+			event.Description = unescape(prop.value)
+		case "LOCATION":
+			event.Location = unescape(prop.value)
+		case "STATUS":
+			event.Status = strings.ToLower(prop.value)
+		case "DTSTART":
+			t, date, err := parseTime(prop, loc)
+			if err != nil {
+				return fmt.Errorf("invalid DTSTART: %v", err)
+			}
+			event.Start, allDay = t, date
+		case "DTEND":
+			t, _, err := parseTime(prop, loc)
+			if err != nil {
+				return fmt.Errorf("invalid DTEND: %v", err)
+			}
+			event.End, hasEnd = t, true
+		case "DURATION":
+			d, err := parseDuration(prop.value)
+			if err != nil {
+				return fmt.Errorf("invalid DURATION: %v", err)
+			}
+			duration = d
+		case "RRULE":
+			rule, err := rrule.Parse(prop.value)
+			if err != nil {
+				return fmt.Errorf("invalid RRULE: %v", err)
+			}
+			event.RRule = rule.String()
+		case "EXDATE":
+			for _, value := range strings.Split(prop.value, ",") {
+				t, _, err := parseTime(property{params: prop.params, value: value}, loc)
+				if err != nil {
+					return fmt.Errorf("invalid EXDATE: %v", err)
+				}
+				event.ExDates = append(event.ExDates, t)
+			}
+		case "RECURRENCE-ID":
+			t, _, err := parseTime(prop, loc)
+			if err != nil {
+				return fmt.Errorf("invalid RECURRENCE-ID: %v", err)
+			}
+			event.RecurrenceID = t
+		case "ATTENDEE":
+			if email := mailto(prop.value); email != "" {
+				event.Attendees = append(event.Attendees, email)
+			}
+		}
+	}
+
+	if event.Start.IsZero() {
+		return fmt.Errorf("missing DTSTART")
+	}
+	event.AllDay = allDay
+	switch {
+	case hasEnd:
+	case duration > 0:
+		event.End = event.Start.Add(duration)
+	case allDay:
+		event.End = event.Start.AddDate(0, 0, 1)
+	default:
+		event.End = event.Start
+	}
+	if event.End.Before(event.Start) {
+		return fmt.Errorf("event ends before it starts")
+	}
+	return nil
+}
+
+// unfold reads the content lines of r, joining folded lines.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %v", err)
+	}
+	return lines, nil
+}
+
+// parseLine splits a content line such as
+// "DTSTART;TZID=Europe/Berlin:20261019T090000" into its parts.
+func parseLine(line string) (property, error) {
+	prop := property{params: map[string]string{}}
+
+	// The value starts at the first colon outside a quoted parameter value
+	quoted := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return prop, fmt.Errorf("missing value in %q", line)
+	}
+	prop.value = line[colon+1:]
+
+	parts := strings.Split(line[:colon], ";")
+	prop.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) == 2 {
+			prop.params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return prop, nil
+}
+
+// parseTime parses a DATE-TIME or DATE value and reports whether it was a
+// date.
+func parseTime(prop property, loc *time.Location) (time.Time, bool, error) {
+	value := prop.value
+	if strings.EqualFold(prop.params["VALUE"], "DATE") || len(value) == len(dateLayout) {
+		t, err := time.ParseInLocation(dateLayout, value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(utcLayout, value)
+		return t, false, err
+	}
+
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if tz, err := time.LoadLocation(tzid); err == nil {
+			loc = tz
+		}
+	}
+	t, err := time.ParseInLocation(localLayout, value, loc)
+	return t, false, err
+}
+
+// parseDuration parses durations such as "PT1H30M" or "P1D".
+func parseDuration(value string) (time.Duration, error) {
+	s := strings.TrimPrefix(strings.ToUpper(value), "+")
+	if !strings.HasPrefix(s, "P") {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	s = s[1:]
+
+	var (
+		total  time.Duration
+		inTime bool
+		n      int
+		digits bool
+	)
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			n = n*10 + int(c-'0')
+			digits = true
+			continue
+		case c == 'T':
+			inTime = true
+			continue
+		}
+		if !digits {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+
+		var unit time.Duration
+		switch {
+		case c == 'W' && !inTime:
+			unit = 7 * 24 * time.Hour
+		case c == 'D' && !inTime:
+			unit = 24 * time.Hour
+		case c == 'H' && inTime:
+			unit = time.Hour
+		case c == 'M' && inTime:
+			unit = time.Minute
+		case c == 'S' && inTime:
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		total += time.Duration(n) * unit
+		n, digits = 0, false
+	}
+	if digits {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return total, nil
+}
+
+// mailto returns the email address of a "mailto:" URI.
+func mailto(value string) string {
+	if len(value) < 7 || !strings.EqualFold(value[:7], "mailto:") {
+		return ""
+	}
+	return strings.TrimSpace(value[7:])
+}
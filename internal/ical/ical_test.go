@@ -0,0 +1,179 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+
+	start := time.Date(2026, time.October, 19, 13, 0, 0, 0, loc)
+	events := []Event{
+		{
+			UID:         "mock-1@ai-agent",
+			Summary:     "Planning; Q4, budget",
+			Description: "Agenda:\n1. Numbers\n2. " + strings.Repeat("Hiring plans for the team ", 5),
+			Location:    "Room 4",
+			Status:      "confirmed",
+			Start:       start,
+			End:         start.Add(45 * time.Minute),
+			Attendees:   []string{"sarah@example.com", "bob@example.com"},
+			RRule:       "FREQ=WEEKLY;BYDAY=MO;COUNT=4",
+			ExDates:     []time.Time{start.AddDate(0, 0, 7)},
+		},
+		{
+			UID:          "mock-1@ai-agent",
+			Summary:      "Planning moved",
+			Start:        start.AddDate(0, 0, 15),
+			End:          start.AddDate(0, 0, 15).Add(time.Hour),
+			RecurrenceID: start.AddDate(0, 0, 14),
+		},
+		{
+			UID:     "mock-2@ai-agent",
+			Summary: "Offsite",
+			Start:   time.Date(2026, time.October, 22, 0, 0, 0, 0, loc),
+			End:     time.Date(2026, time.October, 24, 0, 0, 0, 0, loc),
+			AllDay:  true,
+		},
+	}
+
+	var buf bytes.Buffer
+	calendar := &Calendar{Method: MethodRequest, Organizer: "agent@example.com", Events: events}
+	if err := calendar.Encode(&buf, start); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	encoded := buf.String()
+	for _, line := range strings.Split(strings.TrimSuffix(encoded, "\r\n"), "\r\n") {
+		if len(line) > lineLimit {
+			t.Errorf("line longer than %d octets: %q", lineLimit, line)
+		}
+	}
+
+	decoded, err := Decode(&buf, time.UTC)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if decoded.Method != MethodRequest || len(decoded.Events) != 3 {
+		t.Fatalf("got method %q with %d events", decoded.Method, len(decoded.Events))
+	}
+
+	got, want := decoded.Events[0], events[0]
+	if got.UID != want.UID || got.Summary != want.Summary || got.Description != want.Description || got.Location != want.Location || got.Status != want.Status {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if !got.Start.Equal(want.Start) || !got.End.Equal(want.End) || got.RRule != want.RRule {
+		t.Errorf("got %s-%s %q, want %s-%s %q", got.Start, got.End, got.RRule, want.Start, want.End, want.RRule)
+	}
+	if got.Start.Location().String() != "Europe/Berlin" || len(got.ExDates) != 1 || !got.ExDates[0].Equal(want.ExDates[0]) {
+		t.Errorf("got start in %s with exdates %v", got.Start.Location(), got.ExDates)
+	}
+	if strings.Join(got.Attendees, ",") != "sarah@example.com,bob@example.com" {
+		t.Errorf("got attendees %v", got.Attendees)
+	}
+	if override := decoded.Events[1]; !override.RecurrenceID.Equal(events[1].RecurrenceID) || override.Summary != "Planning moved" {
+		t.Errorf("got override %+v", override)
+	}
+
+	// Dates are written as VALUE=DATE and read back in the given location
+	if !strings.Contains(encoded, "DTSTART;VALUE=DATE:20261022\r\n") || !strings.Contains(encoded, "DTEND;VALUE=DATE:20261024\r\n") {
+		t.Errorf("all-day event not encoded as dates:\n%s", encoded)
+	}
+	if offsite := decoded.Events[2]; !offsite.AllDay || !offsite.Start.Equal(time.Date(2026, time.October, 22, 0, 0, 0, 0, time.UTC)) ||
+		!offsite.End.Equal(time.Date(2026, time.October, 24, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got all-day offsite %+v", offsite)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/Berlin",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:1",
+		"SUMMARY:Berlin sync",
+		"DTSTART;TZID=Europe/Berlin:20261020T100000",
+		"DURATION:PT1H30M",
+		"ATTENDEE;CN=\"Anna: Berlin\":mailto:anna@example.com",
+		"BEGIN:VALARM",
+		"TRIGGER:-PT15M",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:2",
+		"SUMMARY:Offsite",
+		"DTSTART;VALUE=DATE:20261023",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:3",
+		"SUMMARY:Local lunch that has a long",
+		"  folded title",
+		"DTSTART:20261021T120000",
+		"DTEND:20261021T130000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:4",
+		"SUMMARY:Dropped",
+		"STATUS:CANCELLED",
+		"DTSTART:20261022T120000Z",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	calendar, err := Decode(strings.NewReader(data), loc)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if len(calendar.Events) != 4 || calendar.Events[3].Status != "cancelled" {
+		t.Fatalf("got %d events, want 3 and a cancelled one", len(calendar.Events))
+	}
+
+	berlin := calendar.Events[0]
+	if !berlin.Start.Equal(time.Date(2026, time.October, 20, 8, 0, 0, 0, time.UTC)) || berlin.End.Sub(berlin.Start) != 90*time.Minute {
+		t.Errorf("Berlin sync = %s-%s", berlin.Start, berlin.End)
+	}
+	if len(berlin.Attendees) != 1 || berlin.Attendees[0] != "anna@example.com" {
+		t.Errorf("Berlin sync attendees = %v", berlin.Attendees)
+	}
+
+	offsite := calendar.Events[1]
+	if !offsite.AllDay || !offsite.Start.Equal(time.Date(2026, time.October, 23, 0, 0, 0, 0, loc)) || !offsite.End.Equal(time.Date(2026, time.October, 24, 0, 0, 0, 0, loc)) {
+		t.Errorf("all-day offsite = %s-%s", offsite.Start, offsite.End)
+	}
+
+	lunch := calendar.Events[2]
+	if lunch.Summary != "Local lunch that has a long folded title" || !lunch.Start.Equal(time.Date(2026, time.October, 21, 12, 0, 0, 0, loc)) {
+		t.Errorf("floating lunch = %q at %s", lunch.Summary, lunch.Start)
+	}
+}
+
+func TestDecodeRejectsInvalidCalendars(t *testing.T) {
+	tests := map[string]string{
+		"not a calendar":   "hello",
+		"unterminated":     "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20261021T120000Z\n",
+		"missing start":    "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:x\nEND:VEVENT\nEND:VCALENDAR",
+		"invalid start":    "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:tomorrow\nEND:VEVENT\nEND:VCALENDAR",
+		"ends too early":   "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20261021T120000Z\nDTEND:20261021T110000Z\nEND:VEVENT\nEND:VCALENDAR",
+		"invalid rrule":    "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20261021T120000Z\nRRULE:FREQ=HOURLY\nEND:VEVENT\nEND:VCALENDAR",
+		"invalid duration": "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20261021T120000Z\nDURATION:1H\nEND:VEVENT\nEND:VCALENDAR",
+	}
+	for name, data := range tests {
+		if _, err := Decode(strings.NewReader(data), time.UTC); err == nil {
+			t.Errorf("%s: Decode succeeded, want error", name)
+		}
+	}
+}
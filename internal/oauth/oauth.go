@@ -0,0 +1,299 @@
+// Package oauth obtains OAuth2 access tokens for the Google Calendar API,
+// either for a user who authorized the agent through the browser or for a
+// service account.
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+	"github.com/azme12/ai-agent-project/internal/config"
+	"github.com/azme12/ai-agent-project/internal/store"
+)
+
+// CalendarScope grants read and write access to the user's calendars.
+const CalendarScope = "https://www.googleapis.com/auth/calendar"
+
+// expiryDelta refreshes access tokens this long before they expire so that
+// requests in flight don't fail.
+const expiryDelta = time.Minute
+
+// stateTTL bounds how long an authorization started with AuthCodeURL can be
+// completed.
+const stateTTL = 10 * time.Minute
+
+// ErrNotAuthorized is returned when no refresh token has been stored yet.
+var ErrNotAuthorized = errors.New("google calendar is not authorized; visit /oauth/google/start")
+
+// ErrInvalidState is returned for callbacks that don't match an
+// authorization started with AuthCodeURL.
+var ErrInvalidState = errors.New("invalid or expired OAuth state")
+
+// Token is an OAuth2 token as returned by the token endpoint.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// valid reports whether the access token can still be used at now.
+func (t *Token) valid(now time.Time) bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || now.Add(expiryDelta).Before(t.Expiry))
+}
+
+// TokenSource supplies access tokens for API requests.
+type TokenSource interface {
+	// Token returns a valid access token, fetching a new one when needed.
+	Token() (string, error)
+	// Invalidate discards the cached access token, e.g. after the API
+	// rejected it, so that the next Token call fetches a new one.
+	Invalidate()
+}
+
+// NewGoogleTokenSource returns the token source selected by cfg: a service
+// account when a key file is configured, the browser flow when an OAuth
+// client is, and a static access token otherwise. It returns nil when no
+// Google credentials are configured.
+func NewGoogleTokenSource(cfg *config.Config, clk clock.Clock) (TokenSource, error) {
+	switch {
+	case cfg.GoogleServiceAccountFile != "":
+		source, err := NewServiceAccountTokenSource(cfg.GoogleServiceAccountFile, cfg.GoogleImpersonateUser, clk)
+		if err != nil {
+			return nil, err
+		}
+		return source, nil
+	case cfg.GoogleClientID != "":
+		if cfg.TokenEncryptionKey == "" {
+			return nil, fmt.Errorf("google OAuth requires TOKEN_ENCRYPTION_KEY to store refresh tokens")
+		}
+		tokens := NewTokenStore(filepath.Join(cfg.DataDir, "google_token.json"), cfg.TokenEncryptionKey)
+		source, err := NewUserTokenSource(&Config{
+			ClientID:     cfg.GoogleClientID,
+			ClientSecret: cfg.GoogleClientSecret,
+			RedirectURL:  cfg.GoogleRedirectURL,
+			AuthURL:      cfg.GoogleAuthURL,
+			TokenURL:     cfg.GoogleTokenURL,
+			Scopes:       []string{CalendarScope},
+		}, tokens, clk)
+		if err != nil {
+			return nil, err
+		}
+		return source, nil
+	case cfg.GoogleCalendarAPIKey != "":
+		return StaticTokenSource(cfg.GoogleCalendarAPIKey), nil
+	}
+	return nil, nil
+}
+
+// StaticTokenSource always returns the same access token.
+type StaticTokenSource string
+
+func (s StaticTokenSource) Token() (string, error) {
+	return string(s), nil
+}
+
+func (s StaticTokenSource) Invalidate() {}
+
+// Config describes an OAuth2 client.
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	AuthURL      string
+	TokenURL     string
+	Scopes       []string
+}
+
+// UserTokenSource implements the authorization code flow. The refresh token
+// obtained when the user grants access is kept in a TokenStore and used to
+// fetch access tokens from then on.
+type UserTokenSource struct {
+	config *Config
+	tokens *TokenStore
+	clock  clock.Clock
+	client *http.Client
+
+	mu    sync.Mutex
+	token *Token
+	// states maps pending authorization states to their expiry
+	states map[string]time.Time
+}
+
+func NewUserTokenSource(cfg *Config, tokens *TokenStore, clk clock.Clock) (*UserTokenSource, error) {
+	token, err := tokens.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserTokenSource{
+		config: cfg,
+		tokens: tokens,
+		clock:  clk,
+		client: &http.Client{Timeout: 30 * time.Second},
+		token:  token,
+		states: make(map[string]time.Time),
+	}, nil
+}
+
+// AuthCodeURL starts an authorization and returns the consent page the user
+// is sent to.
+func (s *UserTokenSource) AuthCodeURL() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	for state, expiry := range s.states {
+		if now.After(expiry) {
+			delete(s.states, state)
+		}
+	}
+	state := store.NewID()
+	s.states[state] = now.Add(stateTTL)
+
+	// Offline access with forced consent makes Google return a refresh
+	// token even if the user authorized the agent before
+	params := url.Values{
+		"response_type": {"code"},
+		"client_id":     {s.config.ClientID},
+		"redirect_uri":  {s.config.RedirectURL},
+		"scope":         {strings.Join(s.config.Scopes, " ")},
+		"state":         {state},
+		"access_type":   {"offline"},
+		"prompt":        {"consent"},
+	}
+	return s.config.AuthURL + "?" + params.Encode()
+}
+
+// Exchange completes an authorization by trading the code passed to the
+// callback for tokens and storing them.
+func (s *UserTokenSource) Exchange(state, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiry, ok := s.states[state]
+	if !ok || s.clock.Now().After(expiry) {
+		return ErrInvalidState
+	}
+	delete(s.states, state)
+
+	token, err := fetchToken(s.client, s.config.TokenURL, s.clock.Now(), url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.config.RedirectURL},
+		"client_id":     {s.config.ClientID},
+		"client_secret": {s.config.ClientSecret},
+	})
+	if err != nil {
+		return err
+	}
+	if token.RefreshToken == "" {
+		return fmt.Errorf("token endpoint returned no refresh token")
+	}
+
+	if err := s.tokens.Save(token); err != nil {
+		return err
+	}
+	s.token = token
+	return nil
+}
+
+// Authorized reports whether a refresh token has been stored.
+func (s *UserTokenSource) Authorized() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.token != nil && s.token.RefreshToken != ""
+}
+
+func (s *UserTokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if s.token.valid(now) {
+		return s.token.AccessToken, nil
+	}
+	if s.token == nil || s.token.RefreshToken == "" {
+		return "", ErrNotAuthorized
+	}
+
+	token, err := fetchToken(s.client, s.config.TokenURL, now, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {s.token.RefreshToken},
+		"client_id":     {s.config.ClientID},
+		"client_secret": {s.config.ClientSecret},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Google only returns a new refresh token when it rotates it
+	if token.RefreshToken == "" {
+		token.RefreshToken = s.token.RefreshToken
+	}
+	if err := s.tokens.Save(token); err != nil {
+		return "", err
+	}
+	s.token = token
+	return token.AccessToken, nil
+}
+
+func (s *UserTokenSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != nil {
+		s.token.AccessToken = ""
+	}
+}
+
+// fetchToken posts a token request and decodes the response, computing the
+// token's expiry from now.
+func fetchToken(client *http.Client, tokenURL string, now time.Time, params url.Values) (*Token, error) {
+	resp, err := client.PostForm(tokenURL, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint error: %d - %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %v", err)
+	}
+	if result.AccessToken == "" {
+		return nil, fmt.Errorf("token endpoint returned no access token")
+	}
+
+	token := &Token{
+		AccessToken:  result.AccessToken,
+		TokenType:    result.TokenType,
+		RefreshToken: result.RefreshToken,
+	}
+	if result.ExpiresIn > 0 {
+		token.Expiry = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
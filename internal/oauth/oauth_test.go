@@ -0,0 +1,232 @@
+package oauth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+)
+
+// tokenServer is a stand-in for Google's token endpoint that records the
+// grants it receives.
+type tokenServer struct {
+	mu     sync.Mutex
+	grants []url.Values
+	issued int
+	// handle validates a grant and returns the refresh token to issue
+	handle func(url.Values) (string, error)
+}
+
+func newTokenServer(t *testing.T, handle func(url.Values) (string, error)) (*tokenServer, *httptest.Server) {
+	t.Helper()
+
+	s := &tokenServer{handle: handle}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.grants = append(s.grants, r.PostForm)
+
+		refresh, err := s.handle(r.PostForm)
+		if err != nil {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		s.issued++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  fmt.Sprintf("access-%d", s.issued),
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+			"refresh_token": refresh,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return s, server
+}
+
+func TestUserTokenSource(t *testing.T) {
+	_, server := newTokenServer(t, func(form url.Values) (string, error) {
+		switch form.Get("grant_type") {
+		case "authorization_code":
+			if form.Get("code") != "the-code" || form.Get("client_secret") != "secret" {
+				return "", errors.New("bad code")
+			}
+			return "refresh-1", nil
+		case "refresh_token":
+			if form.Get("refresh_token") != "refresh-1" {
+				return "", errors.New("bad refresh token")
+			}
+			return "", nil
+		}
+		return "", errors.New("unsupported grant")
+	})
+
+	path := filepath.Join(t.TempDir(), "google_token.json")
+	clk := clock.NewFake(time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC))
+	cfg := &Config{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8080/oauth/google/callback",
+		AuthURL:      "https://accounts.example.com/auth",
+		TokenURL:     server.URL,
+		Scopes:       []string{CalendarScope},
+	}
+
+	source, err := NewUserTokenSource(cfg, NewTokenStore(path, "key"), clk)
+	if err != nil {
+		t.Fatalf("NewUserTokenSource failed: %v", err)
+	}
+	if _, err := source.Token(); !errors.Is(err, ErrNotAuthorized) {
+		t.Fatalf("Token before authorizing = %v, want ErrNotAuthorized", err)
+	}
+
+	consent, err := url.Parse(source.AuthCodeURL())
+	if err != nil {
+		t.Fatalf("invalid consent URL: %v", err)
+	}
+	state := consent.Query().Get("state")
+	if consent.Query().Get("access_type") != "offline" || consent.Query().Get("redirect_uri") != cfg.RedirectURL || state == "" {
+		t.Fatalf("consent URL %s lacks offline access, redirect or state", consent)
+	}
+
+	if err := source.Exchange("forged", "the-code"); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Exchange with forged state = %v, want ErrInvalidState", err)
+	}
+	if err := source.Exchange(state, "the-code"); err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+	if err := source.Exchange(state, "the-code"); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("reusing state = %v, want ErrInvalidState", err)
+	}
+
+	// The refresh token is only stored encrypted
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("token file not written: %v", err)
+	}
+	if strings.Contains(string(data), "refresh-1") {
+		t.Errorf("token file holds the plaintext refresh token: %s", data)
+	}
+	if _, err := NewTokenStore(path, "other key").Load(); err == nil {
+		t.Error("token file decrypted with the wrong key")
+	}
+
+	if token, err := source.Token(); err != nil || token != "access-1" {
+		t.Errorf("Token = %q, %v, want access-1", token, err)
+	}
+
+	// A restarted agent refreshes with the stored token once the cached
+	// access token expires
+	restarted, err := NewUserTokenSource(cfg, NewTokenStore(path, "key"), clk)
+	if err != nil {
+		t.Fatalf("NewUserTokenSource failed: %v", err)
+	}
+	if token, _ := restarted.Token(); token != "access-1" {
+		t.Errorf("restarted Token = %q, want the stored access-1", token)
+	}
+	clk.Advance(time.Hour)
+	if token, err := restarted.Token(); err != nil || token != "access-2" {
+		t.Errorf("Token after expiry = %q, %v, want access-2", token, err)
+	}
+
+	// Rejected tokens are refreshed even before they expire
+	restarted.Invalidate()
+	if token, err := restarted.Token(); err != nil || token != "access-3" {
+		t.Errorf("Token after Invalidate = %q, %v, want access-3", token, err)
+	}
+	if stored, _ := NewTokenStore(path, "key").Load(); stored == nil || stored.RefreshToken != "refresh-1" {
+		t.Errorf("stored token = %+v, want refresh-1 kept", stored)
+	}
+}
+
+func TestServiceAccountTokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+
+	var claims map[string]interface{}
+	tokens, server := newTokenServer(t, func(form url.Values) (string, error) {
+		if form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			return "", errors.New("unsupported grant")
+		}
+		parts := strings.Split(form.Get("assertion"), ".")
+		if len(parts) != 3 {
+			return "", errors.New("malformed assertion")
+		}
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return "", err
+		}
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		claims = nil
+		return "", json.Unmarshal(payload, &claims)
+	})
+	tokenURL := server.URL
+
+	path := filepath.Join(t.TempDir(), "service-account.json")
+	file, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "agent@project.iam.gserviceaccount.com",
+		"private_key_id": "key-1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":      tokenURL,
+	})
+	if err := os.WriteFile(path, file, 0o600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+
+	clk := clock.NewFake(time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC))
+	source, err := NewServiceAccountTokenSource(path, "alice@example.com", clk)
+	if err != nil {
+		t.Fatalf("NewServiceAccountTokenSource failed: %v", err)
+	}
+
+	if token, err := source.Token(); err != nil || token != "access-1" {
+		t.Fatalf("Token = %q, %v, want access-1", token, err)
+	}
+	if claims["iss"] != "agent@project.iam.gserviceaccount.com" || claims["sub"] != "alice@example.com" ||
+		claims["aud"] != tokenURL || claims["scope"] != CalendarScope {
+		t.Errorf("unexpected claims %v", claims)
+	}
+	if claims["iat"] != float64(clk.Now().Unix()) {
+		t.Errorf("iat = %v, want %d", claims["iat"], clk.Now().Unix())
+	}
+
+	// The access token is cached until it nearly expires
+	clk.Advance(30 * time.Minute)
+	if token, _ := source.Token(); token != "access-1" || len(tokens.grants) != 1 {
+		t.Errorf("Token = %q after %d grants, want cached access-1", token, len(tokens.grants))
+	}
+	clk.Advance(30 * time.Minute)
+	if token, _ := source.Token(); token != "access-2" {
+		t.Errorf("Token after expiry = %q, want access-2", token)
+	}
+
+	if _, err := NewServiceAccountTokenSource(filepath.Join(t.TempDir(), "missing.json"), "", clk); err == nil {
+		t.Error("missing key file accepted")
+	}
+}
@@ -0,0 +1,164 @@
+package oauth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+)
+
+// assertionLifetime is how long signed JWT assertions are valid; Google
+// accepts at most an hour.
+const assertionLifetime = time.Hour
+
+// ServiceAccountTokenSource fetches access tokens with the JWT bearer grant,
+// signing assertions with a service account's private key. With a subject
+// set, it acts on behalf of that user through domain-wide delegation.
+type ServiceAccountTokenSource struct {
+	email    string
+	keyID    string
+	key      *rsa.PrivateKey
+	tokenURL string
+	subject  string
+	scopes   []string
+	clock    clock.Clock
+	client   *http.Client
+
+	mu    sync.Mutex
+	token *Token
+}
+
+// serviceAccountKey holds the fields of a JSON key file downloaded from the
+// Google Cloud console that are needed to sign assertions.
+type serviceAccountKey struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// NewServiceAccountTokenSource reads the JSON key file at path. subject is
+// the user to impersonate and may be empty.
+func NewServiceAccountTokenSource(path, subject string, clk clock.Clock) (*ServiceAccountTokenSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account key: %v", err)
+	}
+
+	var file serviceAccountKey
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode service account key: %v", err)
+	}
+	if file.Type != "service_account" || file.ClientEmail == "" || file.TokenURI == "" {
+		return nil, fmt.Errorf("%s is not a service account key file", path)
+	}
+
+	block, _ := pem.Decode([]byte(file.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("service account key holds no PEM private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse service account private key: %v", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("service account private key is not an RSA key")
+	}
+
+	return &ServiceAccountTokenSource{
+		email:    file.ClientEmail,
+		keyID:    file.PrivateKeyID,
+		key:      key,
+		tokenURL: file.TokenURI,
+		subject:  subject,
+		scopes:   []string{CalendarScope},
+		clock:    clk,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (s *ServiceAccountTokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if s.token.valid(now) {
+		return s.token.AccessToken, nil
+	}
+
+	assertion, err := s.assertion(now)
+	if err != nil {
+		return "", err
+	}
+	token, err := fetchToken(s.client, s.tokenURL, now, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s.token = token
+	return token.AccessToken, nil
+}
+
+func (s *ServiceAccountTokenSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.token = nil
+}
+
+// assertion returns a JWT signed with RS256 that is valid from now.
+func (s *ServiceAccountTokenSource) assertion(now time.Time) (string, error) {
+	header := map[string]string{"alg": "RS256", "typ": "JWT", "kid": s.keyID}
+	claims := map[string]interface{}{
+		"iss":   s.email,
+		"scope": strings.Join(s.scopes, " "),
+		"aud":   s.tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(assertionLifetime).Unix(),
+	}
+	if s.subject != "" {
+		claims["sub"] = s.subject
+	}
+
+	encode := func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode assertion: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data), nil
+	}
+	encodedHeader, err := encode(header)
+	if err != nil {
+		return "", err
+	}
+	encodedClaims, err := encode(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := encodedHeader + "." + encodedClaims
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign assertion: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,92 @@
+package oauth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/azme12/ai-agent-project/internal/store"
+)
+
+// TokenStore keeps a token in a file encrypted with AES-256-GCM, so that the
+// refresh token is useless to anyone who copies the data directory without
+// the key.
+type TokenStore struct {
+	path string
+	key  [32]byte
+}
+
+// sealedToken is the file format of a TokenStore.
+type sealedToken struct {
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// NewTokenStore returns a store for the token file at path. The encryption
+// key is derived from secret.
+func NewTokenStore(path, secret string) *TokenStore {
+	return &TokenStore{path: path, key: sha256.Sum256([]byte(secret))}
+}
+
+// Load returns the stored token, or nil when none has been saved.
+func (s *TokenStore) Load() (*Token, error) {
+	var sealed sealedToken
+	if err := store.LoadJSON(s.path, &sealed); err != nil {
+		return nil, err
+	}
+	if sealed.Ciphertext == nil {
+		return nil, nil
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return nil, err
+	}
+	data, err := gcm.Open(nil, sealed.Nonce, sealed.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s, was TOKEN_ENCRYPTION_KEY changed?", s.path)
+	}
+
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("failed to decode token: %v", err)
+	}
+	return &token, nil
+}
+
+// Save encrypts token and replaces the stored one.
+func (s *TokenStore) Save(token *Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to encode token: %v", err)
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	return store.SaveJSON(s.path, sealedToken{
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, data, nil),
+	})
+}
+
+func (s *TokenStore) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	return gcm, nil
+}
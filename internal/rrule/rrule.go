@@ -0,0 +1,359 @@
+// Package rrule parses, formats and expands the subset of RFC 5545
+// recurrence rules the agent supports: DAILY, WEEKLY and MONTHLY frequencies
+// with INTERVAL, BYDAY, BYMONTHDAY, COUNT and UNTIL.
+package rrule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+)
+
+// maxPeriods bounds expansion of rules that never produce an occurrence,
+// such as the 31st of every other February.
+const maxPeriods = 10000
+
+// Day is a BYDAY entry. N selects the Nth (or, when negative, Nth last)
+// weekday of the month in MONTHLY rules; zero means every such weekday.
+type Day struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// Rule is a parsed recurrence rule.
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByDay      []Day
+	ByMonthDay []int
+	// Count limits the number of occurrences; zero means no limit.
+	Count int
+	// Until is the last time an occurrence may start; zero means no limit.
+	Until time.Time
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Parse parses a rule such as "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10". A leading
+// "RRULE:" is accepted.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	r := &Rule{Interval: 1}
+
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		key, value := strings.ToUpper(strings.TrimSpace(kv[0])), strings.ToUpper(strings.TrimSpace(kv[1]))
+
+		switch key {
+		case "FREQ":
+			switch value {
+			case Daily, Weekly, Monthly:
+				r.Freq = value
+			default:
+				return nil, fmt.Errorf("unsupported frequency %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid interval %q", value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid count %q", value)
+			}
+			r.Count = n
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			r.Until = until
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, err := parseDay(code)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid month day %q", v)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "WKST":
+			// Weeks always start on Monday
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("rule has no frequency")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, fmt.Errorf("rule cannot have both COUNT and UNTIL")
+	}
+	for _, day := range r.ByDay {
+		if day.N != 0 && r.Freq != Monthly {
+			return nil, fmt.Errorf("numbered BYDAY is only supported for MONTHLY rules")
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq != Monthly {
+		return nil, fmt.Errorf("BYMONTHDAY is only supported for MONTHLY rules")
+	}
+	return r, nil
+}
+
+func parseDay(code string) (Day, error) {
+	code = strings.TrimSpace(code)
+	if len(code) < 2 {
+		return Day{}, fmt.Errorf("invalid day %q", code)
+	}
+
+	weekday, ok := weekdayCodes[code[len(code)-2:]]
+	if !ok {
+		return Day{}, fmt.Errorf("invalid day %q", code)
+	}
+
+	day := Day{Weekday: weekday}
+	if prefix := code[:len(code)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return Day{}, fmt.Errorf("invalid day %q", code)
+		}
+		day.N = n
+	}
+	return day, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// A date includes the whole day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid until %q", value)
+}
+
+// String formats the rule without the "RRULE:" prefix.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, day := range r.ByDay {
+			code := weekdayNames[day.Weekday]
+			if day.N != 0 {
+				code = strconv.Itoa(day.N) + code
+			}
+			days = append(days, code)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		var days []string
+		for _, n := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(n))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences returns the start times of a series beginning at start that
+// fall in [from, to), in start's location. Occurrences keep start's wall
+// clock time across daylight saving changes. Times that don't match the
+// rule, including start itself, are not occurrences and don't count towards
+// COUNT.
+func (r *Rule) Occurrences(start, from, to time.Time) []time.Time {
+	var occurrences []time.Time
+	count := 0
+
+	for period := 0; period < maxPeriods; period++ {
+		candidates := r.period(start, period)
+		if len(candidates) == 0 && r.Freq != Monthly {
+			continue
+		}
+
+		for _, t := range candidates {
+			if t.Before(start) {
+				continue
+			}
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return occurrences
+			}
+			if !t.Before(to) {
+				return occurrences
+			}
+
+			count++
+			if !t.Before(from) {
+				occurrences = append(occurrences, t)
+			}
+			if r.Count > 0 && count >= r.Count {
+				return occurrences
+			}
+		}
+	}
+	return occurrences
+}
+
+// First returns the first time at or after start on a day the rule
+// selects, ignoring its interval and limits, which is where a series meant
+// to begin at start should start. It returns the zero time if no day within
+// a year matches.
+func (r *Rule) First(start time.Time) time.Time {
+	rule := *r
+	rule.Interval, rule.Count, rule.Until = 1, 0, time.Time{}
+	if occurrences := rule.Occurrences(start, start, start.AddDate(1, 0, 1)); len(occurrences) > 0 {
+		return occurrences[0]
+	}
+	return time.Time{}
+}
+
+// period returns the candidate start times of the nth period after start,
+// earliest first.
+func (r *Rule) period(start time.Time, n int) []time.Time {
+	loc := start.Location()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, start.Hour(), start.Minute(), start.Second(), 0, loc)
+	}
+
+	switch r.Freq {
+	case Daily:
+		t := at(start.Year(), start.Month(), start.Day()+n*r.Interval)
+		if len(r.ByDay) > 0 && !r.hasWeekday(t.Weekday()) {
+			return nil
+		}
+		return []time.Time{t}
+
+	case Weekly:
+		// Monday of start's week
+		offset := (int(start.Weekday()) + 6) % 7
+		monday := at(start.Year(), start.Month(), start.Day()-offset+7*n*r.Interval)
+
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []Day{{Weekday: start.Weekday()}}
+		}
+		var times []time.Time
+		for _, day := range days {
+			times = append(times, at(monday.Year(), monday.Month(), monday.Day()+(int(day.Weekday)+6)%7))
+		}
+		sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+		return times
+
+	case Monthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(n*r.Interval), 1, 0, 0, 0, 0, loc)
+		year, month := first.Year(), first.Month()
+		daysInMonth := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+
+		// dayNumbers lists the days of the month a BYDAY entry selects
+		dayNumbers := func(bd Day) []int {
+			firstMatch := 1 + (int(bd.Weekday)-int(first.Weekday())+7)%7
+			switch {
+			case bd.N > 0:
+				return []int{firstMatch + 7*(bd.N-1)}
+			case bd.N < 0:
+				last := firstMatch
+				for last+7 <= daysInMonth {
+					last += 7
+				}
+				return []int{last + 7*(bd.N+1)}
+			}
+			var all []int
+			for d := firstMatch; d <= daysInMonth; d += 7 {
+				all = append(all, d)
+			}
+			return all
+		}
+
+		byDay := map[int]bool{}
+		for _, bd := range r.ByDay {
+			for _, day := range dayNumbers(bd) {
+				byDay[day] = true
+			}
+		}
+		byMonthDay := map[int]bool{}
+		for _, md := range r.ByMonthDay {
+			if md < 0 {
+				md = daysInMonth + md + 1
+			}
+			byMonthDay[md] = true
+		}
+
+		// With both, BYDAY limits BYMONTHDAY, as in every Friday the 13th
+		selected := byMonthDay
+		switch {
+		case len(r.ByMonthDay) > 0 && len(r.ByDay) > 0:
+			selected = map[int]bool{}
+			for day := range byMonthDay {
+				if byDay[day] {
+					selected[day] = true
+				}
+			}
+		case len(r.ByDay) > 0:
+			selected = byDay
+		case len(r.ByMonthDay) == 0:
+			// Months without start's day are skipped
+			selected[start.Day()] = true
+		}
+
+		var days []int
+		for day := range selected {
+			if day >= 1 && day <= daysInMonth {
+				days = append(days, day)
+			}
+		}
+		sort.Ints(days)
+		var times []time.Time
+		for _, day := range days {
+			times = append(times, at(year, month, day))
+		}
+		return times
+	}
+	return nil
+}
+
+func (r *Rule) hasWeekday(weekday time.Weekday) bool {
+	for _, day := range r.ByDay {
+		if day.Weekday == weekday {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package rrule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRoundTrip(t *testing.T) {
+	tests := []string{
+		"FREQ=DAILY",
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10",
+		"FREQ=MONTHLY;BYDAY=-1FR;UNTIL=20261231T235959Z",
+		"FREQ=MONTHLY;BYMONTHDAY=15,-1",
+	}
+	for _, s := range tests {
+		rule, err := Parse("RRULE:" + s)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", s, err)
+		}
+		if got := rule.String(); got != s {
+			t.Errorf("Parse(%q).String() = %q", s, got)
+		}
+	}
+
+	for _, s := range []string{"", "FREQ=YEARLY", "FREQ=WEEKLY;BYDAY=1MO", "FREQ=DAILY;COUNT=2;UNTIL=20261231", "FREQ=WEEKLY;BYDAY=XX"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+
+	// Wednesday, October 14 2026 at 09:00, three weeks before DST ends
+	start := time.Date(2026, time.October, 14, 9, 0, 0, 0, loc)
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2026, month, day, 9, 0, 0, 0, loc)
+	}
+	far := start.AddDate(1, 0, 0)
+
+	tests := []struct {
+		rule string
+		from time.Time
+		to   time.Time
+		want []time.Time
+	}{
+		{"FREQ=DAILY;COUNT=3", start, far, []time.Time{date(time.October, 14), date(time.October, 15), date(time.October, 16)}},
+		{"FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4", start, far, []time.Time{date(time.October, 14), date(time.October, 19), date(time.October, 21), date(time.October, 26)}},
+		{"FREQ=WEEKLY;INTERVAL=2;UNTIL=20261112", start, far, []time.Time{date(time.October, 14), date(time.October, 28), date(time.November, 11)}},
+		{"FREQ=WEEKLY", date(time.October, 30), date(time.November, 12), []time.Time{date(time.November, 4), date(time.November, 11)}},
+		{"FREQ=MONTHLY;BYDAY=1MO;COUNT=3", start, far, []time.Time{date(time.November, 2), date(time.December, 7), time.Date(2027, time.January, 4, 9, 0, 0, 0, loc)}},
+		{"FREQ=MONTHLY;BYDAY=-1FR;COUNT=2", start, far, []time.Time{date(time.October, 30), date(time.November, 27)}},
+		// BYDAY limits BYMONTHDAY: only Friday the 13ths
+		{"FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13;COUNT=2", start, far, []time.Time{date(time.November, 13), time.Date(2027, time.August, 13, 9, 0, 0, 0, loc)}},
+		{"FREQ=DAILY;COUNT=5", date(time.October, 17), far, []time.Time{date(time.October, 17), date(time.October, 18)}},
+	}
+
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.rule, err)
+		}
+		got := rule.Occurrences(start, tt.from, tt.to)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.rule, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if !got[i].Equal(tt.want[i]) {
+				t.Errorf("%s: occurrence %d = %v, want %v", tt.rule, i, got[i], tt.want[i])
+			}
+		}
+	}
+
+	// Monthly on the 31st skips shorter months
+	rule, _ := Parse("FREQ=MONTHLY;COUNT=2")
+	got := rule.Occurrences(time.Date(2026, time.October, 31, 9, 0, 0, 0, loc), start, far)
+	if len(got) != 2 || !got[1].Equal(date(time.December, 31)) {
+		t.Errorf("monthly on the 31st = %v", got)
+	}
+}
+
+func TestFromText(t *testing.T) {
+	// Wednesday, October 14 2026 at 10:00
+	ref := time.Date(2026, time.October, 14, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		text string
+		rule string
+		rest string
+	}{
+		{"weekly standup every Monday at 9am", "FREQ=WEEKLY;BYDAY=MO", "weekly standup at 9am"},
+		{"sync every Tuesday and Thursday at 2pm for 10 times", "FREQ=WEEKLY;BYDAY=TU,TH;COUNT=10", "sync at 2pm"},
+		{"schedule a daily check-in at 8:30", "FREQ=DAILY", "schedule a check-in at 8:30"},
+		{"1:1 every other week on Friday until Dec 18", "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR;UNTIL=20261218T235959Z", "1:1"},
+		{"review on the first Monday of every month at 10am", "FREQ=MONTHLY;BYDAY=1MO", "review at 10am"},
+		{"planning every weekday at 9 for 2 weeks", "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR;UNTIL=20261027T235959Z", "planning at 9"},
+		{"retro every 3 weeks", "FREQ=WEEKLY;INTERVAL=3", "retro"},
+	}
+
+	for _, tt := range tests {
+		rule, rest, ok := FromText(tt.text, ref)
+		if !ok {
+			t.Errorf("FromText(%q) found no recurrence", tt.text)
+			continue
+		}
+		if rule.String() != tt.rule || rest != tt.rest {
+			t.Errorf("FromText(%q) = %q, %q; want %q, %q", tt.text, rule.String(), rest, tt.rule, tt.rest)
+		}
+	}
+
+	for _, text := range []string{"meeting tomorrow at 3pm", "every so often", "meet at the month end"} {
+		if rule, _, ok := FromText(text, ref); ok {
+			t.Errorf("FromText(%q) = %q, want no recurrence", text, rule.String())
+		}
+	}
+}
+
+func TestFirst(t *testing.T) {
+	// Saturday, October 17 2026 at 14:00
+	start := time.Date(2026, time.October, 17, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		rule string
+		want time.Time
+	}{
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=FR", time.Date(2026, time.October, 23, 14, 0, 0, 0, time.UTC)},
+		{"FREQ=DAILY", start},
+		{"FREQ=MONTHLY;BYDAY=1MO;COUNT=1", time.Date(2026, time.November, 2, 14, 0, 0, 0, time.UTC)},
+		{"FREQ=MONTHLY;BYMONTHDAY=31", time.Date(2026, time.October, 31, 14, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.rule, err)
+		}
+		if got := rule.First(start); !got.Equal(tt.want) {
+			t.Errorf("%s: First = %v, want %v", tt.rule, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package rrule
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/timeparse"
+)
+
+const (
+	dayName  = `(?:monday|tuesday|wednesday|thursday|friday|saturday|sunday|mon|tues|tue|wed|thurs|thur|thu|fri|sat|sun)s?\b`
+	dayList  = dayName + `(?:\s*(?:,|and|&|,\s*and)\s*` + dayName + `)*`
+	ordinal  = `(first|second|third|fourth|last|1st|2nd|3rd|4th)`
+	interval = `(other|\d+|two|three|four|five|six)`
+	dateExpr = `(\d{4}-\d{1,2}-\d{1,2}` +
+		`|(?:january|february|march|april|may|june|july|august|september|october|november|december|sept|jan|feb|mar|apr|jun|jul|aug|sep|oct|nov|dec)\.?\s+\d{1,2}(?:st|nd|rd|th)?\b(?:,?\s+\d{4}\b)?` +
+		`|\d{1,2}(?:st|nd|rd|th)?\s+(?:of\s+)?(?:january|february|march|april|may|june|july|august|september|october|november|december|sept|jan|feb|mar|apr|jun|jul|aug|sep|oct|nov|dec)\b\.?(?:,?\s+\d{4}\b)?` +
+		`|\d{1,2}/\d{1,2}(?:/\d{4}|/\d{2})?\b)`
+)
+
+var (
+	dayNameRegex = regexp.MustCompile(`(?i)` + dayName)
+
+	// "the first Monday of every month", "every month on the last Friday"
+	monthlyDayRegex  = regexp.MustCompile(`(?i)\b(?:on\s+)?(?:the\s+)?` + ordinal + `\s+(` + dayName + `)\s+of\s+(?:every|each|the)\s+month\b`)
+	everyMonthOnRgx  = regexp.MustCompile(`(?i)\b(?:every|each)\s+month\s+on\s+the\s+` + ordinal + `\s+(` + dayName + `)`)
+	everyDaysRegex   = regexp.MustCompile(`(?i)\b(?:every|each)\s+(?:` + interval + `\s+)?(` + dayList + `)`)
+	everyPeriodRegex = regexp.MustCompile(`(?i)\b(?:every|each)\s+(?:` + interval + `\s+)?(day|weekday|week|month)s?\b(?:\s+on\s+(` + dayList + `))?`)
+	adverbRegex      = regexp.MustCompile(`(?i)\b(daily|weekly|monthly|biweekly|bi-weekly|fortnightly)\b(?:\s+on\s+(` + dayList + `))?`)
+
+	countRegex  = regexp.MustCompile(`(?i)\b(?:for\s+)?(\d+|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve)\s+(?:times|occurrences|sessions)\b`)
+	forRegex    = regexp.MustCompile(`(?i)\bfor\s+(?:the\s+next\s+)?(\d+|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve)\s+(day|week|month)s?\b`)
+	untilRegex  = regexp.MustCompile(`(?i)\b(?:until|till|through|ending(?:\s+on)?)\s+` + dateExpr)
+	numberWords = map[string]int{
+		"two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7,
+		"eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+	}
+	ordinals = map[string]int{
+		"first": 1, "1st": 1, "second": 2, "2nd": 2, "third": 3, "3rd": 3,
+		"fourth": 4, "4th": 4, "last": -1,
+	}
+)
+
+// FromText finds a recurrence such as "every Monday", "daily",
+// "every other week" or "the first Friday of every month" in text, together
+// with an optional limit ("10 times", "for 6 weeks", "until Dec 15"). It
+// returns the rule and text with the recurrence removed, so that the
+// remaining date and time can be parsed as the first occurrence. Relative
+// limits are resolved against ref. It reports false when text doesn't
+// recur.
+func FromText(text string, ref time.Time) (*Rule, string, bool) {
+	rule := &Rule{Interval: 1}
+	rest := text
+
+	switch {
+	case matchMonthlyDay(&rest, rule, monthlyDayRegex), matchMonthlyDay(&rest, rule, everyMonthOnRgx):
+	case matchEveryDays(&rest, rule):
+	case matchEveryPeriod(&rest, rule):
+	case matchAdverb(&rest, rule):
+	default:
+		return nil, text, false
+	}
+
+	if m := find(&rest, countRegex); m != nil {
+		rule.Count = number(m[1])
+	} else if m := find(&rest, untilRegex); m != nil {
+		if until, ok := timeparse.Parse(m[1], ref); ok && until.HasDate {
+			d := until.Start
+			rule.Until = time.Date(d.Year(), d.Month(), d.Day(), 23, 59, 59, 0, d.Location())
+		}
+	} else if m := find(&rest, forRegex); m != nil {
+		n := number(m[1])
+		end := ref
+		switch strings.ToLower(m[2]) {
+		case "day":
+			end = ref.AddDate(0, 0, n)
+		case "week":
+			end = ref.AddDate(0, 0, 7*n)
+		case "month":
+			end = ref.AddDate(0, n, 0)
+		}
+		rule.Until = time.Date(end.Year(), end.Month(), end.Day()-1, 23, 59, 59, 0, end.Location())
+	}
+
+	return rule, strings.Join(strings.Fields(rest), " "), true
+}
+
+func matchMonthlyDay(rest *string, rule *Rule, re *regexp.Regexp) bool {
+	m := find(rest, re)
+	if m == nil {
+		return false
+	}
+	rule.Freq = Monthly
+	rule.ByDay = []Day{{N: ordinals[strings.ToLower(m[1])], Weekday: weekday(m[2])}}
+	return true
+}
+
+func matchEveryDays(rest *string, rule *Rule) bool {
+	m := find(rest, everyDaysRegex)
+	if m == nil {
+		return false
+	}
+	rule.Freq = Weekly
+	rule.Interval = number(m[1])
+	rule.ByDay = days(m[2])
+	return true
+}
+
+func matchEveryPeriod(rest *string, rule *Rule) bool {
+	m := find(rest, everyPeriodRegex)
+	if m == nil {
+		return false
+	}
+	rule.Interval = number(m[1])
+
+	switch strings.ToLower(m[2]) {
+	case "day":
+		rule.Freq = Daily
+	case "weekday":
+		rule.Freq = Weekly
+		rule.ByDay = days("mon, tue, wed, thu, fri")
+	case "week":
+		rule.Freq = Weekly
+		rule.ByDay = days(m[3])
+	case "month":
+		rule.Freq = Monthly
+	}
+	return true
+}
+
+func matchAdverb(rest *string, rule *Rule) bool {
+	m := find(rest, adverbRegex)
+	if m == nil {
+		return false
+	}
+
+	switch strings.ToLower(m[1]) {
+	case "daily":
+		rule.Freq = Daily
+	case "monthly":
+		rule.Freq = Monthly
+	case "weekly":
+		rule.Freq = Weekly
+	default:
+		rule.Freq = Weekly
+		rule.Interval = 2
+	}
+	if rule.Freq == Weekly {
+		rule.ByDay = days(m[2])
+	}
+	return true
+}
+
+// find returns the submatches of re in *s and blanks out the match.
+func find(s *string, re *regexp.Regexp) []string {
+	loc := re.FindStringSubmatchIndex(*s)
+	if loc == nil {
+		return nil
+	}
+
+	m := make([]string, len(loc)/2)
+	for i := range m {
+		if loc[2*i] >= 0 {
+			m[i] = (*s)[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	*s = (*s)[:loc[0]] + " " + (*s)[loc[1]:]
+	return m
+}
+
+func number(s string) int {
+	s = strings.ToLower(s)
+	switch s {
+	case "":
+		return 1
+	case "other":
+		return 2
+	}
+	if n, ok := numberWords[s]; ok {
+		return n
+	}
+	n, _ := strconv.Atoi(s)
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+func days(list string) []Day {
+	seen := map[time.Weekday]bool{}
+	var result []Day
+	for _, name := range dayNameRegex.FindAllString(list, -1) {
+		wd := weekday(name)
+		if !seen[wd] {
+			seen[wd] = true
+			result = append(result, Day{Weekday: wd})
+		}
+	}
+	return result
+}
+
+// weekday maps a day name to its weekday by its first two letters, which
+// are unique.
+func weekday(name string) time.Weekday {
+	return weekdayCodes[strings.ToUpper(name[:2])]
+}
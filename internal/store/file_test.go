@@ -0,0 +1,46 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJSONFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data", "state.json")
+
+	// A missing file leaves the value untouched
+	state := map[string]int{"kept": 1}
+	if err := LoadJSON(path, &state); err != nil || state["kept"] != 1 {
+		t.Fatalf("loading a missing file got %v, %v", state, err)
+	}
+
+	if err := SaveJSON(path, map[string]int{"a": 1, "b": 2}); err != nil {
+		t.Fatalf("SaveJSON failed: %v", err)
+	}
+	var loaded map[string]int
+	if err := LoadJSON(path, &loaded); err != nil || len(loaded) != 2 || loaded["b"] != 2 {
+		t.Errorf("loaded %v, %v", loaded, err)
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("data directory holds %v, %v", entries, err)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadJSON(path, &loaded); err == nil {
+		t.Error("loading a corrupt file succeeded")
+	}
+}
+
+func TestNewID(t *testing.T) {
+	a, b := NewID(), NewID()
+	if len(a) != 16 || a == b {
+		t.Errorf("got IDs %q and %q", a, b)
+	}
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ReminderLedger records which meeting reminders have been sent so repeated
+// scheduler ticks and restarts don't send them again.
+type ReminderLedger struct {
+	mu   sync.Mutex
+	path string
+	// sent maps a reminder key to the start time of its event
+	sent map[string]time.Time
+}
+
+func NewReminderLedger(dir string) (*ReminderLedger, error) {
+	l := &ReminderLedger{
+		path: filepath.Join(dir, "reminders.json"),
+		sent: make(map[string]time.Time),
+	}
+
+	if err := LoadJSON(l.path, &l.sent); err != nil {
+		return nil, err
+	}
+	if l.sent == nil {
+		l.sent = make(map[string]time.Time)
+	}
+
+	return l, nil
+}
+
+// WasSent reports whether the reminder with the given key has been sent.
+func (l *ReminderLedger) WasSent(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, ok := l.sent[key]
+	return ok
+}
+
+// MarkSent records reminders for an event starting at eventStart.
+func (l *ReminderLedger) MarkSent(eventStart time.Time, keys ...string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		l.sent[key] = eventStart
+	}
+	return SaveJSON(l.path, l.sent)
+}
+
+// Prune forgets reminders for events that started before cutoff.
+func (l *ReminderLedger) Prune(cutoff time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pruned := false
+	for key, start := range l.sent {
+		if start.Before(cutoff) {
+			delete(l.sent, key)
+			pruned = true
+		}
+	}
+
+	if !pruned {
+		return nil
+	}
+	return SaveJSON(l.path, l.sent)
+}
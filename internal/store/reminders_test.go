@@ -0,0 +1,44 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReminderLedger(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewReminderLedger(dir)
+	if err != nil {
+		t.Fatalf("NewReminderLedger failed: %v", err)
+	}
+
+	early := time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC)
+	late := early.Add(24 * time.Hour)
+	if err := l.MarkSent(early, "early/1440", "early/15"); err != nil {
+		t.Fatalf("MarkSent failed: %v", err)
+	}
+	if err := l.MarkSent(late, "late/15"); err != nil {
+		t.Fatalf("MarkSent failed: %v", err)
+	}
+
+	// Sent reminders survive a restart
+	l, err = NewReminderLedger(dir)
+	if err != nil {
+		t.Fatalf("NewReminderLedger failed: %v", err)
+	}
+	if !l.WasSent("early/15") || !l.WasSent("late/15") || l.WasSent("late/1440") {
+		t.Error("ledger lost or invented reminders across a restart")
+	}
+
+	// Pruning forgets reminders of events that started before the cutoff
+	if err := l.Prune(early.Add(time.Hour)); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	l, err = NewReminderLedger(dir)
+	if err != nil {
+		t.Fatalf("NewReminderLedger failed: %v", err)
+	}
+	if l.WasSent("early/1440") || l.WasSent("early/15") || !l.WasSent("late/15") {
+		t.Error("Prune kept the wrong reminders")
+	}
+}
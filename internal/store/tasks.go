@@ -0,0 +1,213 @@
+package store
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+)
+
+const (
+	TaskRunning   = "running"
+	TaskSucceeded = "succeeded"
+	TaskFailed    = "failed"
+)
+
+const (
+	// taskRetention is how long finished tasks stay in the history.
+	taskRetention = 30 * 24 * time.Hour
+	// maxTasks bounds the history, and so the file rewritten on every
+	// change, when tasks arrive faster than they age out.
+	maxTasks = 5000
+)
+
+// Task is the history record of one task processed by the agent.
+type Task struct {
+	ID          string          `json:"id"`
+	Input       string          `json:"input"`
+	Type        string          `json:"type,omitempty"`
+	Status      string          `json:"status"`
+	Request     json.RawMessage `json:"request,omitempty"`
+	NLPResponse string          `json:"nlp_response,omitempty"`
+	Outcome     string          `json:"outcome,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	// Result holds task-specific details, such as scheduling conflicts and
+	// suggested slots.
+	Result      json.RawMessage `json:"result,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+}
+
+// TaskFilter narrows List results. Zero fields match everything; From and To
+// bound CreatedAt inclusively and exclusively.
+type TaskFilter struct {
+	Type   string
+	Status string
+	From   time.Time
+	To     time.Time
+}
+
+// TaskStore keeps the task history in a JSON file.
+type TaskStore struct {
+	mu    sync.Mutex
+	clock clock.Clock
+	path  string
+	tasks map[string]*Task
+}
+
+func NewTaskStore(dir string, clk clock.Clock) (*TaskStore, error) {
+	s := &TaskStore{
+		clock: clk,
+		path:  filepath.Join(dir, "tasks.json"),
+		tasks: make(map[string]*Task),
+	}
+
+	var tasks []*Task
+	if err := LoadJSON(s.path, &tasks); err != nil {
+		return nil, err
+	}
+	// Tasks still running were interrupted by a crash or restart and will
+	// never finish
+	changed := false
+	for _, task := range tasks {
+		if task.Status == TaskRunning {
+			now := s.clock.Now()
+			task.Status = TaskFailed
+			task.Error = "interrupted by a restart"
+			task.CompletedAt = &now
+			changed = true
+		}
+		s.tasks[task.ID] = task
+	}
+	if s.prune(s.clock.Now()) {
+		changed = true
+	}
+	if changed {
+		if err := s.save(); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Create records a new running task for input.
+func (s *TaskStore) Create(input string) (*Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	task := &Task{
+		ID:        NewID(),
+		Input:     input,
+		Status:    TaskRunning,
+		CreatedAt: now,
+	}
+	s.tasks[task.ID] = task
+	s.prune(now)
+
+	if err := s.save(); err != nil {
+		delete(s.tasks, task.ID)
+		return nil, err
+	}
+
+	t := *task
+	return &t, nil
+}
+
+// Update replaces the stored record with task.
+func (s *TaskStore) Update(task *Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := *task
+	s.tasks[task.ID] = &t
+	return s.save()
+}
+
+// Get returns the task with the given ID.
+func (s *TaskStore) Get(id string) (*Task, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, false
+	}
+
+	t := *task
+	return &t, true
+}
+
+// List returns the tasks matching filter, newest first.
+func (s *TaskStore) List(filter TaskFilter) []Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := []Task{}
+	for _, task := range s.tasks {
+		if filter.Type != "" && task.Type != filter.Type {
+			continue
+		}
+		if filter.Status != "" && task.Status != filter.Status {
+			continue
+		}
+		if !filter.From.IsZero() && task.CreatedAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !task.CreatedAt.Before(filter.To) {
+			continue
+		}
+		tasks = append(tasks, *task)
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].CreatedAt.After(tasks[j].CreatedAt)
+	})
+	return tasks
+}
+
+// prune forgets finished tasks older than taskRetention, then the oldest
+// finished tasks beyond maxTasks. It reports whether any were removed.
+// Callers hold s.mu or own s.
+func (s *TaskStore) prune(now time.Time) bool {
+	before := len(s.tasks)
+	cutoff := now.Add(-taskRetention)
+
+	var finished []*Task
+	for id, task := range s.tasks {
+		if task.Status == TaskRunning {
+			continue
+		}
+		if task.CreatedAt.Before(cutoff) {
+			delete(s.tasks, id)
+			continue
+		}
+		finished = append(finished, task)
+	}
+
+	if excess := len(s.tasks) - maxTasks; excess > 0 {
+		sort.Slice(finished, func(i, j int) bool {
+			return finished[i].CreatedAt.Before(finished[j].CreatedAt)
+		})
+		for i := 0; i < excess && i < len(finished); i++ {
+			delete(s.tasks, finished[i].ID)
+		}
+	}
+
+	return len(s.tasks) != before
+}
+
+func (s *TaskStore) save() error {
+	tasks := make([]*Task, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+	return SaveJSON(s.path, tasks)
+}
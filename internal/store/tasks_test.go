@@ -0,0 +1,178 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/azme12/ai-agent-project/internal/clock"
+)
+
+func TestTaskStore(t *testing.T) {
+	dir := t.TempDir()
+	clk := clock.NewFake(time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC))
+	s, err := NewTaskStore(dir, clk)
+	if err != nil {
+		t.Fatalf("NewTaskStore failed: %v", err)
+	}
+
+	task, err := s.Create("Schedule a meeting")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if task.Status != TaskRunning || task.ID == "" || !task.CreatedAt.Equal(clk.Now()) {
+		t.Fatalf("created %+v", task)
+	}
+
+	// Changing the returned copy doesn't change the store until Update
+	task.Status = TaskSucceeded
+	task.Type = "schedule"
+	if stored, _ := s.Get(task.ID); stored.Status != TaskRunning {
+		t.Errorf("stored task changed to %q without Update", stored.Status)
+	}
+	if err := s.Update(task); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// Records survive a restart
+	s, err = NewTaskStore(dir, clk)
+	if err != nil {
+		t.Fatalf("NewTaskStore failed: %v", err)
+	}
+	if stored, ok := s.Get(task.ID); !ok || stored.Status != TaskSucceeded || stored.Type != "schedule" {
+		t.Errorf("after a restart got %+v, %v", stored, ok)
+	}
+	if _, ok := s.Get("missing"); ok {
+		t.Error("found a task that doesn't exist")
+	}
+}
+
+func TestTaskStoreFailsInterruptedTasks(t *testing.T) {
+	dir := t.TempDir()
+	clk := clock.NewFake(time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC))
+	s, err := NewTaskStore(dir, clk)
+	if err != nil {
+		t.Fatalf("NewTaskStore failed: %v", err)
+	}
+	task, err := s.Create("Email the team")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// The process dies before the task finishes
+	clk.Advance(time.Hour)
+	s, err = NewTaskStore(dir, clk)
+	if err != nil {
+		t.Fatalf("NewTaskStore failed: %v", err)
+	}
+	stored, _ := s.Get(task.ID)
+	if stored.Status != TaskFailed || stored.Error == "" || stored.CompletedAt == nil || !stored.CompletedAt.Equal(clk.Now()) {
+		t.Errorf("interrupted task is %+v", stored)
+	}
+	if tasks := s.List(TaskFilter{Status: TaskRunning}); len(tasks) != 0 {
+		t.Errorf("%d tasks still running", len(tasks))
+	}
+}
+
+func TestTaskStoreList(t *testing.T) {
+	s, err := NewTaskStore(t.TempDir(), clock.Real())
+	if err != nil {
+		t.Fatalf("NewTaskStore failed: %v", err)
+	}
+
+	day := time.Date(2026, time.October, 14, 0, 0, 0, 0, time.UTC)
+	for i, task := range []Task{
+		{ID: "a", Type: "schedule", Status: TaskSucceeded, CreatedAt: day.Add(9 * time.Hour)},
+		{ID: "b", Type: "email", Status: TaskFailed, CreatedAt: day.Add(33 * time.Hour)},
+		{ID: "c", Type: "schedule", Status: TaskFailed, CreatedAt: day.Add(48 * time.Hour)},
+	} {
+		task := task
+		if err := s.Update(&task); err != nil {
+			t.Fatalf("Update %d failed: %v", i, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter TaskFilter
+		want   string
+	}{
+		{"everything, newest first", TaskFilter{}, "cba"},
+		{"type", TaskFilter{Type: "schedule"}, "ca"},
+		{"status", TaskFilter{Status: TaskFailed}, "cb"},
+		{"type and status", TaskFilter{Type: "schedule", Status: TaskFailed}, "c"},
+		{"from is inclusive", TaskFilter{From: day.Add(33 * time.Hour)}, "cb"},
+		{"to is exclusive", TaskFilter{To: day.Add(48 * time.Hour)}, "ba"},
+		{"window", TaskFilter{From: day.Add(24 * time.Hour), To: day.Add(48 * time.Hour)}, "b"},
+	}
+	for _, tt := range tests {
+		got := ""
+		for _, task := range s.List(tt.filter) {
+			got += task.ID
+		}
+		if got != tt.want {
+			t.Errorf("%s: listed %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTaskStorePrunesHistory(t *testing.T) {
+	dir := t.TempDir()
+	clk := clock.NewFake(time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC))
+	s, err := NewTaskStore(dir, clk)
+	if err != nil {
+		t.Fatalf("NewTaskStore failed: %v", err)
+	}
+
+	old := clk.Now()
+	for _, task := range []Task{
+		{ID: "old", Status: TaskSucceeded, CreatedAt: old},
+		// Running tasks are kept however old they are
+		{ID: "running", Status: TaskRunning, CreatedAt: old},
+		{ID: "recent", Status: TaskFailed, CreatedAt: old.Add(taskRetention)},
+	} {
+		task := task
+		if err := s.Update(&task); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+
+	clk.Advance(taskRetention + time.Hour)
+	created, err := s.Create("Schedule a meeting")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	for id, want := range map[string]bool{"old": false, "running": true, "recent": true, created.ID: true} {
+		if _, ok := s.Get(id); ok != want {
+			t.Errorf("task %s kept: %v, want %v", id, ok, want)
+		}
+	}
+
+	// Beyond maxTasks the oldest finished tasks go first
+	for i := 0; i < maxTasks; i++ {
+		task := Task{ID: NewID(), Status: TaskSucceeded, CreatedAt: clk.Now().Add(time.Duration(i) * time.Second)}
+		s.tasks[task.ID] = &task
+	}
+	if _, err := s.Create("Send an email"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if len(s.tasks) != maxTasks {
+		t.Errorf("history holds %d tasks, want %d", len(s.tasks), maxTasks)
+	}
+	for _, id := range []string{"running", created.ID} {
+		if _, ok := s.Get(id); !ok {
+			t.Errorf("running task %s was pruned", id)
+		}
+	}
+	if _, ok := s.Get("recent"); ok {
+		t.Error("the oldest finished task was kept")
+	}
+
+	// After a restart the old task is no longer running, so it ages out
+	s, err = NewTaskStore(dir, clk)
+	if err != nil {
+		t.Fatalf("NewTaskStore failed: %v", err)
+	}
+	if _, ok := s.Get("running"); ok || len(s.tasks) != maxTasks-1 {
+		t.Errorf("reloaded %d tasks, want %d", len(s.tasks), maxTasks-1)
+	}
+}
@@ -0,0 +1,126 @@
+package templates
+
+import "time"
+
+// Meeting is a calendar event as templates see it.
+type Meeting struct {
+	Title     string
+	Start     time.Time
+	End       time.Time
+	AllDay    bool
+	Calendar  string
+	Attendees []string
+}
+
+// Duration returns how long the meeting lasts.
+func (m Meeting) Duration() time.Duration {
+	return m.End.Sub(m.Start)
+}
+
+// Days returns the number of days an all-day meeting covers. Its end is
+// exclusive, the midnight after its last day.
+func (m Meeting) Days() int {
+	days := 0
+	for day := m.Start; day.Before(m.End); day = day.AddDate(0, 0, 1) {
+		days++
+	}
+	return days
+}
+
+// DailySummary is the data of the daily summary.
+type DailySummary struct {
+	Date     time.Time
+	Tasks    []string
+	Meetings []Meeting
+	// ShowCalendars is set when meetings come from several calendars, so
+	// that each says which one it is on
+	ShowCalendars bool
+}
+
+// PeriodSummary is the data of the weekly and monthly summaries. End is the
+// last day of the period.
+type PeriodSummary struct {
+	Start time.Time
+	End   time.Time
+}
+
+// MeetingReminder is the data of a reminder sent ahead of a meeting.
+type MeetingReminder struct {
+	Meeting  Meeting
+	StartsIn time.Duration
+}
+
+// Invitation is the data of an invitation sent to a meeting attendee.
+type Invitation struct {
+	Meeting Meeting
+}
+
+// Reminder is the data of a reminder task.
+type Reminder struct {
+	Title string
+	Time  time.Time
+}
+
+// TaskReply is the data of the reply to a task received by email. Error is
+// set instead of Outcome when the task failed.
+type TaskReply struct {
+	Subject string
+	Task    string
+	Outcome string
+	Error   string
+}
+
+// sample returns example data for the named template, around now.
+func sample(name string, now time.Time) (interface{}, bool) {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	standup := Meeting{
+		Title:     "Team standup",
+		Start:     day.Add(9*time.Hour + 30*time.Minute),
+		End:       day.Add(9*time.Hour + 45*time.Minute),
+		Calendar:  "work",
+		Attendees: []string{"alice@example.com", "bob@example.com"},
+	}
+	review := Meeting{
+		Title:     "Quarterly review",
+		Start:     day.AddDate(0, 0, 1).Add(14 * time.Hour),
+		End:       day.AddDate(0, 0, 1).Add(15 * time.Hour),
+		Calendar:  "work",
+		Attendees: []string{"carol@example.com"},
+	}
+	offsite := Meeting{
+		Title:    "Team offsite",
+		Start:    day,
+		End:      day.AddDate(0, 0, 2),
+		AllDay:   true,
+		Calendar: "work",
+	}
+
+	switch name {
+	case DailySummaryTemplate:
+		return DailySummary{
+			Date:          day,
+			Tasks:         []string{"Review pull requests", "Prepare the quarterly review"},
+			Meetings:      []Meeting{offsite, standup},
+			ShowCalendars: true,
+		}, true
+	case WeeklySummaryTemplate:
+		start := day.AddDate(0, 0, -int((day.Weekday()+6)%7))
+		return PeriodSummary{Start: start, End: start.AddDate(0, 0, 6)}, true
+	case MonthlySummaryTemplate:
+		start := day.AddDate(0, 0, 1-day.Day())
+		return PeriodSummary{Start: start, End: start.AddDate(0, 1, -1)}, true
+	case MeetingReminderTemplate:
+		return MeetingReminder{Meeting: review, StartsIn: 15 * time.Minute}, true
+	case InvitationTemplate:
+		return Invitation{Meeting: review}, true
+	case ReminderTemplate:
+		return Reminder{Title: "Call the dentist", Time: day.Add(16 * time.Hour)}, true
+	case TaskReplyTemplate:
+		return TaskReply{
+			Subject: "Standup",
+			Task:    "Schedule a standup with bob@example.com tomorrow at 9:30",
+			Outcome: "Scheduled Team standup for tomorrow at 09:30",
+		}, true
+	}
+	return nil, false
+}
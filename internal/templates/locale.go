@@ -0,0 +1,149 @@
+package templates
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultLocale is used for any locale the agent has no translations for.
+const DefaultLocale = "en"
+
+// locale holds how dates and durations are written in a language.
+type locale struct {
+	// Layouts in the Go reference time. Day and month names are replaced
+	// with those below.
+	longDate, shortDate, clock string
+	days                       [7]string
+	months                     [12]string
+	// units are the singular and plural of minute, hour and day
+	units [3][2]string
+}
+
+var locales = map[string]*locale{
+	"en": {
+		longDate:  "Monday, January 2, 2006",
+		shortDate: "Mon Jan 2, 2006",
+		clock:     "15:04",
+		days:      [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		months: [12]string{"January", "February", "March", "April", "May", "June",
+			"July", "August", "September", "October", "November", "December"},
+		units: [3][2]string{{"minute", "minutes"}, {"hour", "hours"}, {"day", "days"}},
+	},
+	"es": {
+		longDate:  "Monday, 2 de January de 2006",
+		shortDate: "Mon 2 Jan 2006",
+		clock:     "15:04",
+		days:      [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		months: [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio",
+			"julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		units: [3][2]string{{"minuto", "minutos"}, {"hora", "horas"}, {"día", "días"}},
+	},
+	"fr": {
+		longDate:  "Monday 2 January 2006",
+		shortDate: "Mon 2 Jan 2006",
+		clock:     "15:04",
+		days:      [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		months: [12]string{"janvier", "février", "mars", "avril", "mai", "juin",
+			"juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		units: [3][2]string{{"minute", "minutes"}, {"heure", "heures"}, {"jour", "jours"}},
+	},
+	"de": {
+		longDate:  "Monday, 2. January 2006",
+		shortDate: "Mon, 2. Jan 2006",
+		clock:     "15:04",
+		days:      [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		months: [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni",
+			"Juli", "August", "September", "Oktober", "November", "Dezember"},
+		units: [3][2]string{{"Minute", "Minuten"}, {"Stunde", "Stunden"}, {"Tag", "Tage"}},
+	},
+}
+
+// fallbacks returns the locales to try for name, most specific first: a
+// region such as "es-MX" falls back to its language.
+func fallbacks(name string) []string {
+	name = strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+	if name == "" {
+		return nil
+	}
+	chain := []string{name}
+	if i := strings.Index(name, "-"); i > 0 {
+		chain = append(chain, name[:i])
+	}
+	return chain
+}
+
+// lookupLocale returns the date formats for name, or those of
+// DefaultLocale when there are none.
+func lookupLocale(name string) *locale {
+	for _, candidate := range fallbacks(name) {
+		if l, ok := locales[candidate]; ok {
+			return l
+		}
+	}
+	return locales[DefaultLocale]
+}
+
+// format formats t with layout, writing day and month names in the language
+// of l. The names are replaced before formatting rather than after, so that
+// "Mon" in "Monday" or a translated name can't be mistaken for a layout
+// element.
+func (l *locale) format(t time.Time, layout string) string {
+	var b strings.Builder
+	for layout != "" {
+		var name string
+		var n int
+		switch {
+		case strings.HasPrefix(layout, "Monday"):
+			name, n = l.days[t.Weekday()], len("Monday")
+		case strings.HasPrefix(layout, "Mon"):
+			name, n = abbreviate(l.days[t.Weekday()]), len("Mon")
+		case strings.HasPrefix(layout, "January"):
+			name, n = l.months[t.Month()-1], len("January")
+		case strings.HasPrefix(layout, "Jan"):
+			name, n = abbreviate(l.months[t.Month()-1]), len("Jan")
+		}
+		if n > 0 {
+			b.WriteString(name)
+			layout = layout[n:]
+			continue
+		}
+
+		// Format up to the next name
+		end := len(layout)
+		for _, element := range []string{"Mon", "Jan"} {
+			if i := strings.Index(layout[1:], element); i >= 0 && i+1 < end {
+				end = i + 1
+			}
+		}
+		b.WriteString(t.Format(layout[:end]))
+		layout = layout[end:]
+	}
+	return b.String()
+}
+
+// abbreviate shortens a day or month name to three letters.
+func abbreviate(name string) string {
+	runes := []rune(name)
+	if len(runes) > 3 {
+		runes = runes[:3]
+	}
+	return string(runes)
+}
+
+// duration writes d in the largest unit that divides it exactly, such as
+// "1 day" or "90 minutes".
+func (l *locale) duration(d time.Duration) string {
+	minutes := int(d.Minutes())
+	n, unit := minutes, l.units[0]
+	switch {
+	case minutes >= 24*60 && minutes%(24*60) == 0:
+		n, unit = minutes/(24*60), l.units[2]
+	case minutes >= 60 && minutes%60 == 0:
+		n, unit = minutes/60, l.units[1]
+	}
+	if n == 1 {
+		return fmt.Sprintf("1 %s", unit[0])
+	}
+	return fmt.Sprintf("%d %s", n, unit[1])
+}
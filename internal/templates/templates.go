@@ -0,0 +1,285 @@
+// Package templates renders the emails the agent sends, such as the daily
+// summary and meeting reminders, from named text and HTML templates.
+//
+// A template is a name.txt text/template that defines a "subject" template
+// besides the body, with an optional name.html html/template alongside it
+// for the HTML version of the body. Defaults for every template are
+// embedded, and each can be overridden by a file in the templates
+// directory:
+//
+//	users/<email>/<locale>/name.txt   for one recipient, in one language
+//	users/<email>/name.txt            for one recipient
+//	<locale>/name.txt                 for a language
+//	name.txt                          for everyone
+//
+// The first of these that exists is used, and a locale such as "es-MX"
+// also matches templates for "es".
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed defaults
+var embedded embed.FS
+
+// Names of the templates the agent renders.
+const (
+	DailySummaryTemplate    = "daily_summary"
+	WeeklySummaryTemplate   = "weekly_summary"
+	MonthlySummaryTemplate  = "monthly_summary"
+	MeetingReminderTemplate = "meeting_reminder"
+	InvitationTemplate      = "invitation"
+	ReminderTemplate        = "reminder"
+	TaskReplyTemplate       = "task_reply"
+)
+
+// Names lists every template, in the order they are documented.
+var Names = []string{
+	DailySummaryTemplate,
+	WeeklySummaryTemplate,
+	MonthlySummaryTemplate,
+	MeetingReminderTemplate,
+	InvitationTemplate,
+	ReminderTemplate,
+	TaskReplyTemplate,
+}
+
+var ErrUnknownTemplate = errors.New("unknown template")
+
+// Email is a rendered template.
+type Email struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html,omitempty"`
+}
+
+// Info says where a template is loaded from.
+type Info struct {
+	Name string `json:"name"`
+	// Source is the path of the override in the templates directory, or
+	// "default" for the embedded template
+	Source string `json:"source"`
+	HTML   bool   `json:"html"`
+}
+
+// Engine renders templates. Files are read on every render, so edits to
+// the templates directory apply without a restart.
+type Engine struct {
+	dir    string
+	locale string
+	loc    *time.Location
+}
+
+// New returns an Engine reading overrides from dir, writing in locale and
+// showing times in loc. dir may be empty or not exist, in which case the
+// embedded defaults are used.
+func New(dir, locale string, loc *time.Location) *Engine {
+	if locale == "" {
+		locale = DefaultLocale
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &Engine{dir: dir, locale: locale, loc: loc}
+}
+
+// Locale returns the locale templates are rendered in by default.
+func (e *Engine) Locale() string {
+	return e.locale
+}
+
+// Render renders the named template for recipient in the default locale.
+func (e *Engine) Render(name, recipient string, data interface{}) (*Email, error) {
+	return e.RenderLocale(name, e.locale, recipient, data)
+}
+
+// RenderLocale renders the named template for recipient in locale.
+func (e *Engine) RenderLocale(name, locale, recipient string, data interface{}) (*Email, error) {
+	src, err := e.find(name, locale, recipient)
+	if err != nil {
+		return nil, err
+	}
+	funcs := e.funcs(locale)
+
+	text, err := texttemplate.New(name).Funcs(texttemplate.FuncMap(funcs)).Parse(string(src.text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %v", src.path(name, ".txt"), err)
+	}
+	if text.Lookup("subject") == nil {
+		return nil, fmt.Errorf("template %s does not define a subject", src.path(name, ".txt"))
+	}
+
+	var subject, body bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %v", name, err)
+	}
+	if err := text.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %v", name, err)
+	}
+	email := &Email{
+		// Subjects are a single line, however the template is laid out
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    body.String(),
+	}
+
+	if src.html != nil {
+		html, err := htmltemplate.New(name).Funcs(htmltemplate.FuncMap(funcs)).Parse(string(src.html))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %v", src.path(name, ".html"), err)
+		}
+		var buf bytes.Buffer
+		if err := html.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render template %s: %v", name, err)
+		}
+		email.HTML = buf.String()
+	}
+	return email, nil
+}
+
+// Preview renders the named template with sample data.
+func (e *Engine) Preview(name, locale, recipient string, now time.Time) (*Email, error) {
+	data, ok := sample(name, now.In(e.loc))
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+	return e.RenderLocale(name, locale, recipient, data)
+}
+
+// List says where each template is loaded from for recipient in locale.
+func (e *Engine) List(locale, recipient string) ([]Info, error) {
+	var infos []Info
+	for _, name := range Names {
+		src, err := e.find(name, locale, recipient)
+		if err != nil {
+			return nil, err
+		}
+		info := Info{Name: name, Source: "default", HTML: src.html != nil}
+		if src.fsys != defaultFS {
+			info.Source = src.path(name, ".txt")
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// defaultFS holds the embedded templates, one directory per locale.
+var defaultFS, _ = fs.Sub(embedded, "defaults")
+
+// source is a directory templates may be loaded from.
+type source struct {
+	fsys fs.FS
+	dir  string
+
+	text, html []byte
+}
+
+func (s *source) path(name, ext string) string {
+	return path.Join(s.dir, name+ext)
+}
+
+// sources lists the directories to look for templates in, in order.
+func (e *Engine) sources(locale, recipient string) []*source {
+	var sources []*source
+	// add adds the locale directories in dir, then last
+	add := func(fsys fs.FS, dir, last string) {
+		for _, l := range fallbacks(locale) {
+			if validDirName(l) {
+				sources = append(sources, &source{fsys: fsys, dir: path.Join(dir, l)})
+			}
+		}
+		sources = append(sources, &source{fsys: fsys, dir: path.Join(dir, last)})
+	}
+
+	if e.dir != "" {
+		dir := os.DirFS(e.dir)
+		if recipient = strings.ToLower(strings.TrimSpace(recipient)); validDirName(recipient) {
+			add(dir, path.Join("users", recipient), ".")
+		}
+		add(dir, ".", ".")
+	}
+	add(defaultFS, ".", DefaultLocale)
+	return sources
+}
+
+// find reads the named template from the first source that has it.
+func (e *Engine) find(name, locale, recipient string) (*source, error) {
+	if !known(name) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+	for _, src := range e.sources(locale, recipient) {
+		text, err := readFile(src.fsys, src.path(name, ".txt"))
+		if err != nil {
+			return nil, err
+		}
+		if text == nil {
+			continue
+		}
+		// The HTML version only comes from the same directory, so that an
+		// override of the text isn't sent alongside a stale default
+		html, err := readFile(src.fsys, src.path(name, ".html"))
+		if err != nil {
+			return nil, err
+		}
+		src.text, src.html = text, html
+		return src, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+}
+
+// readFile returns the contents of a file, or nil when it doesn't exist.
+func readFile(fsys fs.FS, name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, nil
+	}
+	data, err := fs.ReadFile(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %v", name, err)
+	}
+	return data, nil
+}
+
+func known(name string) bool {
+	for _, n := range Names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validDirName reports whether name can be used as a single directory
+// name, so that recipients and locales can't reach outside the templates
+// directory.
+func validDirName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// funcs returns the functions templates can call. Dates and durations are
+// written in the language of locale and times are shown in the configured
+// time zone.
+func (e *Engine) funcs(locale string) map[string]interface{} {
+	l := lookupLocale(locale)
+	return map[string]interface{}{
+		"date":      func(t time.Time) string { return l.format(t.In(e.loc), l.longDate) },
+		"shortDate": func(t time.Time) string { return l.format(t.In(e.loc), l.shortDate) },
+		"time":      func(t time.Time) string { return l.format(t.In(e.loc), l.clock) },
+		"zone":      func(t time.Time) string { return t.In(e.loc).Format("MST") },
+		"duration":  l.duration,
+		"join":      strings.Join,
+		"inc":       func(i int) int { return i + 1 },
+	}
+}
@@ -0,0 +1,199 @@
+package templates
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultsRender(t *testing.T) {
+	now := time.Date(2026, time.October, 15, 8, 0, 0, 0, time.UTC)
+	e := New("", "en", time.UTC)
+	for _, locale := range []string{"en", "es"} {
+		for _, name := range Names {
+			email, err := e.Preview(name, locale, "", now)
+			if err != nil {
+				t.Fatalf("%s in %s: %v", name, locale, err)
+			}
+			if email.Subject == "" || email.Text == "" || email.HTML == "" {
+				t.Errorf("%s in %s rendered %+v", name, locale, email)
+			}
+			if strings.Contains(email.Subject, "\n") {
+				t.Errorf("%s in %s has a subject of several lines: %q", name, locale, email.Subject)
+			}
+		}
+	}
+
+	if _, err := e.Preview("nope", "en", "", now); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("got %v for an unknown template", err)
+	}
+}
+
+func TestDailySummary(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2026, time.October, 15, 0, 0, 0, 0, loc)
+	summary := DailySummary{
+		Date:  day,
+		Tasks: []string{"Review pull requests"},
+		Meetings: []Meeting{
+			{Title: "Offsite", Start: day, End: day.AddDate(0, 0, 1), AllDay: true, Calendar: "team"},
+			// Times are shown in the configured time zone
+			{Title: "Standup <daily>", Start: time.Date(2026, time.October, 15, 13, 30, 0, 0, time.UTC), Calendar: "work"},
+		},
+		ShowCalendars: true,
+	}
+
+	email, err := New("", "en", loc).Render(DailySummaryTemplate, "", summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Good morning! Here's the daily summary:\n\n" +
+		"Today's Tasks:\n1. Review pull requests\n\n" +
+		"Today's Meetings:\n• Offsite (all day) (team)\n• Standup <daily> at 09:30 (work)\n\n" +
+		"Have a productive day!\n"
+	if email.Text != want {
+		t.Errorf("got text\n%s\nwant\n%s", email.Text, want)
+	}
+	if email.Subject != "Daily Summary - AI Assistant" {
+		t.Errorf("got subject %q", email.Subject)
+	}
+	if !strings.Contains(email.HTML, "Standup &lt;daily&gt;") || !strings.Contains(email.HTML, "Thursday, October 15, 2026") {
+		t.Errorf("got HTML\n%s", email.HTML)
+	}
+
+	email, err = New("", "en", loc).Render(DailySummaryTemplate, "", DailySummary{Date: day})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Good morning! Here's the daily summary:\n\nHave a productive day!\n"; email.Text != want {
+		t.Errorf("got text without tasks or meetings\n%s", email.Text)
+	}
+}
+
+func TestLocales(t *testing.T) {
+	reminder := Reminder{Title: "Dentist", Time: time.Date(2026, time.October, 15, 16, 0, 0, 0, time.UTC)}
+	e := New("", "es-MX", time.UTC)
+
+	tests := []struct {
+		locale  string
+		subject string
+		text    string
+	}{
+		// A region falls back to its language
+		{"", "Recordatorio: Dentist", "Programado para: jueves, 15 de octubre de 2026 a las 16:00"},
+		{"en", "Reminder: Dentist", "Scheduled for: Thursday, October 15, 2026 at 16:00"},
+		// Without translated templates, only dates are localized
+		{"fr", "Reminder: Dentist", "Scheduled for: jeudi 15 octobre 2026 at 16:00"},
+		{"xx", "Reminder: Dentist", "Scheduled for: Thursday, October 15, 2026 at 16:00"},
+	}
+	for _, test := range tests {
+		locale := test.locale
+		if locale == "" {
+			locale = e.Locale()
+		}
+		email, err := e.RenderLocale(ReminderTemplate, locale, "", reminder)
+		if err != nil {
+			t.Fatalf("%s: %v", locale, err)
+		}
+		if email.Subject != test.subject || !strings.Contains(email.Text, test.text) {
+			t.Errorf("%s: got %q and\n%s", locale, email.Subject, email.Text)
+		}
+	}
+}
+
+func TestLocaleFormat(t *testing.T) {
+	day := time.Date(2026, time.March, 2, 9, 5, 0, 0, time.UTC)
+	tests := []struct {
+		locale, layout, want string
+	}{
+		{"en", "Mon Jan 2, 2006 15:04", "Mon Mar 2, 2026 09:05"},
+		{"es", "Monday, 2 de January de 2006", "lunes, 2 de marzo de 2026"},
+		{"es", "Mon 2 Jan", "lun 2 mar"},
+		{"de", "Monday, 2. January 2006", "Montag, 2. März 2026"},
+	}
+	for _, test := range tests {
+		if got := lookupLocale(test.locale).format(day, test.layout); got != test.want {
+			t.Errorf("%s %q: got %q, want %q", test.locale, test.layout, got, test.want)
+		}
+	}
+
+	durations := map[time.Duration]string{
+		time.Minute:      "1 minute",
+		90 * time.Minute: "90 minutes",
+		2 * time.Hour:    "2 hours",
+		48 * time.Hour:   "2 days",
+	}
+	for d, want := range durations {
+		if got := lookupLocale("en").duration(d); got != want {
+			t.Errorf("%s: got %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("reminder.txt", `{{define "subject"}}Everyone: {{.Title}}{{end}}everyone`)
+	write("es/reminder.txt", `{{define "subject"}}Todos: {{.Title}}{{end}}todos`)
+	write("users/bob@example.com/reminder.txt", `{{define "subject"}}Bob: {{.Title}}{{end}}bob`)
+	write("users/bob@example.com/reminder.html", `<p>{{.Title}}</p>`)
+	write("invitation.txt", `no subject`)
+
+	e := New(dir, "en", time.UTC)
+	reminder := Reminder{Title: "<Dentist>", Time: time.Now()}
+	tests := []struct {
+		locale, recipient string
+		subject, text     string
+		html              string
+	}{
+		{"en", "alice@example.com", "Everyone: <Dentist>", "everyone", ""},
+		{"es-MX", "alice@example.com", "Todos: <Dentist>", "todos", ""},
+		{"es", "Bob@Example.com", "Bob: <Dentist>", "bob", "<p>&lt;Dentist&gt;</p>"},
+		// Recipients can't name a path outside their directory
+		{"en", "../users/bob@example.com", "Everyone: <Dentist>", "everyone", ""},
+		{"../users/bob@example.com", "", "Everyone: <Dentist>", "everyone", ""},
+	}
+	for _, test := range tests {
+		email, err := e.RenderLocale(ReminderTemplate, test.locale, test.recipient, reminder)
+		if err != nil {
+			t.Fatalf("%s %s: %v", test.locale, test.recipient, err)
+		}
+		// The HTML default isn't mixed with an overridden text
+		if email.Subject != test.subject || email.Text != test.text || email.HTML != test.html {
+			t.Errorf("%s %s: got %+v", test.locale, test.recipient, email)
+		}
+	}
+
+	if _, err := e.Render(InvitationTemplate, "", Invitation{}); err == nil || !strings.Contains(err.Error(), "subject") {
+		t.Errorf("got %v for a template without a subject", err)
+	}
+
+	infos, err := e.List("es", "bob@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := make(map[string]Info)
+	for _, info := range infos {
+		sources[info.Name] = info
+	}
+	if info := sources[ReminderTemplate]; info.Source != "users/bob@example.com/reminder.txt" || !info.HTML {
+		t.Errorf("got %+v for the reminder", info)
+	}
+	if info := sources[DailySummaryTemplate]; info.Source != "default" || !info.HTML {
+		t.Errorf("got %+v for the daily summary", info)
+	}
+}
@@ -0,0 +1,351 @@
+// Package timeparse extracts dates, times and time ranges from natural
+// language task descriptions such as "tomorrow at 3pm" or "next Tuesday 3-4pm".
+package timeparse
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Result describes the date and time found in a piece of text. Start and End
+// are expressed in the location of the reference time passed to Parse.
+type Result struct {
+	Start time.Time
+	// End is zero unless the text contained a time range.
+	End time.Time
+	// HasDate reports whether the text named a day, either explicitly or
+	// relative to the reference time.
+	HasDate bool
+	// HasTime reports whether the text named a time of day. When false,
+	// Start is midnight of the matched day.
+	HasTime bool
+}
+
+const meridiem = `(am\b|pm\b|a\.m\.|p\.m\.)`
+
+var (
+	emailRegex = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
+
+	isoDateRegex   = regexp.MustCompile(`\b(\d{4})-(\d{1,2})-(\d{1,2})(?:\b|t)`)
+	monthDayRegex  = regexp.MustCompile(`\b` + monthNames + `\.?\s+(\d{1,2})(?:st|nd|rd|th)?\b(?:,?\s+(\d{4})\b)?`)
+	dayMonthRegex  = regexp.MustCompile(`\b(\d{1,2})(?:st|nd|rd|th)?\s+(?:of\s+)?` + monthNames + `\b\.?(?:,?\s+(\d{4})\b)?`)
+	slashDateRegex = regexp.MustCompile(`\b(\d{1,2})/(\d{1,2})(?:/(\d{4}|\d{2}))?\b`)
+
+	halfHourRegex = regexp.MustCompile(`\bin\s+half\s+an?\s+hour\b`)
+	offsetRegex   = regexp.MustCompile(`\bin\s+(\d+|an?|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve)\s+(minutes?|mins?|hours?|hrs?|days?|weeks?)\b`)
+
+	dayAfterTomorrowRegex = regexp.MustCompile(`\bday\s+after\s+tomorrow\b`)
+	tomorrowRegex         = regexp.MustCompile(`\btomorrow\b`)
+	todayRegex            = regexp.MustCompile(`\b(today|tonight)\b`)
+	nextWeekRegex         = regexp.MustCompile(`\bnext\s+week\b`)
+	weekdayRegex          = regexp.MustCompile(`\b(?:(next|this|on)\s+)?(sunday|monday|tuesday|wednesday|thursday|friday|saturday)\b`)
+
+	rangeRegex      = regexp.MustCompile(`\b(from\s+|at\s+)?(\d{1,2})(?::(\d{2}))?\s*` + meridiem + `?\s*(?:-|–|to\b|until\b|till\b)\s*(\d{1,2})(?::(\d{2}))?\s*` + meridiem + `?`)
+	clockTimeRegex  = regexp.MustCompile(`\b(\d{1,2}):(\d{2})\s*` + meridiem + `?`)
+	meridiemRegex   = regexp.MustCompile(`\b(\d{1,2})\s*` + meridiem)
+	atHourRegex     = regexp.MustCompile(`(?:\bat|@)\s*(\d{1,2})\b`)
+	namedTimeRegex  = regexp.MustCompile(`\b(noon|midday|midnight)\b`)
+	trailingUnitRgx = regexp.MustCompile(`^\s*(minutes?|mins?|hours?|hrs?|days?|weeks?|people|persons?)\b`)
+)
+
+const monthNames = `(january|february|march|april|may|june|july|august|september|october|november|december|sept|jan|feb|mar|apr|jun|jul|aug|sep|oct|nov|dec)`
+
+var months = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+var numberWords = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+}
+
+// clock is a parsed time of day.
+type clock struct {
+	hour, minute int
+}
+
+// Parse finds a date and/or time of day in text, resolving relative
+// expressions against ref. It reports false when the text contains neither.
+//
+// Bare weekdays and "this <weekday>" resolve to the next occurrence including
+// today; "next <weekday>" always resolves to a later day. A time of day
+// without a date resolves to today, or tomorrow if that time has passed.
+// Dates without a year that have already passed roll over to next year, and
+// single-digit hours from 1 to 6 without am/pm are read as afternoon times.
+func Parse(text string, ref time.Time) (Result, bool) {
+	s := strings.ToLower(text)
+	s = emailRegex.ReplaceAllStringFunc(s, blank)
+
+	var (
+		result  Result
+		date    time.Time
+		offset  time.Duration
+		offsetD int
+	)
+
+	day := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, ref.Location())
+	}
+	today := day(ref)
+
+	// Explicit and relative dates
+	if m, ok := consume(&s, isoDateRegex); ok {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		d, _ := strconv.Atoi(m[3])
+		if t, ok := makeDate(year, time.Month(month), d, ref.Location()); ok {
+			date = t
+		}
+	} else if m, ok := consume(&s, monthDayRegex); ok {
+		d, _ := strconv.Atoi(m[2])
+		date, _ = resolveDate(m[3], months[m[1][:3]], d, today)
+	} else if m, ok := consume(&s, dayMonthRegex); ok {
+		d, _ := strconv.Atoi(m[1])
+		date, _ = resolveDate(m[3], months[m[2][:3]], d, today)
+	} else if m, ok := consume(&s, slashDateRegex); ok {
+		month, _ := strconv.Atoi(m[1])
+		d, _ := strconv.Atoi(m[2])
+		year := m[3]
+		if len(year) == 2 {
+			year = "20" + year
+		}
+		date, _ = resolveDate(year, time.Month(month), d, today)
+	} else if _, ok := consume(&s, dayAfterTomorrowRegex); ok {
+		date = today.AddDate(0, 0, 2)
+	} else if _, ok := consume(&s, tomorrowRegex); ok {
+		date = today.AddDate(0, 0, 1)
+	} else if _, ok := consume(&s, todayRegex); ok {
+		date = today
+	} else if m, ok := consume(&s, weekdayRegex); ok {
+		ahead := (int(weekdays[m[2]]) - int(today.Weekday()) + 7) % 7
+		if m[1] == "next" && ahead == 0 {
+			ahead = 7
+		}
+		date = today.AddDate(0, 0, ahead)
+	} else if _, ok := consume(&s, nextWeekRegex); ok {
+		date = today.AddDate(0, 0, 7)
+	}
+
+	if _, ok := consume(&s, halfHourRegex); ok {
+		offset = 30 * time.Minute
+	} else if m, ok := consume(&s, offsetRegex); ok {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			n = numberWords[m[1]]
+		}
+		switch {
+		case strings.HasPrefix(m[2], "min"):
+			offset = time.Duration(n) * time.Minute
+		case strings.HasPrefix(m[2], "h"):
+			offset = time.Duration(n) * time.Hour
+		case strings.HasPrefix(m[2], "day"):
+			offsetD = n
+		case strings.HasPrefix(m[2], "week"):
+			offsetD = 7 * n
+		}
+	}
+
+	// Times of day
+	start, end, hasTime := parseClock(&s)
+
+	switch {
+	case offset > 0:
+		result.Start = ref.Add(offset)
+		result.HasDate = true
+		result.HasTime = true
+		return result, true
+	case offsetD > 0:
+		date = today.AddDate(0, 0, offsetD)
+	}
+
+	if date.IsZero() && !hasTime {
+		return Result{}, false
+	}
+
+	result.HasDate = !date.IsZero()
+	result.HasTime = hasTime
+
+	if !result.HasDate {
+		date = today
+		if at(today, start).Before(ref) {
+			date = today.AddDate(0, 0, 1)
+		}
+	}
+
+	result.Start = date
+	if hasTime {
+		result.Start = at(date, start)
+	}
+	if end != nil {
+		result.End = at(date, *end)
+		if !result.End.After(result.Start) {
+			result.End = result.End.AddDate(0, 0, 1)
+		}
+	}
+
+	return result, true
+}
+
+// parseClock extracts a time of day or time range from s.
+func parseClock(s *string) (clock, *clock, bool) {
+	if m, ok := consume(s, rangeRegex); ok {
+		startMer, endMer := normalizeMeridiem(m[4]), normalizeMeridiem(m[7])
+		// A bare "3-4" is too ambiguous to be treated as a time range
+		if m[1] != "" || startMer != "" || endMer != "" || m[3] != "" || m[6] != "" {
+			endClock, endOK := toClock(m[5], m[6], endMer)
+			startClock, startOK := toClock(m[2], m[3], startMer)
+			if startMer == "" && endMer != "" && startOK && endOK {
+				// "3-4pm" shares the end meridiem unless that would put the
+				// start after the end, as in "11-1pm".
+				if shared, ok := toClock(m[2], m[3], endMer); ok && !after(shared, endClock) {
+					startClock = shared
+				}
+			}
+			if startOK && endOK {
+				return startClock, &endClock, true
+			}
+		}
+	}
+
+	if m, ok := consume(s, clockTimeRegex); ok {
+		if c, ok := toClock(m[1], m[2], normalizeMeridiem(m[3])); ok {
+			return c, nil, true
+		}
+	}
+
+	if m, ok := consume(s, meridiemRegex); ok {
+		if c, ok := toClock(m[1], "", normalizeMeridiem(m[2])); ok {
+			return c, nil, true
+		}
+	}
+
+	if m, ok := consume(s, namedTimeRegex); ok {
+		if m[1] == "midnight" {
+			return clock{0, 0}, nil, true
+		}
+		return clock{12, 0}, nil, true
+	}
+
+	for _, loc := range atHourRegex.FindAllStringSubmatchIndex(*s, -1) {
+		// Skip quantities such as "at 30 minutes"
+		if trailingUnitRgx.MatchString((*s)[loc[1]:]) {
+			continue
+		}
+		if c, ok := toClock((*s)[loc[2]:loc[3]], "", ""); ok {
+			*s = (*s)[:loc[0]] + strings.Repeat(" ", loc[1]-loc[0]) + (*s)[loc[1]:]
+			return c, nil, true
+		}
+	}
+
+	return clock{}, nil, false
+}
+
+// consume matches re against s and blanks out the match so later patterns
+// cannot reuse the same text.
+func consume(s *string, re *regexp.Regexp) ([]string, bool) {
+	loc := re.FindStringSubmatchIndex(*s)
+	if loc == nil {
+		return nil, false
+	}
+
+	m := make([]string, len(loc)/2)
+	for i := range m {
+		if loc[2*i] >= 0 {
+			m[i] = (*s)[loc[2*i]:loc[2*i+1]]
+		}
+	}
+
+	*s = (*s)[:loc[0]] + blank((*s)[loc[0]:loc[1]]) + (*s)[loc[1]:]
+	return m, true
+}
+
+func blank(s string) string {
+	return strings.Repeat(" ", len(s))
+}
+
+func normalizeMeridiem(m string) string {
+	return strings.ReplaceAll(m, ".", "")
+}
+
+func toClock(hourStr, minuteStr, mer string) (clock, bool) {
+	hour, err := strconv.Atoi(hourStr)
+	if err != nil {
+		return clock{}, false
+	}
+
+	minute := 0
+	if minuteStr != "" {
+		if minute, err = strconv.Atoi(minuteStr); err != nil || minute > 59 {
+			return clock{}, false
+		}
+	}
+
+	switch mer {
+	case "":
+		if hour > 23 {
+			return clock{}, false
+		}
+		// Single-digit hours before 7 without am/pm almost always mean the
+		// afternoon when talking about meetings.
+		if len(hourStr) == 1 && hour >= 1 && hour <= 6 {
+			hour += 12
+		}
+	case "am":
+		if hour < 1 || hour > 12 {
+			return clock{}, false
+		}
+		if hour == 12 {
+			hour = 0
+		}
+	case "pm":
+		if hour < 1 || hour > 12 {
+			return clock{}, false
+		}
+		if hour != 12 {
+			hour += 12
+		}
+	}
+
+	return clock{hour, minute}, true
+}
+
+func after(a, b clock) bool {
+	return a.hour > b.hour || (a.hour == b.hour && a.minute > b.minute)
+}
+
+func at(date time.Time, c clock) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), c.hour, c.minute, 0, 0, date.Location())
+}
+
+// resolveDate builds a date from an optional year, rolling yearless dates
+// that have already passed over to the following year.
+func resolveDate(yearStr string, month time.Month, d int, today time.Time) (time.Time, bool) {
+	if yearStr != "" {
+		year, _ := strconv.Atoi(yearStr)
+		return makeDate(year, month, d, today.Location())
+	}
+
+	t, ok := makeDate(today.Year(), month, d, today.Location())
+	if ok && t.Before(today) {
+		t, ok = makeDate(today.Year()+1, month, d, today.Location())
+	}
+	return t, ok
+}
+
+// makeDate rejects dates such as February 30 that time.Date would normalize.
+func makeDate(year int, month time.Month, d int, loc *time.Location) (time.Time, bool) {
+	t := time.Date(year, month, d, 0, 0, 0, 0, loc)
+	if t.Month() != month || t.Day() != d {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,98 @@
+package timeparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+
+	// Wednesday, October 14 2026 at 10:00
+	ref := time.Date(2026, time.October, 14, 10, 0, 0, 0, loc)
+	date := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		start   time.Time
+		end     time.Time
+		hasDate bool
+		hasTime bool
+	}{
+		{"today", "review the deck today", date(time.October, 14, 0, 0), time.Time{}, true, false},
+		{"tomorrow", "call the bank tomorrow", date(time.October, 15, 0, 0), time.Time{}, true, false},
+		{"tomorrow at 3pm", "Schedule a meeting tomorrow at 3pm", date(time.October, 15, 15, 0), time.Time{}, true, true},
+		{"minutes", "sync tomorrow at 3:45 pm", date(time.October, 15, 15, 45), time.Time{}, true, true},
+		{"dotted meridiem", "lunch tomorrow at 12 p.m.", date(time.October, 15, 12, 0), time.Time{}, true, true},
+		{"day after tomorrow", "the day after tomorrow at 9am", date(time.October, 16, 9, 0), time.Time{}, true, true},
+		{"next week", "plan the offsite next week", date(time.October, 21, 0, 0), time.Time{}, true, false},
+		{"weekday", "demo on Friday at 11am", date(time.October, 16, 11, 0), time.Time{}, true, true},
+		{"next weekday", "meeting next Tuesday at 2pm", date(time.October, 20, 14, 0), time.Time{}, true, true},
+		{"next same weekday", "retro next Wednesday at 4pm", date(time.October, 21, 16, 0), time.Time{}, true, true},
+		{"this same weekday", "retro this Wednesday at 4pm", date(time.October, 14, 16, 0), time.Time{}, true, true},
+		{"in hours", "ping me in 2 hours", date(time.October, 14, 12, 0), time.Time{}, true, true},
+		{"in minutes", "remind me in 45 minutes", date(time.October, 14, 10, 45), time.Time{}, true, true},
+		{"in an hour", "remind me in an hour", date(time.October, 14, 11, 0), time.Time{}, true, true},
+		{"in half an hour", "remind me in half an hour", date(time.October, 14, 10, 30), time.Time{}, true, true},
+		{"in days with time", "follow up in 3 days at 10am", date(time.October, 17, 10, 0), time.Time{}, true, true},
+		{"in weeks", "check back in two weeks", date(time.October, 28, 0, 0), time.Time{}, true, false},
+		{"month day", "board meeting Oct 21 at 9:30am", date(time.October, 21, 9, 30), time.Time{}, true, true},
+		{"full month ordinal", "launch on November 3rd", date(time.November, 3, 0, 0), time.Time{}, true, false},
+		{"day month", "review on 21 October at 4pm", date(time.October, 21, 16, 0), time.Time{}, true, true},
+		{"month day with year", "renewal on Jan 5, 2027", time.Date(2027, time.January, 5, 0, 0, 0, 0, loc), time.Time{}, true, false},
+		{"past month day rolls over", "anniversary on March 2", time.Date(2027, time.March, 2, 0, 0, 0, 0, loc), time.Time{}, true, false},
+		{"iso date", "deadline 2026-10-21 at 17:00", date(time.October, 21, 17, 0), time.Time{}, true, true},
+		{"iso date time", "deadline 2026-10-21T17:30", date(time.October, 21, 17, 30), time.Time{}, true, true},
+		{"slash date", "dentist 11/2 at 8am", date(time.November, 2, 8, 0), time.Time{}, true, true},
+		{"range shared meridiem", "block Thursday 3-4pm for planning", date(time.October, 15, 15, 0), date(time.October, 15, 16, 0), true, true},
+		{"range across noon", "workshop tomorrow 11-1pm", date(time.October, 15, 11, 0), date(time.October, 15, 13, 0), true, true},
+		{"range with minutes", "interview Oct 20 from 3:30 to 4:15pm", date(time.October, 20, 15, 30), date(time.October, 20, 16, 15), true, true},
+		{"range 24 hour", "on-site tomorrow 13:00-17:30", date(time.October, 15, 13, 0), date(time.October, 15, 17, 30), true, true},
+		{"time only later today", "call at 4pm", date(time.October, 14, 16, 0), time.Time{}, false, true},
+		{"time only already passed", "call at 8am", date(time.October, 15, 8, 0), time.Time{}, false, true},
+		{"bare afternoon hour", "sync tomorrow at 3", date(time.October, 15, 15, 0), time.Time{}, true, true},
+		{"noon", "lunch tomorrow at noon", date(time.October, 15, 12, 0), time.Time{}, true, true},
+		{"email digits ignored", "email bob42@example.com tomorrow at 9am", date(time.October, 15, 9, 0), time.Time{}, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Parse(tt.text, ref)
+			if !ok {
+				t.Fatalf("Parse(%q) found nothing", tt.text)
+			}
+			if !got.Start.Equal(tt.start) {
+				t.Errorf("Parse(%q).Start = %v, want %v", tt.text, got.Start, tt.start)
+			}
+			if !got.End.Equal(tt.end) {
+				t.Errorf("Parse(%q).End = %v, want %v", tt.text, got.End, tt.end)
+			}
+			if got.HasDate != tt.hasDate || got.HasTime != tt.hasTime {
+				t.Errorf("Parse(%q) HasDate=%v HasTime=%v, want %v %v", tt.text, got.HasDate, got.HasTime, tt.hasDate, tt.hasTime)
+			}
+			if got.Start.Location() != loc {
+				t.Errorf("Parse(%q).Start location = %v, want %v", tt.text, got.Start.Location(), loc)
+			}
+		})
+	}
+}
+
+func TestParseNoMatch(t *testing.T) {
+	ref := time.Date(2026, time.October, 14, 10, 0, 0, 0, time.UTC)
+
+	for _, text := range []string{
+		"send the report to alice@example.com",
+		"book a room for 3-4 people",
+		"schedule a 30 minutes meeting",
+	} {
+		if got, ok := Parse(text, ref); ok {
+			t.Errorf("Parse(%q) = %+v, want no match", text, got)
+		}
+	}
+}
@@ -19,6 +19,10 @@ func (l *Logger) Info(msg string, args ...interface{}) {
 	l.Printf("[INFO] "+msg, args...)
 }
 
+func (l *Logger) Warn(msg string, args ...interface{}) {
+	l.Printf("[WARN] "+msg, args...)
+}
+
 func (l *Logger) Error(msg string, args ...interface{}) {
 	l.Printf("[ERROR] "+msg, args...)
 }